@@ -27,6 +27,7 @@ type cacheEntry struct {
 	valid      bool
 	expiresAt  time.Time
 	customerID string
+	settings   accountSettings
 }
 
 // newAPIKeyCache creates a new cache with TTL and cleanup
@@ -61,20 +62,20 @@ func (c *apiKeyCache) cleanup() {
 }
 
 // get retrieves a cache entry if valid and not expired
-func (c *apiKeyCache) get(key string) (bool, string) {
+func (c *apiKeyCache) get(key string) (bool, string, accountSettings) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	entry, exists := c.keys[key]
 	if !exists {
-		return false, ""
+		return false, "", accountSettings{}
 	}
 
 	if time.Now().After(entry.expiresAt) {
-		return false, ""
+		return false, "", accountSettings{}
 	}
 
-	return entry.valid, entry.customerID
+	return entry.valid, entry.customerID, entry.settings
 }
 
 // set stores a cache entry with expiration
@@ -89,6 +90,60 @@ func (c *apiKeyCache) set(key string, valid bool, customerID string) {
 	}
 }
 
+// setValid stores a validated API key together with its account settings
+func (c *apiKeyCache) setValid(key string, customerID string, settings accountSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keys[key] = cacheEntry{
+		valid:      true,
+		expiresAt:  time.Now().Add(c.ttl),
+		customerID: customerID,
+		settings:   settings,
+	}
+}
+
+// accountSettings are per-account defaults kept in the Stripe customer
+// metadata. Changes take effect once the cached API key expires.
+type accountSettings struct {
+	VATRounding string // metadata "vat_rounding": line or bucket
+	Profile     string // metadata "profile": b2g_federal, b2g_state or b2b
+}
+
+// accountSettingsFromCustomer reads the settings from the customer metadata,
+// ignoring invalid values
+func accountSettingsFromCustomer(cust *stripe.Customer) accountSettings {
+	var settings accountSettings
+	if v := cust.Metadata["vat_rounding"]; v != "" {
+		if err := validateVATRounding(v); err != nil {
+			log.Printf("Ignoring metadata of customer %s: %v", cust.ID, err)
+		} else {
+			settings.VATRounding = v
+		}
+	}
+	if v := cust.Metadata["profile"]; v != "" {
+		if err := validateProfile(v); err != nil {
+			log.Printf("Ignoring metadata of customer %s: %v", cust.ID, err)
+		} else {
+			settings.Profile = v
+		}
+	}
+	return settings
+}
+
+type accountSettingsKey struct{}
+
+// withAccountSettings attaches the settings of the authenticated account to ctx
+func withAccountSettings(ctx context.Context, settings accountSettings) context.Context {
+	return context.WithValue(ctx, accountSettingsKey{}, settings)
+}
+
+// accountSettingsFromContext returns the settings attached by StripeAuthMiddleware
+func accountSettingsFromContext(ctx context.Context) accountSettings {
+	settings, _ := ctx.Value(accountSettingsKey{}).(accountSettings)
+	return settings
+}
+
 // global cache instance (5 minute TTL)
 var apiKeyCacheInstance = newAPIKeyCache(5 * time.Minute)
 
@@ -202,11 +257,11 @@ func StripeAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Check cache first
-		valid, customerID := apiKeyCacheInstance.get(apiKey)
+		valid, customerID, settings := apiKeyCacheInstance.get(apiKey)
 		if valid {
 			// Cache hit - allow request
 			log.Printf("API key validated from cache: %s (customer: %s)", apiKey[:20]+"...", customerID)
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(withAccountSettings(r.Context(), settings)))
 			return
 		}
 
@@ -261,9 +316,10 @@ func StripeAuthMiddleware(next http.Handler) http.Handler {
 			}
 
 			// Free tier keys don't need subscription check
-			apiKeyCacheInstance.set(apiKey, true, cust.ID)
+			settings := accountSettingsFromCustomer(cust)
+			apiKeyCacheInstance.setValid(apiKey, cust.ID, settings)
 			log.Printf("Free tier API key validated: %s (customer: %s, usage: %d/5)", apiKey[:20]+"...", cust.ID, usageCount)
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(withAccountSettings(ctx, settings)))
 			return
 		}
 
@@ -285,8 +341,9 @@ func StripeAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Valid key - cache positive result
-		apiKeyCacheInstance.set(apiKey, true, cust.ID)
+		settings = accountSettingsFromCustomer(cust)
+		apiKeyCacheInstance.setValid(apiKey, cust.ID, settings)
 		log.Printf("API key validated via Stripe: %s (customer: %s, status: %s)", apiKey[:20]+"...", cust.ID, status)
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(withAccountSettings(ctx, settings)))
 	})
 }
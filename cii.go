@@ -0,0 +1,607 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// UN/CEFACT Cross Industry Invoice (CII D16B) output as used by ZUGFeRD 2.x
+// and Factur-X. The profile selects the guideline the document claims
+// conformance to; the mapping itself is the EN 16931 core in both cases.
+const (
+	ciiRSMNamespace = "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+	ciiRAMNamespace = "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+	ciiQDTNamespace = "urn:un:unece:uncefact:data:standard:QualifiedDataType:100"
+	ciiUDTNamespace = "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100"
+
+	// DefaultCIIProfile is used when a request does not select a profile.
+	DefaultCIIProfile = "en16931"
+)
+
+// ciiProfile is a selectable CII conformance level.
+type ciiProfile struct {
+	Name        string
+	GuidelineID string // BT-24 specification identifier
+	// BusinessProcessID is the BT-23 process, only emitted when set.
+	BusinessProcessID string
+}
+
+var ciiProfiles = map[string]ciiProfile{
+	"en16931": {
+		Name:        "en16931",
+		GuidelineID: "urn:cen.eu:en16931:2017",
+	},
+	"xrechnung": {
+		Name:              "xrechnung",
+		GuidelineID:       xrechnungCustomizationID,
+		BusinessProcessID: peppolBillingProfileID,
+	},
+}
+
+// lookupCIIProfile returns the named profile, defaulting to DefaultCIIProfile.
+func lookupCIIProfile(name string) (ciiProfile, error) {
+	if name == "" {
+		name = DefaultCIIProfile
+	}
+	p, ok := ciiProfiles[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(ciiProfiles))
+		for n := range ciiProfiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return ciiProfile{}, fmt.Errorf("unsupported CII profile %q (supported: %s)", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+func init() {
+	registerOutputFormat(outputFormat{
+		Name:        "cii",
+		ContentType: "application/xml; charset=utf-8",
+		Check: func(inv InvoiceJSON, opts renderOptions) error {
+			if opts.CIIProfile == "xrechnung" {
+				return checkXRechnung(inv)
+			}
+			return nil
+		},
+		Render: func(inv InvoiceJSON, opts renderOptions) ([]byte, error) {
+			return TransformToCII(inv, opts.CIIProfile)
+		},
+	})
+}
+
+// -------- CII XML models (EN 16931 subset) --------
+// Element names carry the rsm/ram/udt/qdt prefixes declared on the root element.
+
+// CIIInvoice is the root element rsm:CrossIndustryInvoice.
+type CIIInvoice struct {
+	XMLName     xml.Name                       `xml:"rsm:CrossIndustryInvoice"`
+	XmlnsRSM    string                         `xml:"xmlns:rsm,attr"`
+	XmlnsRAM    string                         `xml:"xmlns:ram,attr"`
+	XmlnsQDT    string                         `xml:"xmlns:qdt,attr"`
+	XmlnsUDT    string                         `xml:"xmlns:udt,attr"`
+	Context     CIIDocumentContext             `xml:"rsm:ExchangedDocumentContext"`
+	Document    CIIExchangedDocument           `xml:"rsm:ExchangedDocument"`
+	Transaction CIISupplyChainTradeTransaction `xml:"rsm:SupplyChainTradeTransaction"`
+}
+
+type CIIDocumentContext struct {
+	BusinessProcess *CIIIDHolder `xml:"ram:BusinessProcessSpecifiedDocumentContextParameter,omitempty"`
+	Guideline       CIIIDHolder  `xml:"ram:GuidelineSpecifiedDocumentContextParameter"`
+}
+
+type CIIIDHolder struct {
+	ID string `xml:"ram:ID"`
+}
+
+type CIIExchangedDocument struct {
+	ID            string      `xml:"ram:ID"`
+	TypeCode      string      `xml:"ram:TypeCode"` // UNTDID 1001: 380 invoice, 381 credit note, 386 prepayment invoice
+	IssueDateTime CIIDateTime `xml:"ram:IssueDateTime"`
+	IncludedNote  []CIINote   `xml:"ram:IncludedNote,omitempty"`
+}
+
+// CIIDateTime wraps a udt:DateTimeString in format 102 (YYYYMMDD).
+type CIIDateTime struct {
+	DateTimeString CIIDateTimeString `xml:"udt:DateTimeString"`
+}
+
+type CIIDateTimeString struct {
+	Format string `xml:"format,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// CIIFormattedDateTime is the qdt variant used in referenced documents.
+type CIIFormattedDateTime struct {
+	DateTimeString CIIDateTimeString `xml:"qdt:DateTimeString"`
+}
+
+type CIINote struct {
+	Content string `xml:"ram:Content"`
+}
+
+// CIISupplyChainTradeTransaction element order: line items, agreement, delivery, settlement
+type CIISupplyChainTradeTransaction struct {
+	Lines      []CIILineItem       `xml:"ram:IncludedSupplyChainTradeLineItem"`
+	Agreement  CIIHeaderAgreement  `xml:"ram:ApplicableHeaderTradeAgreement"`
+	Delivery   CIIHeaderDelivery   `xml:"ram:ApplicableHeaderTradeDelivery"`
+	Settlement CIIHeaderSettlement `xml:"ram:ApplicableHeaderTradeSettlement"`
+}
+
+type CIILineItem struct {
+	LineDocument CIILineDocument   `xml:"ram:AssociatedDocumentLineDocument"`
+	Product      CIITradeProduct   `xml:"ram:SpecifiedTradeProduct"`
+	Agreement    CIILineAgreement  `xml:"ram:SpecifiedLineTradeAgreement"`
+	Delivery     CIILineDelivery   `xml:"ram:SpecifiedLineTradeDelivery"`
+	Settlement   CIILineSettlement `xml:"ram:SpecifiedLineTradeSettlement"`
+}
+
+type CIILineDocument struct {
+	LineID string `xml:"ram:LineID"`
+}
+
+// CIITradeProduct element order: GlobalID, SellerAssignedID, BuyerAssignedID, Name
+type CIITradeProduct struct {
+	GlobalID         *CIISchemeID `xml:"ram:GlobalID,omitempty"` // GTIN, scheme 0160
+	SellerAssignedID string       `xml:"ram:SellerAssignedID,omitempty"`
+	BuyerAssignedID  string       `xml:"ram:BuyerAssignedID,omitempty"`
+	Name             string       `xml:"ram:Name"`
+}
+
+type CIILineAgreement struct {
+	BuyerOrderReferencedDocument *CIILineReference `xml:"ram:BuyerOrderReferencedDocument,omitempty"`
+	NetPrice                     CIITradePrice     `xml:"ram:NetPriceProductTradePrice"`
+}
+
+type CIILineReference struct {
+	LineID string `xml:"ram:LineID"`
+}
+
+type CIITradePrice struct {
+	ChargeAmount string `xml:"ram:ChargeAmount"`
+}
+
+type CIILineDelivery struct {
+	BilledQuantity CIIQuantity `xml:"ram:BilledQuantity"`
+}
+
+type CIIQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type CIILineSettlement struct {
+	Tax              CIITradeTax          `xml:"ram:ApplicableTradeTax"`
+	Period           *CIIPeriod           `xml:"ram:BillingSpecifiedPeriod,omitempty"` // BG-26
+	AllowanceCharges []CIIAllowanceCharge `xml:"ram:SpecifiedTradeAllowanceCharge,omitempty"`
+	Summation        CIILineSummation     `xml:"ram:SpecifiedTradeSettlementLineMonetarySummation"`
+}
+
+// CIIAllowanceCharge is a reduction (allowance) or surcharge (charge) on the
+// document or on a line; only document level entries carry CategoryTradeTax.
+// Element order: ChargeIndicator, CalculationPercent, BasisAmount, ActualAmount, Reason, CategoryTradeTax
+type CIIAllowanceCharge struct {
+	ChargeIndicator    CIIIndicator `xml:"ram:ChargeIndicator"`
+	CalculationPercent string       `xml:"ram:CalculationPercent,omitempty"`
+	BasisAmount        string       `xml:"ram:BasisAmount"`
+	ActualAmount       string       `xml:"ram:ActualAmount"`
+	Reason             string       `xml:"ram:Reason"`
+	CategoryTradeTax   *CIITradeTax `xml:"ram:CategoryTradeTax,omitempty"`
+}
+
+type CIIIndicator struct {
+	Indicator bool `xml:"udt:Indicator"`
+}
+
+type CIILineSummation struct {
+	LineTotalAmount string `xml:"ram:LineTotalAmount"`
+}
+
+// CIITradeTax serves both the line tax (category and rate only) and the header
+// tax breakdown. Element order: CalculatedAmount, TypeCode, ExemptionReason,
+// BasisAmount, CategoryCode, ExemptionReasonCode, RateApplicablePercent
+type CIITradeTax struct {
+	CalculatedAmount      string `xml:"ram:CalculatedAmount,omitempty"`
+	TypeCode              string `xml:"ram:TypeCode"`
+	ExemptionReason       string `xml:"ram:ExemptionReason,omitempty"` // Tax breakdown of untaxed categories only
+	BasisAmount           string `xml:"ram:BasisAmount,omitempty"`
+	CategoryCode          string `xml:"ram:CategoryCode"`
+	ExemptionReasonCode   string `xml:"ram:ExemptionReasonCode,omitempty"` // VATEX, e.g. VATEX-EU-AE
+	RateApplicablePercent string `xml:"ram:RateApplicablePercent"`
+}
+
+// CIIHeaderAgreement element order: BuyerReference, SellerTradeParty, BuyerTradeParty, BuyerOrderReferencedDocument
+type CIIHeaderAgreement struct {
+	BuyerReference               string                 `xml:"ram:BuyerReference,omitempty"`
+	Seller                       CIITradeParty          `xml:"ram:SellerTradeParty"`
+	Buyer                        CIITradeParty          `xml:"ram:BuyerTradeParty"`
+	BuyerOrderReferencedDocument *CIIReferencedDocument `xml:"ram:BuyerOrderReferencedDocument,omitempty"`
+}
+
+// CIITradeParty element order: ID, Name, DefinedTradeContact, PostalTradeAddress,
+// URIUniversalCommunication, SpecifiedTaxRegistration
+type CIITradeParty struct {
+	ID              string              `xml:"ram:ID,omitempty"`
+	Name            string              `xml:"ram:Name"`
+	Contact         *CIITradeContact    `xml:"ram:DefinedTradeContact,omitempty"`
+	Address         CIITradeAddress     `xml:"ram:PostalTradeAddress"`
+	URI             *CIIURI             `xml:"ram:URIUniversalCommunication,omitempty"`
+	TaxRegistration *CIITaxRegistration `xml:"ram:SpecifiedTaxRegistration,omitempty"`
+}
+
+type CIITradeContact struct {
+	PersonName string    `xml:"ram:PersonName,omitempty"`
+	Telephone  *CIIPhone `xml:"ram:TelephoneUniversalCommunication,omitempty"`
+	Email      *CIIEmail `xml:"ram:EmailURIUniversalCommunication,omitempty"`
+}
+
+type CIIPhone struct {
+	CompleteNumber string `xml:"ram:CompleteNumber"`
+}
+
+type CIIEmail struct {
+	URIID string `xml:"ram:URIID"`
+}
+
+type CIITradeAddress struct {
+	PostcodeCode string `xml:"ram:PostcodeCode"`
+	LineOne      string `xml:"ram:LineOne"`
+	CityName     string `xml:"ram:CityName"`
+	CountryID    string `xml:"ram:CountryID"`
+}
+
+// CIIURI is the electronic address (BT-34/BT-49).
+type CIIURI struct {
+	URIID CIISchemeID `xml:"ram:URIID"`
+}
+
+type CIISchemeID struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type CIITaxRegistration struct {
+	ID CIISchemeID `xml:"ram:ID"` // schemeID VA: VAT identification number
+}
+
+type CIIReferencedDocument struct {
+	IssuerAssignedID       string                `xml:"ram:IssuerAssignedID"`
+	FormattedIssueDateTime *CIIFormattedDateTime `xml:"ram:FormattedIssueDateTime,omitempty"`
+}
+
+// CIIHeaderDelivery element order: ShipToTradeParty, ActualDeliverySupplyChainEvent
+type CIIHeaderDelivery struct {
+	ShipTo         *CIIShipToParty      `xml:"ram:ShipToTradeParty,omitempty"`
+	ActualDelivery *CIISupplyChainEvent `xml:"ram:ActualDeliverySupplyChainEvent,omitempty"`
+}
+
+// CIIShipToParty is the deliver-to party (BT-70) and address (BG-15).
+type CIIShipToParty struct {
+	Name    string          `xml:"ram:Name"`
+	Address CIITradeAddress `xml:"ram:PostalTradeAddress"`
+}
+
+// CIIPeriod is an invoicing period of the document (BG-14) or a line (BG-26).
+type CIIPeriod struct {
+	StartDateTime CIIDateTime `xml:"ram:StartDateTime"`
+	EndDateTime   CIIDateTime `xml:"ram:EndDateTime"`
+}
+
+type CIISupplyChainEvent struct {
+	OccurrenceDateTime CIIDateTime `xml:"ram:OccurrenceDateTime"`
+}
+
+// CIIHeaderSettlement element order: PaymentReference, InvoiceCurrencyCode, PaymentMeans,
+// ApplicableTradeTax, BillingSpecifiedPeriod, AllowanceCharges, Summation, InvoiceReferencedDocument
+type CIIHeaderSettlement struct {
+	PaymentReference          string                 `xml:"ram:PaymentReference,omitempty"`
+	InvoiceCurrencyCode       string                 `xml:"ram:InvoiceCurrencyCode"`
+	PaymentMeans              *CIIPaymentMeans       `xml:"ram:SpecifiedTradeSettlementPaymentMeans,omitempty"`
+	Taxes                     []CIITradeTax          `xml:"ram:ApplicableTradeTax"`
+	Period                    *CIIPeriod             `xml:"ram:BillingSpecifiedPeriod,omitempty"` // BG-14
+	AllowanceCharges          []CIIAllowanceCharge   `xml:"ram:SpecifiedTradeAllowanceCharge,omitempty"`
+	PaymentTerms              *CIIPaymentTerms       `xml:"ram:SpecifiedTradePaymentTerms,omitempty"`
+	Summation                 CIIHeaderSummation     `xml:"ram:SpecifiedTradeSettlementHeaderMonetarySummation"`
+	InvoiceReferencedDocument *CIIReferencedDocument `xml:"ram:InvoiceReferencedDocument,omitempty"`
+}
+
+// CIIPaymentTerms carries the payment terms text (BT-20) and due date (BT-9).
+type CIIPaymentTerms struct {
+	Description     string       `xml:"ram:Description"`
+	DueDateDateTime *CIIDateTime `xml:"ram:DueDateDateTime,omitempty"`
+}
+
+// CIIPaymentMeans describes a SEPA credit transfer (UNCL4461 code 58).
+type CIIPaymentMeans struct {
+	TypeCode    string                `xml:"ram:TypeCode"`
+	Account     CIICreditorAccount    `xml:"ram:PayeePartyCreditorFinancialAccount"`
+	Institution *CIICreditorInstitute `xml:"ram:PayeeSpecifiedCreditorFinancialInstitution,omitempty"`
+}
+
+type CIICreditorAccount struct {
+	IBANID      string `xml:"ram:IBANID"`
+	AccountName string `xml:"ram:AccountName,omitempty"`
+}
+
+type CIICreditorInstitute struct {
+	BICID string `xml:"ram:BICID"`
+}
+
+// CIIHeaderSummation element order: LineTotalAmount, ChargeTotalAmount, AllowanceTotalAmount,
+// TaxBasisTotalAmount, TaxTotalAmount, GrandTotalAmount, TotalPrepaidAmount, DuePayableAmount
+type CIIHeaderSummation struct {
+	LineTotalAmount      string    `xml:"ram:LineTotalAmount"`
+	ChargeTotalAmount    string    `xml:"ram:ChargeTotalAmount,omitempty"`
+	AllowanceTotalAmount string    `xml:"ram:AllowanceTotalAmount,omitempty"`
+	TaxBasisTotalAmount  string    `xml:"ram:TaxBasisTotalAmount"`
+	TaxTotalAmount       CIIAmount `xml:"ram:TaxTotalAmount"`
+	GrandTotalAmount     string    `xml:"ram:GrandTotalAmount"`
+	TotalPrepaidAmount   string    `xml:"ram:TotalPrepaidAmount,omitempty"`
+	DuePayableAmount     string    `xml:"ram:DuePayableAmount"`
+}
+
+type CIIAmount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+// TransformToCII maps the JSON invoice into a CII document for the given
+// profile. Amounts come from computeTotals, so they match the ebInterface
+// output exactly; credit memos and cancellations are stated positive with
+// type code 381 as EN 16931 requires.
+func TransformToCII(inv InvoiceJSON, profileName string) ([]byte, error) {
+	profile, err := lookupCIIProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	t := computeTotals(inv)
+	sign := documentSign(inv)
+	amount := func(cts int64) string { return formatCentsAsDecimal(sign * cts) }
+
+	doc := &CIIInvoice{
+		XmlnsRSM: ciiRSMNamespace,
+		XmlnsRAM: ciiRAMNamespace,
+		XmlnsQDT: ciiQDTNamespace,
+		XmlnsUDT: ciiUDTNamespace,
+		Context: CIIDocumentContext{
+			Guideline: CIIIDHolder{ID: profile.GuidelineID},
+		},
+		Document: CIIExchangedDocument{
+			ID:            inv.InvoiceNumber,
+			TypeCode:      invoiceTypeCode(inv),
+			IssueDateTime: ciiDate(inv.InvoiceDate),
+		},
+	}
+	if profile.BusinessProcessID != "" {
+		doc.Context.BusinessProcess = &CIIIDHolder{ID: profile.BusinessProcessID}
+	}
+
+	tx := &doc.Transaction
+	for i, li := range inv.Items {
+		lt := t.Lines[i]
+		position := fmt.Sprintf("%d", i+1)
+		var charges []CIIAllowanceCharge
+		for _, at := range lt.Adjustments {
+			charges = append(charges, buildCIIAllowanceCharge(at, amount))
+		}
+		tx.Lines = append(tx.Lines, CIILineItem{
+			LineDocument: CIILineDocument{LineID: position},
+			Product:      buildCIITradeProduct(li),
+			Agreement: CIILineAgreement{
+				BuyerOrderReferencedDocument: buildCIILineReference(inv, i),
+				NetPrice:                     CIITradePrice{ChargeAmount: formatPrice(lt.UnitPrice)},
+			},
+			Delivery: CIILineDelivery{
+				BilledQuantity: CIIQuantity{UnitCode: lt.UnitCode, Value: formatQuantity(lt.Quantity.Mul(decimal.NewFromInt(sign)))},
+			},
+			Settlement: CIILineSettlement{
+				Tax: CIITradeTax{
+					TypeCode:              "VAT",
+					CategoryCode:          lt.TaxCategory,
+					RateApplicablePercent: formatRate(lt.TaxRate),
+				},
+				Period:           buildCIIPeriod(li.Delivery),
+				AllowanceCharges: charges,
+				Summation:        CIILineSummation{LineTotalAmount: amount(lt.NetCts)},
+			},
+		})
+	}
+
+	tx.Agreement = CIIHeaderAgreement{
+		BuyerReference: inv.Recipient.OrderID,
+		Seller: buildCIIParty(inv.Biller.BillerID, inv.Biller.Name, inv.Biller.VATID, inv.Biller.Email, inv.Biller.Address,
+			&CIITradeContact{PersonName: getContactName(inv.Biller.ContactName, "Billing Department")}),
+		Buyer: buildCIIParty("", inv.Recipient.Name, inv.Recipient.VATID, inv.Recipient.Email, inv.Recipient.Address,
+			&CIITradeContact{PersonName: getContactName(inv.Recipient.ContactName, "Accounting")}),
+	}
+	if inv.Recipient.OrderID != "" {
+		tx.Agreement.BuyerOrderReferencedDocument = &CIIReferencedDocument{IssuerAssignedID: inv.Recipient.OrderID}
+	}
+	if inv.Biller.Phone != "" {
+		tx.Agreement.Seller.Contact.Telephone = &CIIPhone{CompleteNumber: inv.Biller.Phone}
+	}
+	if inv.Biller.Email != "" {
+		tx.Agreement.Seller.Contact.Email = &CIIEmail{URIID: inv.Biller.Email}
+	}
+	if inv.Recipient.Email != "" {
+		tx.Agreement.Buyer.Contact.Email = &CIIEmail{URIID: inv.Recipient.Email}
+	}
+
+	delivery := en16931Delivery(inv, t)
+	tx.Delivery = buildCIIHeaderDelivery(inv, delivery)
+
+	tx.Settlement = CIIHeaderSettlement{
+		PaymentReference:    inv.InvoiceNumber,
+		InvoiceCurrencyCode: invoiceCurrency,
+		PaymentMeans: &CIIPaymentMeans{
+			TypeCode:    "58", // SEPA credit transfer
+			Account:     CIICreditorAccount{IBANID: inv.Payment.IBAN, AccountName: inv.Biller.Name},
+			Institution: &CIICreditorInstitute{BICID: inv.Payment.BIC},
+		},
+		Period: buildCIIPeriod(delivery),
+		Summation: CIIHeaderSummation{
+			LineTotalAmount:     amount(t.LineNetCts),
+			TaxBasisTotalAmount: amount(t.NetCts),
+			TaxTotalAmount:      CIIAmount{CurrencyID: invoiceCurrency, Value: amount(t.TaxCts)},
+			GrandTotalAmount:    amount(t.GrossCts),
+			DuePayableAmount:    amount(t.PayableCts),
+		},
+	}
+	for _, b := range t.Buckets {
+		tax := CIITradeTax{
+			CalculatedAmount:      amount(b.TaxCts),
+			TypeCode:              "VAT",
+			BasisAmount:           amount(b.TaxableCts),
+			CategoryCode:          b.Category,
+			RateApplicablePercent: formatRate(b.Rate),
+		}
+		if b.ExemptionReason != "" {
+			tax.ExemptionReason = b.ExemptionReason
+			tax.ExemptionReasonCode = taxExemptionReasonCodes[b.Category]
+		}
+		tx.Settlement.Taxes = append(tx.Settlement.Taxes, tax)
+	}
+	for _, at := range t.Adjustments {
+		ac := buildCIIAllowanceCharge(at, amount)
+		ac.CategoryTradeTax = &CIITradeTax{
+			TypeCode:              "VAT",
+			CategoryCode:          at.TaxCategory,
+			RateApplicablePercent: formatRate(at.TaxRate),
+		}
+		tx.Settlement.AllowanceCharges = append(tx.Settlement.AllowanceCharges, ac)
+	}
+	if t.PaymentTerms != nil {
+		due := ciiDate(t.PaymentTerms.DueDate)
+		tx.Settlement.PaymentTerms = &CIIPaymentTerms{
+			Description:     paymentTermsNote(inv, t.PaymentTerms, profile.Name == "xrechnung"),
+			DueDateDateTime: &due,
+		}
+	}
+	if t.PrepaidCts != 0 {
+		tx.Settlement.Summation.TotalPrepaidAmount = amount(t.PrepaidCts)
+	}
+	if t.SurchargeCts != 0 {
+		tx.Settlement.Summation.ChargeTotalAmount = amount(t.SurchargeCts)
+	}
+	if t.ReductionCts != 0 {
+		tx.Settlement.Summation.AllowanceTotalAmount = amount(t.ReductionCts)
+	}
+	if ref := inv.OriginalInvoice; ref != nil {
+		tx.Settlement.InvoiceReferencedDocument = &CIIReferencedDocument{
+			IssuerAssignedID:       ref.InvoiceNumber,
+			FormattedIssueDateTime: &CIIFormattedDateTime{DateTimeString: ciiDateString(ref.InvoiceDate)},
+		}
+		if ref.Comment != "" {
+			doc.Document.IncludedNote = append(doc.Document.IncludedNote, CIINote{Content: ref.Comment})
+		}
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal CII: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// buildCIIAddress maps a postal address.
+func buildCIIAddress(addr AddressJSON) CIITradeAddress {
+	return CIITradeAddress{
+		PostcodeCode: addr.ZIP,
+		LineOne:      addr.Street,
+		CityName:     addr.City,
+		CountryID:    addressCountry(addr),
+	}
+}
+
+// buildCIIHeaderDelivery maps the delivery date and address; a delivery
+// period is stated as the billing period instead (see buildCIIPeriod).
+func buildCIIHeaderDelivery(inv InvoiceJSON, d *DeliveryJSON) CIIHeaderDelivery {
+	var delivery CIIHeaderDelivery
+	if d == nil {
+		return delivery
+	}
+	if d.Address != nil {
+		delivery.ShipTo = &CIIShipToParty{Name: deliveryName(inv, *d), Address: buildCIIAddress(*d.Address)}
+	}
+	if d.Date != "" {
+		delivery.ActualDelivery = &CIISupplyChainEvent{OccurrenceDateTime: ciiDate(d.Date)}
+	}
+	return delivery
+}
+
+// buildCIIPeriod returns the delivery period of the document or a line, or nil.
+func buildCIIPeriod(d *DeliveryJSON) *CIIPeriod {
+	if d == nil || !d.isPeriod() {
+		return nil
+	}
+	return &CIIPeriod{StartDateTime: ciiDate(d.FromDate), EndDateTime: ciiDate(d.ToDate)}
+}
+
+// buildCIIParty maps a biller or recipient. As in UBL, the electronic address is
+// the e-mail address if known, otherwise the VAT ID with the EAS code of its country.
+func buildCIIParty(id, name, vatID, email string, addr AddressJSON, contact *CIITradeContact) CIITradeParty {
+	party := CIITradeParty{
+		ID:      id,
+		Name:    name,
+		Contact: contact,
+		Address: buildCIIAddress(addr),
+	}
+	switch {
+	case email != "":
+		party.URI = &CIIURI{URIID: CIISchemeID{SchemeID: "EM", Value: email}}
+	case vatID != "":
+		party.URI = &CIIURI{URIID: CIISchemeID{SchemeID: vatEndpointScheme(vatID, addressCountry(addr)), Value: vatID}}
+	}
+	// A recipient without VAT ID (small B2B invoices) has no tax registration.
+	if vatID != "" {
+		party.TaxRegistration = &CIITaxRegistration{ID: CIISchemeID{SchemeID: "VA", Value: vatID}}
+	}
+	return party
+}
+
+// buildCIITradeProduct maps the description and article numbers of a line item.
+func buildCIITradeProduct(li LineItemJSON) CIITradeProduct {
+	product := CIITradeProduct{
+		SellerAssignedID: li.BillerArticleNumber,
+		BuyerAssignedID:  li.RecipientArticleNumber,
+		Name:             li.Description,
+	}
+	if li.GTIN != "" {
+		product.GlobalID = &CIISchemeID{SchemeID: gtinSchemeID, Value: li.GTIN}
+	}
+	return product
+}
+
+// buildCIILineReference links line i to the recipient's order, or returns nil.
+func buildCIILineReference(inv InvoiceJSON, i int) *CIILineReference {
+	position := lineOrderPosition(inv, i)
+	if position == "" {
+		return nil
+	}
+	return &CIILineReference{LineID: position}
+}
+
+// buildCIIAllowanceCharge maps a reduction or surcharge; amount states it in document currency.
+func buildCIIAllowanceCharge(at adjustmentTotals, amount func(int64) string) CIIAllowanceCharge {
+	return CIIAllowanceCharge{
+		ChargeIndicator:    CIIIndicator{Indicator: at.Surcharge},
+		CalculationPercent: adjustmentPercentage(at),
+		BasisAmount:        amount(at.BaseCts),
+		ActualAmount:       amount(at.AmountCts),
+		Reason:             adjustmentReason(at),
+	}
+}
+
+// ciiDate converts an ISO date (YYYY-MM-DD) into a format 102 date.
+func ciiDate(isoDate string) CIIDateTime {
+	return CIIDateTime{DateTimeString: ciiDateString(isoDate)}
+}
+
+func ciiDateString(isoDate string) CIIDateTimeString {
+	return CIIDateTimeString{Format: "102", Value: strings.ReplaceAll(isoDate, "-", "")}
+}
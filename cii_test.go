@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// ciiChildren returns the local names of the child elements of the element
+// at path, e.g. "SupplyChainTradeTransaction/ApplicableHeaderTradeSettlement"
+// below the root; "" lists the children of the root.
+func ciiChildren(t *testing.T, doc []byte, path string) []string {
+	t.Helper()
+	want := []string{"CrossIndustryInvoice"}
+	if path != "" {
+		want = append(want, strings.Split(path, "/")...)
+	}
+	var stack, children []string
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return children
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if strings.Join(stack, "/") == strings.Join(want, "/") {
+				children = append(children, el.Name.Local)
+			}
+			stack = append(stack, el.Name.Local)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// ciiTestDocument picks the values the tests check out of a CII document.
+type ciiTestDocument struct {
+	BusinessProcess string `xml:"ExchangedDocumentContext>BusinessProcessSpecifiedDocumentContextParameter>ID"`
+	Guideline       string `xml:"ExchangedDocumentContext>GuidelineSpecifiedDocumentContextParameter>ID"`
+	TypeCode        string `xml:"ExchangedDocument>TypeCode"`
+	Lines           []struct {
+		Quantity  string `xml:"SpecifiedLineTradeDelivery>BilledQuantity"`
+		LineTotal string `xml:"SpecifiedLineTradeSettlement>SpecifiedTradeSettlementLineMonetarySummation>LineTotalAmount"`
+	} `xml:"SupplyChainTradeTransaction>IncludedSupplyChainTradeLineItem"`
+	Settlement struct {
+		Taxes []struct {
+			Calculated string `xml:"CalculatedAmount"`
+			Basis      string `xml:"BasisAmount"`
+			Category   string `xml:"CategoryCode"`
+			Rate       string `xml:"RateApplicablePercent"`
+		} `xml:"ApplicableTradeTax"`
+		AllowanceCharges []struct {
+			Charge   bool   `xml:"ChargeIndicator>Indicator"`
+			Percent  string `xml:"CalculationPercent"`
+			Basis    string `xml:"BasisAmount"`
+			Actual   string `xml:"ActualAmount"`
+			Reason   string `xml:"Reason"`
+			Category string `xml:"CategoryTradeTax>CategoryCode"`
+			Rate     string `xml:"CategoryTradeTax>RateApplicablePercent"`
+		} `xml:"SpecifiedTradeAllowanceCharge"`
+		Summation struct {
+			LineTotal      string `xml:"LineTotalAmount"`
+			ChargeTotal    string `xml:"ChargeTotalAmount"`
+			AllowanceTotal string `xml:"AllowanceTotalAmount"`
+			TaxBasisTotal  string `xml:"TaxBasisTotalAmount"`
+			TaxTotal       string `xml:"TaxTotalAmount"`
+			GrandTotal     string `xml:"GrandTotalAmount"`
+			Prepaid        string `xml:"TotalPrepaidAmount"`
+			DuePayable     string `xml:"DuePayableAmount"`
+		} `xml:"SpecifiedTradeSettlementHeaderMonetarySummation"`
+		PaymentTerms     string `xml:"SpecifiedTradePaymentTerms>Description"`
+		InvoiceReference string `xml:"InvoiceReferencedDocument>IssuerAssignedID"`
+	} `xml:"SupplyChainTradeTransaction>ApplicableHeaderTradeSettlement"`
+}
+
+func TestLookupCIIProfile(t *testing.T) {
+	for _, name := range []string{"", "en16931", "EN16931", "xrechnung", "XRechnung"} {
+		p, err := lookupCIIProfile(name)
+		if err != nil {
+			t.Errorf("lookupCIIProfile(%q): %v", name, err)
+			continue
+		}
+		if want := strings.ToLower(name); want != "" && p.Name != want || want == "" && p.Name != DefaultCIIProfile {
+			t.Errorf("lookupCIIProfile(%q) = %s", name, p.Name)
+		}
+	}
+	_, err := lookupCIIProfile("zugferd-basic")
+	if want := `unsupported CII profile "zugferd-basic" (supported: en16931, xrechnung)`; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+	if _, err := TransformToCII(readTestInvoice(t, "test_invoice_small.json"), "zugferd-basic"); err == nil {
+		t.Error("TransformToCII accepted an unknown profile")
+	}
+}
+
+func TestCIIChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"en16931", "", func(inv *InvoiceJSON) {}, ""},
+		{"en16931 b2b without order id", "en16931", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.OrderID = ""
+		}, ""},
+		{"xrechnung without phone", "xrechnung", func(inv *InvoiceJSON) {}, "biller.phone"},
+		{"xrechnung complete", "xrechnung", func(inv *InvoiceJSON) { inv.Biller.Phone = "+43 1 234" }, ""},
+		{"xrechnung without order id", "xrechnung", func(inv *InvoiceJSON) {
+			inv.Biller.Phone = "+43 1 234"
+			inv.Recipient.OrderID = ""
+		}, "Leitweg-ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readGoldenInvoice(t)
+			tt.edit(&inv)
+			err := outputFormats["cii"].Check(inv, renderOptions{CIIProfile: tt.profile})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCIIElementOrder(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Delivery = &DeliveryJSON{Date: "2026-01-05", Address: &AddressJSON{Street: "Lager 2", ZIP: "4020", City: "Linz"}}
+	inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 10}}
+	inv.PaymentTerms = &PaymentTermsJSON{NetDays: 30}
+	inv.DocumentType = DocTypeCreditMemo
+	inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02"}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := TransformToCII(inv, "xrechnung")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "ExchangedDocumentContext ExchangedDocument SupplyChainTradeTransaction"},
+		{"ExchangedDocumentContext", "BusinessProcessSpecifiedDocumentContextParameter GuidelineSpecifiedDocumentContextParameter"},
+		{"ExchangedDocument", "ID TypeCode IssueDateTime"},
+		{"SupplyChainTradeTransaction", "IncludedSupplyChainTradeLineItem ApplicableHeaderTradeAgreement ApplicableHeaderTradeDelivery ApplicableHeaderTradeSettlement"},
+		{"SupplyChainTradeTransaction/IncludedSupplyChainTradeLineItem", "AssociatedDocumentLineDocument SpecifiedTradeProduct SpecifiedLineTradeAgreement SpecifiedLineTradeDelivery SpecifiedLineTradeSettlement"},
+		{"SupplyChainTradeTransaction/IncludedSupplyChainTradeLineItem/SpecifiedLineTradeSettlement", "ApplicableTradeTax SpecifiedTradeAllowanceCharge SpecifiedTradeSettlementLineMonetarySummation"},
+		{"SupplyChainTradeTransaction/ApplicableHeaderTradeAgreement", "BuyerReference SellerTradeParty BuyerTradeParty BuyerOrderReferencedDocument"},
+		{"SupplyChainTradeTransaction/ApplicableHeaderTradeAgreement/SellerTradeParty", "ID Name DefinedTradeContact PostalTradeAddress URIUniversalCommunication SpecifiedTaxRegistration"},
+		{"SupplyChainTradeTransaction/ApplicableHeaderTradeDelivery", "ShipToTradeParty ActualDeliverySupplyChainEvent"},
+		{"SupplyChainTradeTransaction/ApplicableHeaderTradeSettlement", "PaymentReference InvoiceCurrencyCode SpecifiedTradeSettlementPaymentMeans ApplicableTradeTax SpecifiedTradePaymentTerms SpecifiedTradeSettlementHeaderMonetarySummation InvoiceReferencedDocument"},
+		{"SupplyChainTradeTransaction/ApplicableHeaderTradeSettlement/ApplicableTradeTax", "CalculatedAmount TypeCode BasisAmount CategoryCode RateApplicablePercent"},
+		{"SupplyChainTradeTransaction/ApplicableHeaderTradeSettlement/SpecifiedTradeSettlementHeaderMonetarySummation", "LineTotalAmount TaxBasisTotalAmount TaxTotalAmount GrandTotalAmount DuePayableAmount"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(ciiChildren(t, doc, tt.path), " "); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCIIGuideline(t *testing.T) {
+	tests := []struct {
+		profile        string
+		wantGuideline  string
+		wantBusinessID string
+		wantSkonto     bool // XRechnung states Skonto in its #SKONTO# syntax
+	}{
+		{"", "urn:cen.eu:en16931:2017", "", false},
+		{"en16931", "urn:cen.eu:en16931:2017", "", false},
+		{"xrechnung", "urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0", "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0", true},
+	}
+	for _, tt := range tests {
+		inv := readTestInvoice(t, "test_invoice_small.json")
+		inv.PaymentTerms = &PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2, Days: 14}}}
+		doc, err := TransformToCII(inv, tt.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got ciiTestDocument
+		if err := xml.Unmarshal(doc, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Guideline != tt.wantGuideline || got.BusinessProcess != tt.wantBusinessID {
+			t.Errorf("profile %q: guideline %q, business process %q", tt.profile, got.Guideline, got.BusinessProcess)
+		}
+		if !strings.HasPrefix(got.Settlement.PaymentTerms, "2 % Skonto bei Zahlung bis 22.01.2026\nZahlbar bis 07.02.2026 ohne Abzug") ||
+			strings.Contains(got.Settlement.PaymentTerms, "#SKONTO#TAGE=14#PROZENT=2.00#") != tt.wantSkonto {
+			t.Errorf("profile %q: payment terms %q", tt.profile, got.Settlement.PaymentTerms)
+		}
+	}
+}
+
+func TestCIITotals(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json") // 4500.00 at 20 %
+	inv.Items = append(inv.Items, LineItemJSON{Description: "Hosting", Quantity: quantity("12"), UnitPriceCents: 2500, TaxRate: 10, OrderPosition: "2"})
+	inv.Prepayments = []PrepaymentJSON{{AmountCents: 100000}}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := TransformToCII(inv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ciiTestDocument
+	if err := xml.Unmarshal(doc, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Lines) != 2 || got.Lines[0].LineTotal != "4500.00" || got.Lines[1].Quantity != "12" || got.Lines[1].LineTotal != "300.00" {
+		t.Errorf("lines %+v", got.Lines)
+	}
+	taxes := got.Settlement.Taxes
+	if len(taxes) != 2 ||
+		taxes[0].Rate != "20" || taxes[0].Basis != "4500.00" || taxes[0].Calculated != "900.00" ||
+		taxes[1].Rate != "10" || taxes[1].Basis != "300.00" || taxes[1].Calculated != "30.00" {
+		t.Errorf("tax breakdown %+v", taxes)
+	}
+	s := got.Settlement.Summation
+	if s.LineTotal != "4800.00" || s.TaxBasisTotal != "4800.00" || s.TaxTotal != "930.00" || s.GrandTotal != "5730.00" ||
+		s.Prepaid != "1000.00" || s.DuePayable != "4730.00" || s.ChargeTotal != "" || s.AllowanceTotal != "" {
+		t.Errorf("summation %+v", s)
+	}
+	if want := `<ram:TaxTotalAmount currencyID="EUR">930.00</ram:TaxTotalAmount>`; !strings.Contains(string(doc), want) {
+		t.Errorf("missing %s", want)
+	}
+}
+
+func TestCIIDocumentTypes(t *testing.T) {
+	tests := []struct {
+		docType  string
+		wantCode string
+	}{
+		{DocTypeInvoice, "380"},
+		{DocTypeCreditMemo, "381"},
+		{DocTypeCancellation, "381"},
+		{DocTypeAdvancePayment, "386"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.docType, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			inv.DocumentType = tt.docType
+			if documentSign(inv) < 0 {
+				inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02", Comment: "Storno"}
+			}
+			if err := validateInvoice(inv); err != nil {
+				t.Fatal(err)
+			}
+			doc, err := TransformToCII(inv, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got ciiTestDocument
+			if err := xml.Unmarshal(doc, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.TypeCode != tt.wantCode {
+				t.Errorf("type code %s, want %s", got.TypeCode, tt.wantCode)
+			}
+			// Corrections are stated positive and refer to the corrected invoice.
+			if got.Settlement.Summation.DuePayable != "5400.00" || got.Lines[0].Quantity != "1" {
+				t.Errorf("payable %s, quantity %s", got.Settlement.Summation.DuePayable, got.Lines[0].Quantity)
+			}
+			if strings.Contains(string(doc), ">-") {
+				t.Errorf("negative amount:\n%s", doc)
+			}
+			if documentSign(inv) < 0 {
+				if got.Settlement.InvoiceReference != "2025-100" {
+					t.Errorf("invoice reference %q", got.Settlement.InvoiceReference)
+				}
+				if want := "<ram:IncludedNote>\n      <ram:Content>Storno</ram:Content>"; !strings.Contains(string(doc), want) {
+					t.Errorf("missing %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestCIIAllowanceCharges(t *testing.T) {
+	rate := 20.0
+	inv := readTestInvoice(t, "test_invoice_small.json") // 4500.00 at 20 %
+	inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 10, Reason: "Mengenrabatt"}}
+	inv.Adjustments = []AdjustmentJSON{
+		{Type: AdjustmentReduction, Percentage: 5, Reason: "Treuerabatt", TaxRate: &rate},
+		{Type: AdjustmentSurcharge, AmountCents: 5000, Reason: "Versand", TaxRate: &rate},
+	}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := TransformToCII(inv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The line allowance carries no tax category; it belongs to the line.
+	lineCharge := `<ram:SpecifiedTradeAllowanceCharge>
+          <ram:ChargeIndicator>
+            <udt:Indicator>false</udt:Indicator>
+          </ram:ChargeIndicator>
+          <ram:CalculationPercent>10</ram:CalculationPercent>
+          <ram:BasisAmount>4500.00</ram:BasisAmount>
+          <ram:ActualAmount>450.00</ram:ActualAmount>
+          <ram:Reason>Mengenrabatt</ram:Reason>
+        </ram:SpecifiedTradeAllowanceCharge>
+        <ram:SpecifiedTradeSettlementLineMonetarySummation>
+          <ram:LineTotalAmount>4050.00</ram:LineTotalAmount>`
+	if !strings.Contains(string(doc), lineCharge) {
+		t.Errorf("missing line allowance %s", lineCharge)
+	}
+
+	var got ciiTestDocument
+	if err := xml.Unmarshal(doc, &got); err != nil {
+		t.Fatal(err)
+	}
+	charges := got.Settlement.AllowanceCharges
+	if len(charges) != 2 {
+		t.Fatalf("document allowances and charges %+v", charges)
+	}
+	if c := charges[0]; c.Charge || c.Percent != "5" || c.Basis != "4050.00" || c.Actual != "202.50" || c.Reason != "Treuerabatt" || c.Category != "S" || c.Rate != "20" {
+		t.Errorf("allowance %+v", c)
+	}
+	if c := charges[1]; !c.Charge || c.Percent != "" || c.Actual != "50.00" || c.Reason != "Versand" || c.Category != "S" || c.Rate != "20" {
+		t.Errorf("charge %+v", c)
+	}
+	s := got.Settlement.Summation
+	if s.LineTotal != "4050.00" || s.AllowanceTotal != "202.50" || s.ChargeTotal != "50.00" ||
+		s.TaxBasisTotal != "3897.50" || s.TaxTotal != "779.50" || s.GrandTotal != "4677.00" || s.DuePayable != "4677.00" {
+		t.Errorf("summation %+v", s)
+	}
+	if taxes := got.Settlement.Taxes; len(taxes) != 1 || taxes[0].Basis != "3897.50" || taxes[0].Calculated != "779.50" {
+		t.Errorf("tax breakdown %+v", taxes)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+)
+
+// DefaultCountryCode is used for addresses without a country.
+const DefaultCountryCode = "AT"
+
+// iso3166List is the bundled ISO 3166-1 alpha-2 code list with German names.
+//
+//go:embed codelists/iso3166-de.txt
+var iso3166List string
+
+// countryNamesDE maps each ISO 3166-1 alpha-2 code to its German short name,
+// which ebInterface and the PDF print next to the code.
+var countryNamesDE = parseCodeList(iso3166List)
+
+// euMemberStates are the EU member states by ISO 3166-1 alpha-2 code.
+var euMemberStates = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true,
+	"EE": true, "ES": true, "FI": true, "FR": true, "GR": true, "HR": true, "HU": true,
+	"IE": true, "IT": true, "LT": true, "LU": true, "LV": true, "MT": true, "NL": true,
+	"PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+}
+
+// addressCountry returns the country code of an address, AT if none is given.
+func addressCountry(a AddressJSON) string {
+	if a.Country == "" {
+		return DefaultCountryCode
+	}
+	return a.Country
+}
+
+// countryNameDE returns the German name of a country code, or the code itself.
+func countryNameDE(code string) string {
+	if name, ok := countryNamesDE[code]; ok {
+		return name
+	}
+	return code
+}
+
+func isEUCountry(code string) bool {
+	return euMemberStates[code]
+}
+
+// validateAddress checks the required fields and the country code of an address.
+func validateAddress(a AddressJSON) error {
+	if a.Street == "" || a.ZIP == "" || a.City == "" {
+		return fmt.Errorf("street, zip and city are required")
+	}
+	if _, ok := countryNamesDE[a.Country]; a.Country != "" && !ok {
+		return fmt.Errorf("country %q is not an ISO 3166-1 alpha-2 code (e.g., AT, DE, IT)", a.Country)
+	}
+	return nil
+}
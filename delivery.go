@@ -0,0 +1,105 @@
+package main
+
+import "fmt"
+
+// DeliveryJSON states when goods were delivered or services rendered (Liefer-
+// bzw. Leistungsdatum), either as a single date or as a period, and
+// optionally where they were delivered.
+type DeliveryJSON struct {
+	Date        string       `json:"date,omitempty"`      // ISO-8601 (YYYY-MM-DD)
+	FromDate    string       `json:"from_date,omitempty"` // Start of the period, with to_date; alternative to date
+	ToDate      string       `json:"to_date,omitempty"`   // End of the period (inclusive)
+	Name        string       `json:"name,omitempty"`      // Addressee at the delivery address; default recipient.name
+	Address     *AddressJSON `json:"address,omitempty"`
+	ContactName string       `json:"contact_name,omitempty"`
+	Email       string       `json:"email,omitempty"`
+}
+
+// isPeriod reports whether the delivery covers a period rather than a date.
+func (d DeliveryJSON) isPeriod() bool {
+	return d.FromDate != ""
+}
+
+// validateDelivery checks a delivery; field is its JSON path for error messages.
+func validateDelivery(d *DeliveryJSON, field string) error {
+	if d == nil {
+		return nil
+	}
+	switch {
+	case d.Date != "" && (d.FromDate != "" || d.ToDate != ""):
+		return fmt.Errorf("%s: only one of date and from_date/to_date is allowed", field)
+	case d.Date == "" && (d.FromDate == "" || d.ToDate == ""):
+		return fmt.Errorf("%s.date or %s.from_date and %s.to_date are required", field, field, field)
+	}
+	for _, date := range [][2]string{{"date", d.Date}, {"from_date", d.FromDate}, {"to_date", d.ToDate}} {
+		if date[1] == "" {
+			continue
+		}
+		if err := validateDate(date[1]); err != nil {
+			return fmt.Errorf("%s.%s: %w", field, date[0], err)
+		}
+	}
+	// Both dates are validated YYYY-MM-DD strings, so they compare lexically.
+	if d.FromDate > d.ToDate {
+		return fmt.Errorf("%s.from_date must not be after %s.to_date", field, field)
+	}
+	if d.Address != nil {
+		if err := validateAddress(*d.Address); err != nil {
+			return fmt.Errorf("%s.address: %w", field, err)
+		}
+	} else if d.Name != "" {
+		return fmt.Errorf("%s.name requires %s.address", field, field)
+	}
+	if d.Email != "" && d.ContactName == "" {
+		return fmt.Errorf("%s.email requires %s.contact_name", field, field)
+	}
+	return nil
+}
+
+// validateDeliveries checks the document level delivery and those of the line items.
+func validateDeliveries(inv InvoiceJSON) error {
+	if err := validateDelivery(inv.Delivery, "delivery"); err != nil {
+		return err
+	}
+	for i, li := range inv.Items {
+		if err := validateDelivery(li.Delivery, fmt.Sprintf("items[%d].delivery", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliveryName returns the addressee at the delivery address.
+func deliveryName(inv InvoiceJSON, d DeliveryJSON) string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return inv.Recipient.Name
+}
+
+// en16931Delivery returns the document level delivery stated in UBL and CII,
+// or nil. EN 16931 requires a delivery date or period and the deliver-to
+// country for intra-community supplies (BR-IC-11, BR-IC-12); where the invoice
+// states neither, the invoice date and the recipient's address stand in.
+func en16931Delivery(inv InvoiceJSON, t invoiceTotals) *DeliveryJSON {
+	var d DeliveryJSON
+	if inv.Delivery != nil {
+		d = *inv.Delivery
+	}
+	for _, b := range t.Buckets {
+		if b.Category != TaxCategoryIntraCommunity {
+			continue
+		}
+		if d.Date == "" && !d.isPeriod() {
+			d.Date = inv.InvoiceDate
+		}
+		if d.Address == nil {
+			addr := inv.Recipient.Address
+			d.Address = &addr
+		}
+	}
+	if d == (DeliveryJSON{}) {
+		return nil
+	}
+	return &d
+}
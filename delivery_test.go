@@ -0,0 +1,168 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDelivery(t *testing.T) {
+	warehouse := func() *AddressJSON { return &AddressJSON{Street: "Lager 2", ZIP: "4020", City: "Linz"} }
+	tests := []struct {
+		name    string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"date", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{Date: "2026-01-05"} }, ""},
+		{"period", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-31"} }, ""},
+		{"one day period", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-01"} }, ""},
+		{"date and period", func(inv *InvoiceJSON) {
+			inv.Delivery = &DeliveryJSON{Date: "2026-01-05", FromDate: "2025-12-01", ToDate: "2025-12-31"}
+		}, "delivery: only one of date and from_date/to_date is allowed"},
+		{"date and to_date", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{Date: "2026-01-05", ToDate: "2025-12-31"} }, "delivery: only one of date and from_date/to_date is allowed"},
+		{"no date", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{Address: warehouse()} }, "delivery.date or delivery.from_date and delivery.to_date are required"},
+		{"open period", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{FromDate: "2025-12-01"} }, "delivery.date or delivery.from_date and delivery.to_date are required"},
+		{"period without start", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{ToDate: "2025-12-31"} }, "delivery.date or delivery.from_date and delivery.to_date are required"},
+		{"date format", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{Date: "05.01.2026"} }, "delivery.date: date must be in YYYY-MM-DD format"},
+		{"to_date format", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-32"} }, "delivery.to_date: date must be in YYYY-MM-DD format"},
+		{"reversed period", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{FromDate: "2025-12-31", ToDate: "2025-12-01"} }, "delivery.from_date must not be after delivery.to_date"},
+
+		{"address", func(inv *InvoiceJSON) {
+			inv.Delivery = &DeliveryJSON{Date: "2026-01-05", Name: "Lager Linz", Address: warehouse(), ContactName: "Lagerleitung", Email: "lager@example.at"}
+		}, ""},
+		{"incomplete address", func(inv *InvoiceJSON) {
+			inv.Delivery = &DeliveryJSON{Date: "2026-01-05", Address: &AddressJSON{Street: "Lager 2", City: "Linz"}}
+		}, "delivery.address: street, zip and city are required"},
+		{"address country", func(inv *InvoiceJSON) {
+			inv.Delivery = &DeliveryJSON{Date: "2026-01-05", Address: &AddressJSON{Street: "Lager 2", ZIP: "4020", City: "Linz", Country: "AUT"}}
+		}, `delivery.address: country "AUT" is not an ISO 3166-1 alpha-2 code`},
+		{"name without address", func(inv *InvoiceJSON) { inv.Delivery = &DeliveryJSON{Date: "2026-01-05", Name: "Lager Linz"} }, "delivery.name requires delivery.address"},
+		{"email without contact", func(inv *InvoiceJSON) {
+			inv.Delivery = &DeliveryJSON{Date: "2026-01-05", Address: warehouse(), Email: "lager@example.at"}
+		}, "delivery.email requires delivery.contact_name"},
+
+		{"line period", func(inv *InvoiceJSON) {
+			inv.Items[0].Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-31"}
+		}, ""},
+		{"line reversed period", func(inv *InvoiceJSON) {
+			inv.Items[0].Delivery = &DeliveryJSON{FromDate: "2025-12-31", ToDate: "2025-12-01"}
+		}, "items[0].delivery.from_date must not be after items[0].delivery.to_date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			tt.edit(&inv)
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeliveryOutput(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Delivery = &DeliveryJSON{Date: "2026-01-05", Address: &AddressJSON{Street: "Lager 2", ZIP: "4020", City: "Linz"}}
+	inv.Items[0].Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-31"}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range supportedEbInterfaceVersions() {
+		doc, err := TransformToEbInterfaceVersion(inv, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateEbInterface(doc); err != nil {
+			t.Errorf("%s schema: %v", version, err)
+		}
+		for _, want := range []string{
+			"<Delivery>\n    <Date>2026-01-05</Date>\n    <Address>\n      <Name>" + inv.Recipient.Name + "</Name>\n      <Street>Lager 2</Street>",
+			"<Delivery>\n          <Period>\n            <FromDate>2025-12-01</FromDate>\n            <ToDate>2025-12-31</ToDate>\n          </Period>",
+		} {
+			if !strings.Contains(string(doc), want) {
+				t.Errorf("%s is missing %s", version, want)
+			}
+		}
+	}
+
+	ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<cac:Delivery>\n    <cbc:ActualDeliveryDate>2026-01-05</cbc:ActualDeliveryDate>\n    <cac:DeliveryLocation>\n      <cac:Address>\n        <cbc:StreetName>Lager 2</cbc:StreetName>",
+		"<cac:DeliveryParty>\n      <cac:PartyName>\n        <cbc:Name>" + inv.Recipient.Name + "</cbc:Name>",
+		"<cac:InvoicePeriod>\n      <cbc:StartDate>2025-12-01</cbc:StartDate>\n      <cbc:EndDate>2025-12-31</cbc:EndDate>\n    </cac:InvoicePeriod>",
+	} {
+		if !strings.Contains(string(ubl), want) {
+			t.Errorf("UBL is missing %s", want)
+		}
+	}
+
+	cii, err := TransformToCII(inv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<ram:ShipToTradeParty>\n        <ram:Name>" + inv.Recipient.Name + "</ram:Name>\n        <ram:PostalTradeAddress>\n          <ram:PostcodeCode>4020</ram:PostcodeCode>",
+		"</ram:ShipToTradeParty>\n      <ram:ActualDeliverySupplyChainEvent>\n        <ram:OccurrenceDateTime>\n          <udt:DateTimeString format=\"102\">20260105</udt:DateTimeString>",
+		"<ram:BillingSpecifiedPeriod>\n          <ram:StartDateTime>\n            <udt:DateTimeString format=\"102\">20251201</udt:DateTimeString>",
+	} {
+		if !strings.Contains(string(cii), want) {
+			t.Errorf("CII is missing %s", want)
+		}
+	}
+}
+
+func TestDeliveryPeriodOutput(t *testing.T) {
+	// A document level period is the invoicing period; there is no delivery date.
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-31"}
+
+	ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<cac:InvoicePeriod>\n    <cbc:StartDate>2025-12-01</cbc:StartDate>\n    <cbc:EndDate>2025-12-31</cbc:EndDate>\n  </cac:InvoicePeriod>"; !strings.Contains(string(ubl), want) {
+		t.Errorf("UBL is missing %s", want)
+	}
+	if strings.Contains(string(ubl), "<cac:Delivery>") {
+		t.Error("UBL states a delivery without a date or address")
+	}
+
+	cii, err := TransformToCII(inv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<ram:BillingSpecifiedPeriod>\n        <ram:StartDateTime>\n          <udt:DateTimeString format=\"102\">20251201</udt:DateTimeString>"; !strings.Contains(string(cii), want) {
+		t.Errorf("CII is missing %s", want)
+	}
+	if strings.Contains(string(cii), "ActualDeliverySupplyChainEvent") {
+		t.Error("CII states a delivery date for a period")
+	}
+}
+
+func TestEN16931Delivery(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	if d := en16931Delivery(inv, computeTotals(inv)); d != nil {
+		t.Errorf("delivery %+v for an invoice without one", d)
+	}
+
+	// Intra-community supplies fall back to the invoice date and recipient address.
+	inv.Recipient.VATID = "DE136695976"
+	inv.Recipient.Address.Country = "DE"
+	inv.Items[0].TaxRate = 0
+	inv.Items[0].TaxCategory = TaxCategoryIntraCommunity
+	d := en16931Delivery(inv, computeTotals(inv))
+	if d == nil || d.Date != inv.InvoiceDate || d.Address == nil || d.Address.Country != "DE" {
+		t.Fatalf("delivery %+v", d)
+	}
+
+	inv.Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-31"}
+	if d := en16931Delivery(inv, computeTotals(inv)); d == nil || d.Date != "" || d.FromDate != "2025-12-01" {
+		t.Errorf("delivery %+v, want the stated period", d)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import "encoding/xml"
+
+// ebInterface 5.0 is still the only version some older recipient ERPs import.
+// It predates the TaxItem structure of 6.x: lines carry a VATRate and the
+// tax summary is a list of VAT/VATItem elements.
+const ebInterface50Namespace = "http://www.ebinterface.at/schema/5p0/"
+
+func init() {
+	registerEbInterfaceVersion(ebInterfaceVersion{
+		Version:    "5.0",
+		Namespace:  ebInterface50Namespace,
+		SchemaFile: "schemas/ebinterface-5p0.xsd",
+		Build: func(inv InvoiceJSON, t invoiceTotals) any {
+			return buildEbInterface50(inv, t)
+		},
+	})
+}
+
+// -------- ebInterface 5.0 XML models (simplified) --------
+
+// Eb50Invoice represents a minimal ebInterface 5.0 invoice.
+// Field order here defines the element order in the generated XML:
+// InvoiceNumber, InvoiceDate, CancelledOriginalDocument, RelatedDocument, Delivery, Biller, InvoiceRecipient, OrderingParty,
+// Details, ReductionAndSurchargeDetails, Tax, TotalGrossAmount, PrepaidAmount, PayableAmount, PaymentMethod, PaymentConditions
+type Eb50Invoice struct {
+	XMLName                      xml.Name                          `xml:"http://www.ebinterface.at/schema/5p0/ Invoice"`
+	GeneratingSystem             string                            `xml:"GeneratingSystem,attr"`
+	DocumentType                 string                            `xml:"DocumentType,attr"`
+	InvoiceCurrency              string                            `xml:"InvoiceCurrency,attr"`
+	Language                     string                            `xml:"Language,attr"` // ISO 639-2 code in 5.0 (e.g., ger)
+	InvoiceNumber                string                            `xml:"InvoiceNumber"`
+	InvoiceDate                  string                            `xml:"InvoiceDate"`
+	CancelledOriginalDocument    *EbCancelledOriginalDocument      `xml:"CancelledOriginalDocument,omitempty"`
+	RelatedDocument              []EbRelatedDocument               `xml:"RelatedDocument,omitempty"`
+	Delivery                     *Eb50Delivery                     `xml:"Delivery,omitempty"`
+	Biller                       Eb50Biller                        `xml:"Biller"`
+	InvoiceRecipient             Eb50Recipient                     `xml:"InvoiceRecipient"`
+	OrderingParty                *Eb50OrderingParty                `xml:"OrderingParty,omitempty"`
+	Details                      Eb50Details                       `xml:"Details"`
+	ReductionAndSurchargeDetails *Eb50ReductionAndSurchargeDetails `xml:"ReductionAndSurchargeDetails,omitempty"`
+	Tax                          Eb50Tax                           `xml:"Tax"`
+	TotalGrossAmount             string                            `xml:"TotalGrossAmount"`
+	PrepaidAmount                string                            `xml:"PrepaidAmount,omitempty"`
+	PayableAmount                string                            `xml:"PayableAmount"`
+	PaymentMethod                EbPaymentMethod                   `xml:"PaymentMethod"`
+	PaymentConditions            *EbPaymentConditions              `xml:"PaymentConditions,omitempty"`
+}
+
+// Eb50Address is a 5.0 address. Unlike 6.x, which moved them into a
+// separate Contact element, it carries the party's e-mail and contact person.
+// Element order: Name, Street, Town, ZIP, Country, Email, Contact
+type Eb50Address struct {
+	Name    string    `xml:"Name"`
+	Street  string    `xml:"Street"`
+	Town    string    `xml:"Town"`
+	ZIP     string    `xml:"ZIP"`
+	Country EbCountry `xml:"Country"`
+	Email   string    `xml:"Email,omitempty"`
+	Contact string    `xml:"Contact,omitempty"`
+}
+
+// Eb50Biller follows strict element order: VATID, InvoiceRecipientsBillerID, FurtherIdentification, Address.
+type Eb50Biller struct {
+	VATID                     string                    `xml:"VATIdentificationNumber"`
+	InvoiceRecipientsBillerID string                    `xml:"InvoiceRecipientsBillerID,omitempty"`
+	FurtherIdentification     []EbFurtherIdentification `xml:"FurtherIdentification,omitempty"`
+	Address                   Eb50Address               `xml:"Address"`
+}
+
+// Eb50Recipient follows strict element order: VATID, FurtherIdentification, OrderReference, Address.
+type Eb50Recipient struct {
+	VATID                 string                    `xml:"VATIdentificationNumber"`
+	FurtherIdentification []EbFurtherIdentification `xml:"FurtherIdentification,omitempty"`
+	OrderReference        *EbOrderReference         `xml:"OrderReference,omitempty"`
+	Address               Eb50Address               `xml:"Address"`
+}
+
+// Eb50OrderingParty follows strict element order: VATID, Address, BillersOrderingPartyID.
+type Eb50OrderingParty struct {
+	VATID                  string      `xml:"VATIdentificationNumber"`
+	Address                Eb50Address `xml:"Address"`
+	BillersOrderingPartyID string      `xml:"BillersOrderingPartyID"`
+}
+
+// Eb50Delivery is a delivery without the Contact element of 6.x; the contact
+// person goes into the address. Element order: Date or Period, Address
+type Eb50Delivery struct {
+	Date    string       `xml:"Date,omitempty"`
+	Period  *EbPeriod    `xml:"Period,omitempty"`
+	Address *Eb50Address `xml:"Address,omitempty"`
+}
+
+// eb50Address moves the contact of a 6.x party into its 5.0 address.
+func eb50Address(a EbAddress, c *EbContact) Eb50Address {
+	addr := Eb50Address{Name: a.Name, Street: a.Street, Town: a.Town, ZIP: a.ZIP, Country: a.Country}
+	if c != nil {
+		addr.Email, addr.Contact = c.Email, c.Name
+	}
+	return addr
+}
+
+func buildEb50Biller(inv InvoiceJSON) Eb50Biller {
+	b := buildEbBiller(inv)
+	return Eb50Biller{
+		VATID:                     b.VATID,
+		InvoiceRecipientsBillerID: b.InvoiceRecipientsBillerID,
+		FurtherIdentification:     b.FurtherIdentification,
+		Address:                   eb50Address(b.Address, &b.Contact),
+	}
+}
+
+func buildEb50Recipient(inv InvoiceJSON) Eb50Recipient {
+	r := buildEbRecipient(inv)
+	return Eb50Recipient{
+		VATID:                 r.VATID,
+		FurtherIdentification: r.FurtherIdentification,
+		OrderReference:        r.OrderReference,
+		Address:               eb50Address(r.Address, &r.Contact),
+	}
+}
+
+// buildEb50OrderingParty returns nil unless the invoice names an ordering party.
+func buildEb50OrderingParty(inv InvoiceJSON) *Eb50OrderingParty {
+	op := buildEbOrderingParty(inv)
+	if op == nil {
+		return nil
+	}
+	return &Eb50OrderingParty{
+		VATID:                  op.VATID,
+		Address:                eb50Address(op.Address, op.Contact),
+		BillersOrderingPartyID: op.BillersOrderingPartyID,
+	}
+}
+
+// buildEb50Delivery returns nil when d is nil. A contact without an address
+// has no place in 5.0 and is left out.
+func buildEb50Delivery(inv InvoiceJSON, d *DeliveryJSON) *Eb50Delivery {
+	delivery := buildEbDelivery(inv, d)
+	if delivery == nil {
+		return nil
+	}
+	out := &Eb50Delivery{Date: delivery.Date, Period: delivery.Period}
+	if delivery.Address != nil {
+		addr := eb50Address(*delivery.Address, delivery.Contact)
+		out.Address = &addr
+	}
+	return out
+}
+
+type Eb50Details struct {
+	ItemList Eb50ItemList `xml:"ItemList"`
+}
+
+type Eb50ItemList struct {
+	Items []Eb50Item `xml:"ListLineItem"`
+}
+
+// Eb50Item represents a single line item in a 5.0 invoice.
+// Element order: Description, ArticleNumber (optional), Quantity, UnitPrice, VATRate, ReductionAndSurchargeListLineItemDetails (optional),
+// Delivery (optional), InvoiceRecipientsOrderReference (optional), LineItemAmount
+type Eb50Item struct {
+	Description                              string                                      `xml:"Description"`
+	ArticleNumber                            []EbArticleNumber                           `xml:"ArticleNumber,omitempty"`
+	Quantity                                 EbQuantity                                  `xml:"Quantity"`
+	UnitPrice                                string                                      `xml:"UnitPrice"`
+	Eb50TaxRate                                                                          // VATRate or TaxExemption, directly after UnitPrice in 5.0
+	ReductionAndSurchargeListLineItemDetails *EbReductionAndSurchargeListLineItemDetails `xml:"ReductionAndSurchargeListLineItemDetails,omitempty"`
+	Delivery                                 *Eb50Delivery                               `xml:"Delivery,omitempty"`
+	InvoiceRecipientsOrderReference          *EbOrderReferenceItem                       `xml:"InvoiceRecipientsOrderReference,omitempty"`
+	LineItemAmount                           string                                      `xml:"LineItemAmount"`
+}
+
+// Eb50TaxRate is the choice between a VATRate and a TaxExemption, which
+// states the legal reason of untaxed categories instead of a rate.
+type Eb50TaxRate struct {
+	TaxExemption *Eb50TaxExemption `xml:"TaxExemption,omitempty"`
+	VATRate      *Eb50VATRate      `xml:"VATRate,omitempty"`
+}
+
+// Eb50TaxExemption carries the exemption reason; TaxExemptionCode holds the
+// tax category, e.g. AE.
+type Eb50TaxExemption struct {
+	TaxExemptionCode string `xml:"TaxExemptionCode,attr,omitempty"`
+	Value            string `xml:",chardata"`
+}
+
+// buildEb50TaxRate uses a TaxExemption for categories with an exemption reason.
+func buildEb50TaxRate(category string, rate float64, exemptionReason string) Eb50TaxRate {
+	if exemptionReason != "" {
+		return Eb50TaxRate{TaxExemption: &Eb50TaxExemption{TaxExemptionCode: category, Value: exemptionReason}}
+	}
+	return Eb50TaxRate{VATRate: &Eb50VATRate{TaxCategoryCode: category, Value: rate}}
+}
+
+// Eb50VATRate represents the VAT rate with its category code as an attribute.
+type Eb50VATRate struct {
+	TaxCategoryCode string  `xml:"TaxCategoryCode,attr,omitempty"`
+	Value           float64 `xml:",chardata"`
+}
+
+// Eb50ReductionAndSurchargeDetails lists the document level reductions and surcharges.
+type Eb50ReductionAndSurchargeDetails struct {
+	Entries []Eb50ReductionAndSurcharge // Reduction or Surcharge
+}
+
+// Eb50ReductionAndSurcharge is a document level reduction or surcharge; XMLName selects the kind.
+// Element order: BaseAmount, Percentage, Amount, VATRate or TaxExemption, Comment
+type Eb50ReductionAndSurcharge struct {
+	XMLName    xml.Name
+	BaseAmount string `xml:"BaseAmount"`
+	Percentage string `xml:"Percentage,omitempty"`
+	Amount     string `xml:"Amount"`
+	Eb50TaxRate
+	Comment string `xml:"Comment,omitempty"`
+}
+
+// Eb50Tax wraps the VAT summary.
+type Eb50Tax struct {
+	VAT Eb50VAT `xml:"VAT"`
+}
+
+type Eb50VAT struct {
+	Items []Eb50VATItem `xml:"VATItem"`
+}
+
+// Eb50VATItem is one VAT summary line.
+// Element order: TaxedAmount, VATRate or TaxExemption, Amount
+type Eb50VATItem struct {
+	TaxedAmount string `xml:"TaxedAmount"`
+	Eb50TaxRate
+	Amount string `xml:"Amount"`
+}
+
+// buildEbInterface50 assembles an ebInterface 5.0 document.
+func buildEbInterface50(inv InvoiceJSON, t invoiceTotals) *Eb50Invoice {
+	items := make([]Eb50Item, 0, len(inv.Items))
+	for i, li := range inv.Items {
+		lt := t.Lines[i]
+		items = append(items, Eb50Item{
+			Description:                              li.Description,
+			ArticleNumber:                            buildEbArticleNumbers(li),
+			Quantity:                                 buildEbQuantity(lt),
+			UnitPrice:                                formatPrice(lt.UnitPrice),
+			Eb50TaxRate:                              buildEb50TaxRate(lt.TaxCategory, lt.TaxRate, lt.TaxExemptionReason),
+			ReductionAndSurchargeListLineItemDetails: buildEbLineAdjustments(lt),
+			Delivery:                                 buildEb50Delivery(inv, li.Delivery),
+			InvoiceRecipientsOrderReference:          buildEbLineOrderReference(inv, i),
+			LineItemAmount:                           formatCentsAsDecimal(lt.NetCts),
+		})
+	}
+
+	vat := make([]Eb50VATItem, 0, len(t.Buckets))
+	for _, b := range t.Buckets {
+		vat = append(vat, Eb50VATItem{
+			TaxedAmount: formatCentsAsDecimal(b.TaxableCts),
+			Eb50TaxRate: buildEb50TaxRate(b.Category, b.Rate, b.ExemptionReason),
+			Amount:      formatCentsAsDecimal(b.TaxCts),
+		})
+	}
+
+	doc := &Eb50Invoice{
+		GeneratingSystem: generatingSystem,
+		DocumentType:     ebDocumentType(inv),
+		InvoiceCurrency:  invoiceCurrency,
+		Language:         "ger",
+		InvoiceNumber:    inv.InvoiceNumber,
+		InvoiceDate:      inv.InvoiceDate,
+		Delivery:         buildEb50Delivery(inv, inv.Delivery),
+		Biller:           buildEb50Biller(inv),
+		InvoiceRecipient: buildEb50Recipient(inv),
+		OrderingParty:    buildEb50OrderingParty(inv),
+		Details: Eb50Details{
+			ItemList: Eb50ItemList{
+				Items: items,
+			},
+		},
+		Tax: Eb50Tax{
+			VAT: Eb50VAT{Items: vat},
+		},
+		TotalGrossAmount:  formatCentsAsDecimal(t.GrossCts),
+		PayableAmount:     formatCentsAsDecimal(t.PayableCts),
+		PaymentMethod:     buildEbPaymentMethod(inv),
+		PaymentConditions: buildEbPaymentConditions(t),
+	}
+	doc.CancelledOriginalDocument, doc.RelatedDocument = buildEbDocumentReferences(inv)
+	if t.PrepaidCts != 0 {
+		doc.PrepaidAmount = formatCentsAsDecimal(t.PrepaidCts)
+	}
+	if len(t.Adjustments) > 0 {
+		doc.ReductionAndSurchargeDetails = &Eb50ReductionAndSurchargeDetails{}
+		for _, at := range t.Adjustments {
+			doc.ReductionAndSurchargeDetails.Entries = append(doc.ReductionAndSurchargeDetails.Entries, Eb50ReductionAndSurcharge{
+				XMLName:     ebAdjustmentName(at, ""),
+				BaseAmount:  formatCentsAsDecimal(at.BaseCts),
+				Percentage:  adjustmentPercentage(at),
+				Amount:      formatCentsAsDecimal(at.AmountCts),
+				Eb50TaxRate: buildEb50TaxRate(at.TaxCategory, at.TaxRate, at.TaxExemptionReason),
+				Comment:     at.Reason,
+			})
+		}
+	}
+	return doc
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readGoldenInvoice(t *testing.T) InvoiceJSON {
+	t.Helper()
+	return readTestInvoice(t, "golden_test.json")
+}
+
+// readTestInvoice decodes one of the sample invoices in tests/.
+func readTestInvoice(t *testing.T, name string) InvoiceJSON {
+	t.Helper()
+	data, err := os.ReadFile("tests/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inv InvoiceJSON
+	if err := json.Unmarshal(data, &inv); err != nil {
+		t.Fatal(err)
+	}
+	return inv
+}
+
+func TestEbInterface50ContactInAddress(t *testing.T) {
+	inv := readGoldenInvoice(t)
+	inv.Biller.ContactName = "Maria Huber"
+	inv.OrderingParty = &OrderingPartyJSON{
+		Name:        "Beispiel GmbH",
+		VATID:       "ATU13585627",
+		CustomerID:  "K-1",
+		ContactName: "Max Muster",
+		Email:       "max@example.at",
+		Address:     AddressJSON{Street: "Ring 1", ZIP: "8010", City: "Graz"},
+	}
+	inv.Delivery = &DeliveryJSON{
+		Date:        "2026-01-05",
+		Address:     &AddressJSON{Street: "Lager 2", ZIP: "4020", City: "Linz"},
+		ContactName: "Lagerleitung",
+		Email:       "lager@example.at",
+	}
+	doc, err := TransformToEbInterfaceVersion(inv, "5.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateEbInterface(doc); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	for _, want := range []string{
+		"<Email>billing@yourstartup.at</Email>\n      <Contact>Maria Huber</Contact>\n    </Address>",
+		"<Email>max@example.at</Email>\n      <Contact>Max Muster</Contact>\n    </Address>",
+		"<Email>lager@example.at</Email>\n      <Contact>Lagerleitung</Contact>\n    </Address>",
+	} {
+		if !strings.Contains(string(doc), want) {
+			t.Errorf("missing %q in\n%s", want, doc)
+		}
+	}
+	if strings.Contains(string(doc), "<Contact>\n") {
+		t.Errorf("5.0 document has a 6.x Contact element:\n%s", doc)
+	}
+
+	parsed, err := ParseEbInterface(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Unmapped) > 0 {
+		t.Errorf("unmapped: %+v", parsed.Unmapped)
+	}
+	got := parsed.Invoice
+	if got.Biller.ContactName != "Maria Huber" || got.Biller.Email != "billing@yourstartup.at" {
+		t.Errorf("biller contact %q <%s>", got.Biller.ContactName, got.Biller.Email)
+	}
+	if op := got.OrderingParty; op == nil || op.ContactName != "Max Muster" || op.Email != "max@example.at" {
+		t.Errorf("ordering party %+v", op)
+	}
+	if d := got.Delivery; d == nil || d.ContactName != "Lagerleitung" || d.Email != "lager@example.at" {
+		t.Errorf("delivery %+v", d)
+	}
+}
+
+func TestEbInterface50RejectsContactElement(t *testing.T) {
+	doc, err := TransformToEbInterfaceVersion(readGoldenInvoice(t), "5.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := strings.Replace(string(doc), "</Address>\n  </Biller>", "</Address>\n    <Contact><Name>X</Name></Contact>\n  </Biller>", 1)
+	if invalid == string(doc) {
+		t.Fatal("biller address not found")
+	}
+	if err := ValidateEbInterface([]byte(invalid)); err == nil {
+		t.Error("5.0 schema accepted a Contact element in Biller")
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+// ebInterface 6.0 introduced the TaxItem and Contact structure that 6.1 kept,
+// so the 6.1 model serves both; only the namespace differs in what this
+// service emits. Version-specific elements beyond that are not generated.
+const ebInterface60Namespace = "http://www.ebinterface.at/schema/6p0/"
+
+func init() {
+	registerEbInterfaceVersion(ebInterfaceVersion{
+		Version:    "6.0",
+		Namespace:  ebInterface60Namespace,
+		SchemaFile: "schemas/ebinterface-6p0.xsd",
+		Build: func(inv InvoiceJSON, t invoiceTotals) any {
+			return buildEbInterface6(inv, t, ebInterface60Namespace)
+		},
+	})
+}
@@ -0,0 +1,214 @@
+package main
+
+import "encoding/xml"
+
+// ebInterface 6.1 is the default output version.
+const ebInterface61Namespace = "http://www.ebinterface.at/schema/6p1/"
+
+func init() {
+	registerEbInterfaceVersion(ebInterfaceVersion{
+		Version:    "6.1",
+		Namespace:  ebInterface61Namespace,
+		SchemaFile: "schemas/ebinterface-6p1.xsd",
+		Build: func(inv InvoiceJSON, t invoiceTotals) any {
+			return buildEbInterface6(inv, t, ebInterface61Namespace)
+		},
+	})
+}
+
+// -------- ebInterface 6.x XML models (simplified) --------
+
+// EbInterfaceInvoice represents a minimal ebInterface 6.x invoice.
+// ebInterface 6.0 and 6.1 share this layout; XMLName carries the version namespace.
+// Field order here defines the element order in the generated XML.
+// Correct order based on official ebInterface 6.1 example:
+// InvoiceNumber, InvoiceDate, CancelledOriginalDocument, RelatedDocument, Delivery, Biller, InvoiceRecipient, OrderingParty,
+// Details, ReductionAndSurchargeDetails, Tax, TotalGrossAmount, PrepaidAmount, PayableAmount, PaymentMethod, PaymentConditions
+// Note: There is NO InvoiceSummary element in ebInterface 6.1 - tax summary is in Tax element
+type EbInterfaceInvoice struct {
+	XMLName                      xml.Name                        // {namespace, "Invoice"}, set by the version builder
+	GeneratingSystem             string                          `xml:"GeneratingSystem,attr"`
+	DocumentType                 string                          `xml:"DocumentType,attr"`
+	InvoiceCurrency              string                          `xml:"InvoiceCurrency,attr"`
+	Language                     string                          `xml:"Language,attr"`
+	InvoiceNumber                string                          `xml:"InvoiceNumber"`
+	InvoiceDate                  string                          `xml:"InvoiceDate"`
+	CancelledOriginalDocument    *EbCancelledOriginalDocument    `xml:"CancelledOriginalDocument,omitempty"` // Only for cancellations (Storno)
+	RelatedDocument              []EbRelatedDocument             `xml:"RelatedDocument,omitempty"`           // References to earlier invoices
+	Delivery                     *EbDelivery                     `xml:"Delivery,omitempty"`                  // Optional delivery information
+	Biller                       EbBiller                        `xml:"Biller"`
+	InvoiceRecipient             EbRecipient                     `xml:"InvoiceRecipient"`
+	OrderingParty                *EbOrderingParty                `xml:"OrderingParty,omitempty"` // Only where it differs from the recipient
+	Details                      EbDetails                       `xml:"Details"`
+	ReductionAndSurchargeDetails *EbReductionAndSurchargeDetails `xml:"ReductionAndSurchargeDetails,omitempty"` // Document level adjustments
+	Tax                          EbTax                           `xml:"Tax"`                                    // REQUIRED after Details - contains tax summary
+	TotalGrossAmount             string                          `xml:"TotalGrossAmount"`                       // Direct child of Invoice
+	PrepaidAmount                string                          `xml:"PrepaidAmount,omitempty"`                // Sum of prepayments
+	PayableAmount                string                          `xml:"PayableAmount"`                          // Direct child of Invoice
+	PaymentMethod                EbPaymentMethod                 `xml:"PaymentMethod"`                          // PaymentMethod (not PaymentInstructions)
+	PaymentConditions            *EbPaymentConditions            `xml:"PaymentConditions,omitempty"`            // Due date and Skonto
+	// Note: Extensions removed - not in official ebInterface 6.1 example
+	// Optional elements after PaymentConditions: Comment, Extension (singular)
+}
+
+type EbDetails struct {
+	ItemList EbItemList `xml:"ItemList"`
+}
+
+type EbItemList struct {
+	Items []EbItem `xml:"ListLineItem"`
+}
+
+// EbItem represents a single line item in the invoice.
+// Element order: Description, ArticleNumber (optional), Quantity, UnitPrice, ReductionAndSurchargeListLineItemDetails (optional),
+// Delivery (optional), InvoiceRecipientsOrderReference (optional), TaxItem, LineItemAmount
+type EbItem struct {
+	Description                              string                                      `xml:"Description"`
+	ArticleNumber                            []EbArticleNumber                           `xml:"ArticleNumber,omitempty"`
+	Quantity                                 EbQuantity                                  `xml:"Quantity"`
+	UnitPrice                                string                                      `xml:"UnitPrice"` // Decimal string (e.g., "120.00")
+	ReductionAndSurchargeListLineItemDetails *EbReductionAndSurchargeListLineItemDetails `xml:"ReductionAndSurchargeListLineItemDetails,omitempty"`
+	Delivery                                 *EbDelivery                                 `xml:"Delivery,omitempty"`
+	InvoiceRecipientsOrderReference          *EbOrderReferenceItem                       `xml:"InvoiceRecipientsOrderReference,omitempty"`
+	TaxItem                                  EbTaxItem                                   `xml:"TaxItem"`
+	LineItemAmount                           string                                      `xml:"LineItemAmount"` // Decimal string (e.g., "1200.00") - MUST come after TaxItem
+}
+
+// EbTaxItem represents tax information for a line item (inside Details/ListLineItem).
+// Element order: TaxableAmount, TaxPercent, Comment
+// Note: In Details, TaxItem does NOT have TaxAmount - only TaxableAmount and TaxPercent
+type EbTaxItem struct {
+	TaxableAmount string       `xml:"TaxableAmount"`     // Decimal string (e.g., "1200.00") - net amount for the line
+	TaxPercent    EbTaxPercent `xml:"TaxPercent"`        // Tax rate with category code attribute (e.g., <TaxPercent TaxCategoryCode="S">20</TaxPercent>)
+	Comment       string       `xml:"Comment,omitempty"` // Tax exemption reason of untaxed categories
+}
+
+// EbTaxPercent represents the tax rate with category code as an attribute.
+type EbTaxPercent struct {
+	TaxCategoryCode string  `xml:"TaxCategoryCode,attr"` // e.g., S
+	Value           float64 `xml:",chardata"`            // e.g., 20
+}
+
+// EbReductionAndSurchargeDetails lists the document level reductions and surcharges.
+type EbReductionAndSurchargeDetails struct {
+	Entries []EbReductionAndSurcharge // Reduction or Surcharge
+}
+
+// EbReductionAndSurcharge is a document level reduction or surcharge; XMLName selects the kind.
+// Element order: BaseAmount, Percentage, Amount, Comment, TaxItem
+// TaxItem/TaxableAmount equals Amount; the sign follows from the element name.
+type EbReductionAndSurcharge struct {
+	XMLName    xml.Name
+	BaseAmount string    `xml:"BaseAmount"`
+	Percentage string    `xml:"Percentage,omitempty"`
+	Amount     string    `xml:"Amount"`
+	Comment    string    `xml:"Comment,omitempty"`
+	TaxItem    EbTaxItem `xml:"TaxItem"`
+}
+
+// EbTax represents the top-level tax element (required after Details).
+// Contains a list of TaxItem elements with full tax information.
+type EbTax struct {
+	TaxItems []EbTaxItemSummary `xml:"TaxItem"` // TaxItem elements with TaxableAmount, TaxPercent, TaxAmount
+}
+
+// EbTaxItemSummary represents tax summary information (inside Tax element).
+// Element order: TaxableAmount, TaxPercent, TaxAmount, Comment
+// Note: In Tax element, TaxItem includes TaxAmount
+type EbTaxItemSummary struct {
+	TaxableAmount string       `xml:"TaxableAmount"`     // Decimal string (e.g., "1200.00") - MUST be FIRST
+	TaxPercent    EbTaxPercent `xml:"TaxPercent"`        // MUST be SECOND - Tax rate with category code attribute
+	TaxAmount     string       `xml:"TaxAmount"`         // Decimal string (e.g., "240.00") - MUST be THIRD
+	Comment       string       `xml:"Comment,omitempty"` // Tax exemption reason of untaxed categories
+}
+
+// buildEbInterface6 assembles an ebInterface 6.x document in the given namespace.
+func buildEbInterface6(inv InvoiceJSON, t invoiceTotals, namespace string) *EbInterfaceInvoice {
+	items := make([]EbItem, 0, len(inv.Items))
+	for i, li := range inv.Items {
+		lt := t.Lines[i]
+		items = append(items, EbItem{
+			Description:                              li.Description,
+			ArticleNumber:                            buildEbArticleNumbers(li),
+			Quantity:                                 buildEbQuantity(lt),
+			UnitPrice:                                formatPrice(lt.UnitPrice),
+			ReductionAndSurchargeListLineItemDetails: buildEbLineAdjustments(lt),
+			Delivery:                                 buildEbDelivery(inv, li.Delivery),
+			InvoiceRecipientsOrderReference:          buildEbLineOrderReference(inv, i),
+			TaxItem: EbTaxItem{
+				TaxableAmount: formatCentsAsDecimal(lt.NetCts), // Net amount for the line (before tax)
+				TaxPercent: EbTaxPercent{
+					TaxCategoryCode: lt.TaxCategory,
+					Value:           lt.TaxRate,
+				},
+				// Note: TaxItem in Details does NOT have TaxAmount
+				Comment: lt.TaxExemptionReason,
+			},
+			LineItemAmount: formatCentsAsDecimal(lt.NetCts), // Line item NET amount (before tax) - MUST come after TaxItem
+		})
+	}
+
+	summary := make([]EbTaxItemSummary, 0, len(t.Buckets))
+	for _, b := range t.Buckets {
+		summary = append(summary, EbTaxItemSummary{
+			TaxableAmount: formatCentsAsDecimal(b.TaxableCts), // MUST be FIRST
+			TaxPercent: EbTaxPercent{
+				TaxCategoryCode: b.Category,
+				Value:           b.Rate,
+			}, // MUST be SECOND
+			TaxAmount: formatCentsAsDecimal(b.TaxCts), // MUST be THIRD
+			Comment:   b.ExemptionReason,
+		})
+	}
+
+	doc := &EbInterfaceInvoice{
+		XMLName:          xml.Name{Space: namespace, Local: "Invoice"},
+		GeneratingSystem: generatingSystem,
+		DocumentType:     ebDocumentType(inv),
+		InvoiceCurrency:  invoiceCurrency,
+		Language:         "de",
+		InvoiceNumber:    inv.InvoiceNumber,
+		InvoiceDate:      inv.InvoiceDate,
+		Delivery:         buildEbDelivery(inv, inv.Delivery),
+		Biller:           buildEbBiller(inv),
+		InvoiceRecipient: buildEbRecipient(inv),
+		OrderingParty:    buildEbOrderingParty(inv),
+		Details: EbDetails{
+			ItemList: EbItemList{
+				Items: items,
+			},
+		},
+		Tax: EbTax{
+			TaxItems: summary, // Tax summary items with TaxableAmount, TaxPercent, TaxAmount
+		},
+		TotalGrossAmount:  formatCentsAsDecimal(t.GrossCts),   // Direct child of Invoice
+		PayableAmount:     formatCentsAsDecimal(t.PayableCts), // Direct child of Invoice
+		PaymentMethod:     buildEbPaymentMethod(inv),
+		PaymentConditions: buildEbPaymentConditions(t),
+	}
+	doc.CancelledOriginalDocument, doc.RelatedDocument = buildEbDocumentReferences(inv)
+	if t.PrepaidCts != 0 {
+		doc.PrepaidAmount = formatCentsAsDecimal(t.PrepaidCts)
+	}
+	if len(t.Adjustments) > 0 {
+		doc.ReductionAndSurchargeDetails = &EbReductionAndSurchargeDetails{}
+		for _, at := range t.Adjustments {
+			doc.ReductionAndSurchargeDetails.Entries = append(doc.ReductionAndSurchargeDetails.Entries, EbReductionAndSurcharge{
+				XMLName:    ebAdjustmentName(at, ""),
+				BaseAmount: formatCentsAsDecimal(at.BaseCts),
+				Percentage: adjustmentPercentage(at),
+				Amount:     formatCentsAsDecimal(at.AmountCts),
+				Comment:    at.Reason,
+				TaxItem: EbTaxItem{
+					TaxableAmount: formatCentsAsDecimal(at.AmountCts),
+					TaxPercent: EbTaxPercent{
+						TaxCategoryCode: at.TaxCategory,
+						Value:           at.TaxRate,
+					},
+					Comment: at.TaxExemptionReason,
+				},
+			})
+		}
+	}
+	return doc
+}
@@ -14,14 +14,19 @@ const (
 	ErrCodeRateLimitExceeded  = "RATE_LIMIT_EXCEEDED"
 	ErrCodeInvalidJSON        = "INVALID_JSON"
 	ErrCodeValidationError    = "VALIDATION_ERROR"
+	ErrCodeVATIDChecksum      = "VAT_ID_CHECKSUM_INVALID" // Well-formed VAT ID with a wrong check digit
 	ErrCodeInternalError      = "INTERNAL_ERROR"
+	ErrCodeSchemaValidation   = "SCHEMA_VALIDATION_ERROR"
+	ErrCodeInvalidXML         = "INVALID_XML"
+	ErrCodeNotAcceptable      = "NOT_ACCEPTABLE"
 )
 
 // APIError represents a standardized error response
 type APIError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code       string      `json:"code"`
+	Message    string      `json:"message"`
+	Details    string      `json:"details,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
 }
 
 // ErrorResponse wraps an APIError
@@ -48,6 +53,25 @@ func writeError(w http.ResponseWriter, statusCode int, code, message, details st
 	}
 }
 
+// writeViolations writes a standardized error response that lists every validation finding
+func writeViolations(w http.ResponseWriter, statusCode int, code, message string, violations []Violation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	response := ErrorResponse{
+		Error: APIError{
+			Code:       code,
+			Message:    message,
+			Details:    fmt.Sprintf("%d violation(s) found", len(violations)),
+			Violations: violations,
+		},
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, message, statusCode)
+	}
+}
+
 // writeErrorf is a convenience function for formatted error messages
 func writeErrorf(w http.ResponseWriter, statusCode int, code, message string, args ...interface{}) {
 	details := fmt.Sprintf(message, args...)
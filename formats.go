@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultOutputFormat is produced when a request selects neither a format nor a media type.
+const DefaultOutputFormat = "ebinterface"
+
+// renderOptions carries the per-request choices that only some formats use.
+type renderOptions struct {
+	EbInterfaceVersion string
+	CIIProfile         string
+	Embed              string // XML syntax embedded into a PDF/A-3: ebinterface or cii
+}
+
+// outputFormat is one document syntax /generate can produce from an InvoiceJSON.
+// Formats register themselves from init, like the ebInterface versions.
+type outputFormat struct {
+	Name        string
+	ContentType string   // Content-Type of the response
+	MediaTypes  []string // Accept header media types selecting this format
+	// Check rejects invoices that are valid in general but lack data the format requires.
+	Check  func(inv InvoiceJSON, opts renderOptions) error
+	Render func(inv InvoiceJSON, opts renderOptions) ([]byte, error)
+	// Validate checks the rendered document, e.g. against an embedded schema. Optional.
+	Validate func(doc []byte) error
+}
+
+var outputFormats = map[string]outputFormat{}
+
+func registerOutputFormat(f outputFormat) {
+	outputFormats[f.Name] = f
+}
+
+// supportedOutputFormats lists the registered format names in ascending order.
+func supportedOutputFormats() []string {
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	registerOutputFormat(outputFormat{
+		Name:        "ebinterface",
+		ContentType: "application/xml; charset=utf-8",
+		MediaTypes:  []string{"application/xml", "text/xml"},
+		Render: func(inv InvoiceJSON, opts renderOptions) ([]byte, error) {
+			return TransformToEbInterfaceVersion(inv, opts.EbInterfaceVersion)
+		},
+		Validate: ValidateEbInterface,
+	})
+}
+
+// renderOptionsFromRequest reads the format specific query parameters and
+// rejects those that do not apply to the chosen format.
+func renderOptionsFromRequest(r *http.Request, f outputFormat) (renderOptions, error) {
+	q := r.URL.Query()
+	var opts renderOptions
+
+	// ?embed=ebinterface|cii selects the XML inside a PDF/A-3; default is ebInterface.
+	syntax := f.Name
+	if f.Name == "pdfa" {
+		opts.Embed = strings.ToLower(q.Get("embed"))
+		if opts.Embed == "" {
+			opts.Embed = "ebinterface"
+		}
+		if opts.Embed != "ebinterface" && opts.Embed != "cii" {
+			return opts, fmt.Errorf("unsupported embed %q (supported: ebinterface, cii)", q.Get("embed"))
+		}
+		syntax = opts.Embed
+	} else if q.Get("embed") != "" {
+		return opts, fmt.Errorf("embed only applies to format=pdfa")
+	}
+
+	// ?version=5.0|6.0|6.1 selects the ebInterface release; default is 6.1.
+	if v := q.Get("version"); v != "" && syntax != "ebinterface" {
+		return opts, fmt.Errorf("version only applies to ebInterface output")
+	}
+	version, err := lookupEbInterfaceVersion(q.Get("version"))
+	if err != nil {
+		return opts, err
+	}
+	opts.EbInterfaceVersion = version.Version
+
+	// ?cii_profile=en16931|xrechnung selects the CII guideline; default is en16931.
+	if p := q.Get("cii_profile"); p != "" && syntax != "cii" {
+		return opts, fmt.Errorf("cii_profile only applies to CII output")
+	}
+	profile, err := lookupCIIProfile(q.Get("cii_profile"))
+	if err != nil {
+		return opts, err
+	}
+	opts.CIIProfile = profile.Name
+	return opts, nil
+}
+
+// errNotAcceptable is returned by negotiateOutputFormat when the Accept header
+// names no media type any registered format can produce.
+type errNotAcceptable struct {
+	accept string
+}
+
+func (e *errNotAcceptable) Error() string {
+	return fmt.Sprintf("no output format matches Accept %q (supported formats: %s)", e.accept, strings.Join(supportedOutputFormats(), ", "))
+}
+
+// negotiateOutputFormat picks the output format of a request. An explicit
+// ?format= wins; otherwise the Accept header is matched in order of preference.
+// A missing Accept header or a wildcard selects DefaultOutputFormat.
+func negotiateOutputFormat(r *http.Request) (outputFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		f, ok := outputFormats[strings.ToLower(name)]
+		if !ok {
+			return outputFormat{}, fmt.Errorf("unsupported format %q (supported: %s)", name, strings.Join(supportedOutputFormats(), ", "))
+		}
+		return f, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return outputFormats[DefaultOutputFormat], nil
+	}
+	for _, mediaType := range parseAccept(accept) {
+		if mediaType == "*/*" || mediaType == "application/*" {
+			return outputFormats[DefaultOutputFormat], nil
+		}
+		// Prefer the default format when several formats share a media type.
+		if matchesMediaType(outputFormats[DefaultOutputFormat], mediaType) {
+			return outputFormats[DefaultOutputFormat], nil
+		}
+		for _, name := range supportedOutputFormats() {
+			if f := outputFormats[name]; matchesMediaType(f, mediaType) {
+				return f, nil
+			}
+		}
+	}
+	return outputFormat{}, &errNotAcceptable{accept: accept}
+}
+
+func matchesMediaType(f outputFormat, mediaType string) bool {
+	for _, mt := range f.MediaTypes {
+		if mt == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAccept returns the media ranges of an Accept header ordered by
+// descending quality. Ranges with q=0 are dropped.
+func parseAccept(header string) []string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	out := make([]string, 0, len(ranges))
+	for _, mr := range ranges {
+		out = append(out, mr.mediaType)
+	}
+	return out
+}
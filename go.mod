@@ -4,10 +4,13 @@ go 1.21
 
 require (
 	github.com/sendgrid/sendgrid-go v3.16.1+incompatible
+	github.com/shopspring/decimal v1.4.0
 	github.com/stripe/stripe-go/v76 v76.25.0
+	golang.org/x/image v0.18.0
 )
 
 require (
 	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/sendgrid/rest v2.6.9+incompatible // indirect
+	golang.org/x/text v0.16.0 // indirect
 )
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ibanFormat is the account structure of one SEPA country as published in
+// the SWIFT IBAN registry.
+type ibanFormat struct {
+	Length int            // Total IBAN length including country code and check digits
+	BBAN   *regexp.Regexp // Basic bank account number after the check digits
+}
+
+// newIBANFormat compiles a BBAN structure in registry notation, e.g. "5n11n":
+// n digits, a upper case letters, c letters or digits.
+func newIBANFormat(length int, structure string) ibanFormat {
+	classes := map[byte]string{'n': `\d`, 'a': `[A-Z]`, 'c': `[A-Z0-9]`}
+	pattern := "^"
+	for _, part := range regexp.MustCompile(`\d+[nac]`).FindAllString(structure, -1) {
+		pattern += classes[part[len(part)-1]] + "{" + part[:len(part)-1] + "}"
+	}
+	return ibanFormat{Length: length, BBAN: regexp.MustCompile(pattern + "$")}
+}
+
+var ibanCheckDigitsRegex = regexp.MustCompile(`^\d{2}$`)
+
+// sepaIBANFormats lists the countries of the SEPA scheme. Overseas departments
+// and dependencies use the IBAN of their home country (FR, GB, FI).
+var sepaIBANFormats = map[string]ibanFormat{
+	"AD": newIBANFormat(24, "4n4n12c"),
+	"AL": newIBANFormat(28, "8n16c"),
+	"AT": newIBANFormat(20, "5n11n"),
+	"BE": newIBANFormat(16, "3n7n2n"),
+	"BG": newIBANFormat(22, "4a4n2n8c"),
+	"CH": newIBANFormat(21, "5n12c"),
+	"CY": newIBANFormat(28, "3n5n16c"),
+	"CZ": newIBANFormat(24, "4n6n10n"),
+	"DE": newIBANFormat(22, "8n10n"),
+	"DK": newIBANFormat(18, "4n9n1n"),
+	"EE": newIBANFormat(20, "2n2n11n1n"),
+	"ES": newIBANFormat(24, "4n4n1n1n10n"),
+	"FI": newIBANFormat(18, "3n11n"),
+	"FR": newIBANFormat(27, "5n5n11c2n"),
+	"GB": newIBANFormat(22, "4a6n8n"),
+	"GI": newIBANFormat(23, "4a15c"),
+	"GR": newIBANFormat(27, "3n4n16c"),
+	"HR": newIBANFormat(21, "7n10n"),
+	"HU": newIBANFormat(28, "3n4n1n15n1n"),
+	"IE": newIBANFormat(22, "4a6n8n"),
+	"IS": newIBANFormat(26, "4n2n6n10n"),
+	"IT": newIBANFormat(27, "1a5n5n12c"),
+	"LI": newIBANFormat(21, "5n12c"),
+	"LT": newIBANFormat(20, "5n11n"),
+	"LU": newIBANFormat(20, "3n13c"),
+	"LV": newIBANFormat(21, "4a13c"),
+	"MC": newIBANFormat(27, "5n5n11c2n"),
+	"MD": newIBANFormat(24, "2c18c"),
+	"ME": newIBANFormat(22, "3n13n2n"),
+	"MK": newIBANFormat(19, "3n10c2n"),
+	"MT": newIBANFormat(31, "4a5n18c"),
+	"NL": newIBANFormat(18, "4a10n"),
+	"NO": newIBANFormat(15, "4n6n1n"),
+	"PL": newIBANFormat(28, "8n16n"),
+	"PT": newIBANFormat(25, "4n4n11n2n"),
+	"RO": newIBANFormat(24, "4a16c"),
+	"RS": newIBANFormat(22, "3n13n2n"),
+	"SE": newIBANFormat(24, "3n16n1n"),
+	"SI": newIBANFormat(19, "5n8n2n"),
+	"SK": newIBANFormat(24, "4n6n10n"),
+	"SM": newIBANFormat(27, "1a5n5n12c"),
+	"VA": newIBANFormat(22, "3n15n"),
+}
+
+// bicCountriesByIBAN lists the BIC countries accepted besides the IBAN
+// country itself, for territories that use their home country's IBAN.
+var bicCountriesByIBAN = map[string][]string{
+	"FR": {"GF", "GP", "MQ", "RE", "YT", "PM", "BL", "MF"},
+	"GB": {"JE", "GG", "IM"},
+	"FI": {"AX"},
+}
+
+// validateIBAN checks the length and structure of a SEPA IBAN and its ISO
+// 7064 mod-97 check digits. Spaces are ignored.
+func validateIBAN(iban string) error {
+	iban = strings.ReplaceAll(iban, " ", "")
+	if len(iban) < 4 {
+		return fmt.Errorf("iban must start with a country code and two check digits (e.g., AT611904300234573201)")
+	}
+	country := iban[:2]
+	f, ok := sepaIBANFormats[country]
+	if !ok {
+		return fmt.Errorf("iban country %s is not part of the SEPA scheme", country)
+	}
+	if len(iban) != f.Length {
+		return fmt.Errorf("iban for %s must be %d characters long", country, f.Length)
+	}
+	if !ibanCheckDigitsRegex.MatchString(iban[2:4]) || !f.BBAN.MatchString(iban[4:]) {
+		return fmt.Errorf("iban does not match the account format of %s", country)
+	}
+	if mod97(iban[4:]+iban[:4]) != 1 {
+		return fmt.Errorf("iban has invalid check digits")
+	}
+	return nil
+}
+
+// validateBICCountry checks that the BIC belongs to a bank in the IBAN's country.
+// Both are expected to be well-formed.
+func validateBICCountry(bic, iban string) error {
+	iban = strings.ReplaceAll(iban, " ", "")
+	if len(bic) < 6 || len(iban) < 2 {
+		return nil
+	}
+	bicCountry, ibanCountry := bic[4:6], iban[:2]
+	if bicCountry == ibanCountry {
+		return nil
+	}
+	for _, c := range bicCountriesByIBAN[ibanCountry] {
+		if c == bicCountry {
+			return nil
+		}
+	}
+	return fmt.Errorf("bic country %s does not match iban country %s", bicCountry, ibanCountry)
+}
+
+// mod97 returns the ISO 7064 MOD 97-10 remainder of s, with letters
+// counted as 10 (A) to 35 (Z).
+func mod97(s string) int {
+	r := 0
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			r = (r*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			r = (r*100 + int(c-'A') + 10) % 97
+		}
+	}
+	return r
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	tests := []struct {
+		iban    string
+		wantErr string
+	}{
+		{"AT611904300234573201", ""},
+		{"AT61 1904 3002 3457 3201", ""},
+		{"DE89370400440532013000", ""},
+		{"GB29NWBK60161331926819", ""},
+		{"FR1420041010050500013M02606", ""},
+		{"BE68539007547034", ""},
+		{"NL91ABNA0417164300", ""},
+		{"CH9300762011623852957", ""},
+		{"IT60X0542811101000000123456", ""},
+		{"ES9121000418450200051332", ""},
+		{"NO9386011117947", ""},
+		{"MT84MALT011000012345MTLCAST001S", ""},
+		{"SE4550000000058398257466", ""},
+		{"PL61109010140000071219812874", ""},
+		{"AT611904300234573202", "invalid check digits"},
+		{"AT161904300234573201", "invalid check digits"},
+		{"AT61190430023457320", "must be 20 characters long"},
+		{"ATXX1904300234573201", "account format of AT"},
+		{"DE89370400440532O13000", "account format of DE"},
+		{"US12345678", "not part of the SEPA scheme"},
+		{"at611904300234573201", "not part of the SEPA scheme"},
+		{"AT", "must start with a country code"},
+	}
+	for _, tt := range tests {
+		err := validateIBAN(tt.iban)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validateIBAN(%q) = %v", tt.iban, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validateIBAN(%q) = %v, want an error containing %q", tt.iban, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateBIC(t *testing.T) {
+	tests := []struct {
+		bic     string
+		wantErr bool
+	}{
+		{"BKAUATWW", false},
+		{"GIBAATWWXXX", false},
+		{"BKAUATW", true},
+		{"BKAUATWWXX", true},
+		{"bkauatww", true},
+		{"1KAUATWW", true},
+	}
+	for _, tt := range tests {
+		if err := validateBIC(tt.bic); (err != nil) != tt.wantErr {
+			t.Errorf("validateBIC(%q) = %v, want error %v", tt.bic, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateBICCountry(t *testing.T) {
+	tests := []struct {
+		bic, iban string
+		wantErr   bool
+	}{
+		{"BKAUATWW", "AT611904300234573201", false},
+		{"COBADEFF", "AT611904300234573201", true},
+		{"BNPAGPGP", "FR1420041010050500013M02606", false}, // Guadeloupe uses French IBANs
+		{"BNPAGPGP", "DE89370400440532013000", true},
+		{"JERSJESH", "GB29NWBK60161331926819", false}, // Jersey uses British IBANs
+	}
+	for _, tt := range tests {
+		if err := validateBICCountry(tt.bic, tt.iban); (err != nil) != tt.wantErr {
+			t.Errorf("validateBICCountry(%q, %q) = %v, want error %v", tt.bic, tt.iban, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateInvoicePayment(t *testing.T) {
+	tests := []struct {
+		name      string
+		iban, bic string
+		wantErr   string
+	}{
+		{"valid", "AT611904300234573201", "BKAUATWW", ""},
+		{"check digits", "AT611904300234573202", "BKAUATWW", "iban has invalid check digits"},
+		{"bic format", "AT611904300234573201", "BKAUAT", "bic must be 8 or 11 characters"},
+		{"bic country", "AT611904300234573201", "COBADEFF", "bic country DE does not match iban country AT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readGoldenInvoice(t)
+			inv.Payment.IBAN, inv.Payment.BIC = tt.iban, tt.bic
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
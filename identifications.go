@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Further identification types (ebInterface IdentificationType) accepted for
+// the biller and the recipient.
+const (
+	IdentificationFN             = "FN"             // Firmenbuchnummer, e.g. 123456a
+	IdentificationFBG            = "FBG"            // Firmenbuchgericht, e.g. Handelsgericht Wien
+	IdentificationFS             = "FS"             // Firmensitz
+	IdentificationGLN            = "GLN"            // GS1 Global Location Number
+	IdentificationDVR            = "DVR"            // Datenverarbeitungsregisternummer
+	IdentificationSupplierNumber = "SupplierNumber" // Supplier number assigned by a recipient, besides biller_id
+)
+
+var supportedIdentificationTypes = []string{
+	IdentificationFN, IdentificationFBG, IdentificationFS,
+	IdentificationGLN, IdentificationDVR, IdentificationSupplierNumber,
+}
+
+var (
+	fnRegex  = regexp.MustCompile(`^\d{1,6}[a-z]$`)
+	glnRegex = regexp.MustCompile(`^\d{13}$`)
+	dvrRegex = regexp.MustCompile(`^\d{7}$`)
+)
+
+// FurtherIdentificationJSON is an identifier of a party besides its VAT ID.
+type FurtherIdentificationJSON struct {
+	Type  string `json:"type"` // FN, FBG, FS, GLN, DVR or SupplierNumber
+	Value string `json:"value"`
+}
+
+// validateIdentificationValue checks the value of one identification against its type.
+func validateIdentificationValue(id FurtherIdentificationJSON) error {
+	switch id.Type {
+	case IdentificationFN:
+		if !fnRegex.MatchString(id.Value) {
+			return fmt.Errorf("FN must be up to 6 digits followed by the check letter, without the FN prefix (e.g., 123456a)")
+		}
+	case IdentificationGLN:
+		if !glnRegex.MatchString(id.Value) {
+			return fmt.Errorf("GLN must have 13 digits")
+		}
+		if !checkGTIN(id.Value) {
+			return fmt.Errorf("GLN %s has an invalid check digit", id.Value)
+		}
+	case IdentificationDVR:
+		if !dvrRegex.MatchString(id.Value) {
+			return fmt.Errorf("DVR number must have 7 digits")
+		}
+	}
+	return nil
+}
+
+// validateFurtherIdentifications checks the identifications of a party; field
+// is their JSON path for error messages. Each type may occur once, except
+// supplier numbers assigned by different recipients. A Firmenbuchnummer is
+// only meaningful with its court, so FN and FBG come together (§ 14 UGB).
+func validateFurtherIdentifications(ids []FurtherIdentificationJSON, field string) error {
+	seen := map[string]bool{}
+	for i, id := range ids {
+		switch {
+		case !isSupportedIdentificationType(id.Type):
+			return fmt.Errorf("%s[%d].type %q is not supported (supported: %s)", field, i, id.Type, strings.Join(supportedIdentificationTypes, ", "))
+		case strings.TrimSpace(id.Value) == "":
+			return fmt.Errorf("%s[%d].value is required", field, i)
+		case seen[id.Type] && id.Type != IdentificationSupplierNumber:
+			return fmt.Errorf("%s[%d]: type %s is given more than once", field, i, id.Type)
+		}
+		if err := validateIdentificationValue(id); err != nil {
+			return fmt.Errorf("%s[%d].value: %w", field, i, err)
+		}
+		seen[id.Type] = true
+	}
+	if seen[IdentificationFN] != seen[IdentificationFBG] {
+		return fmt.Errorf("%s: types FN and FBG must be given together", field)
+	}
+	return nil
+}
+
+func isSupportedIdentificationType(typ string) bool {
+	for _, t := range supportedIdentificationTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// furtherIdentification returns the first value of the given type, or "".
+func furtherIdentification(ids []FurtherIdentificationJSON, typ string) string {
+	for _, id := range ids {
+		if id.Type == typ {
+			return id.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateFurtherIdentifications(t *testing.T) {
+	ids := func(pairs ...string) []FurtherIdentificationJSON {
+		var out []FurtherIdentificationJSON
+		for i := 0; i < len(pairs); i += 2 {
+			out = append(out, FurtherIdentificationJSON{Type: pairs[i], Value: pairs[i+1]})
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"company register", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("FN", "123456a", "FBG", "Handelsgericht Wien", "FS", "Wien")
+		}, ""},
+		{"short fn", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("FN", "1a", "FBG", "Landesgericht Linz")
+		}, ""},
+		{"fn with prefix", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("FN", "FN 123456a", "FBG", "Handelsgericht Wien")
+		}, "biller.further_identifications[0].value: FN must be up to 6 digits followed by the check letter"},
+		{"fn without check letter", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("FN", "123456", "FBG", "Handelsgericht Wien")
+		}, "biller.further_identifications[0].value: FN must be"},
+		{"fn too long", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("FN", "1234567a", "FBG", "Handelsgericht Wien")
+		}, "biller.further_identifications[0].value: FN must be"},
+		{"fn upper case letter", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("FN", "123456A", "FBG", "Handelsgericht Wien")
+		}, "biller.further_identifications[0].value: FN must be"},
+		{"fn without court", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("FN", "123456a") }, "biller.further_identifications: types FN and FBG must be given together"},
+		{"court without fn", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("FBG", "Handelsgericht Wien") }, "biller.further_identifications: types FN and FBG must be given together"},
+		{"seat", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("FS", "Graz") }, ""},
+
+		{"gln", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("GLN", "9012345000004") }, ""},
+		{"gln check digit", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("GLN", "9012345000005") }, "biller.further_identifications[0].value: GLN 9012345000005 has an invalid check digit"},
+		{"gln length", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("GLN", "901234500000") }, "biller.further_identifications[0].value: GLN must have 13 digits"},
+		{"gtin-14 as gln", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("GLN", "10012345678902") }, "GLN must have 13 digits"},
+		{"gln letters", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("GLN", "901234500000A") }, "GLN must have 13 digits"},
+
+		{"dvr", func(inv *InvoiceJSON) { inv.Recipient.FurtherIdentifications = ids("DVR", "0000001") }, ""},
+		{"dvr length", func(inv *InvoiceJSON) { inv.Recipient.FurtherIdentifications = ids("DVR", "123456") }, "recipient.further_identifications[0].value: DVR number must have 7 digits"},
+		{"dvr letters", func(inv *InvoiceJSON) { inv.Recipient.FurtherIdentifications = ids("DVR", "DVR0001") }, "DVR number must have 7 digits"},
+
+		{"supplier numbers", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("SupplierNumber", "L-77", "SupplierNumber", "4711")
+		}, ""},
+		{"free-form supplier number", func(inv *InvoiceJSON) {
+			inv.Recipient.FurtherIdentifications = ids("SupplierNumber", "Lieferant 77/B")
+		}, ""},
+
+		{"unsupported type", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("UID", "ATU13585627") }, `biller.further_identifications[0].type "UID" is not supported (supported: FN, FBG, FS, GLN, DVR, SupplierNumber)`},
+		{"lower case type", func(inv *InvoiceJSON) { inv.Biller.FurtherIdentifications = ids("gln", "9012345000004") }, `biller.further_identifications[0].type "gln" is not supported`},
+		{"empty value", func(inv *InvoiceJSON) { inv.Recipient.FurtherIdentifications = ids("FS", " ") }, "recipient.further_identifications[0].value is required"},
+		{"repeated type", func(inv *InvoiceJSON) {
+			inv.Biller.FurtherIdentifications = ids("GLN", "9012345000004", "GLN", "4006381333931")
+		}, "biller.further_identifications[1]: type GLN is given more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			tt.edit(&inv)
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBillerFooterDE(t *testing.T) {
+	b := BillerJSON{Name: "Your Startup GmbH", VATID: "ATU13585627", BillerID: "2026001", FurtherIdentifications: []FurtherIdentificationJSON{
+		{Type: IdentificationGLN, Value: "9012345000004"},
+		{Type: IdentificationFBG, Value: "Handelsgericht Wien"},
+		{Type: IdentificationFN, Value: "123456a"},
+		{Type: IdentificationFS, Value: "Wien"},
+		{Type: IdentificationSupplierNumber, Value: "L-77"},
+	}}
+	want := "Your Startup GmbH · Sitz Wien · FN 123456a · Handelsgericht Wien · UID ATU13585627 · GLN 9012345000004"
+	if got := billerFooterDE(b); got != want {
+		t.Errorf("billerFooterDE = %q, want %q", got, want)
+	}
+	if got := supplierNumbers(b); strings.Join(got, ",") != "2026001,L-77" {
+		t.Errorf("supplierNumbers = %q", got)
+	}
+}
+
+func TestFurtherIdentificationOutput(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Biller.FurtherIdentifications = []FurtherIdentificationJSON{
+		{Type: IdentificationFN, Value: "123456a"},
+		{Type: IdentificationFBG, Value: "Handelsgericht Wien"},
+		{Type: IdentificationGLN, Value: "9012345000004"},
+	}
+	inv.Recipient.FurtherIdentifications = []FurtherIdentificationJSON{
+		{Type: IdentificationSupplierNumber, Value: "L-77"},
+		{Type: IdentificationDVR, Value: "0000001"},
+	}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range supportedEbInterfaceVersions() {
+		doc, err := TransformToEbInterfaceVersion(inv, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateEbInterface(doc); err != nil {
+			t.Errorf("%s schema: %v", version, err)
+		}
+		// Identifications follow the VAT ID (and 5.0's InvoiceRecipientsBillerID)
+		// and precede the address and the order reference.
+		for _, want := range []string{
+			`<FurtherIdentification IdentificationType="FN">123456a</FurtherIdentification>
+    <FurtherIdentification IdentificationType="FBG">Handelsgericht Wien</FurtherIdentification>
+    <FurtherIdentification IdentificationType="GLN">9012345000004</FurtherIdentification>
+    <Address>`,
+			`<VATIdentificationNumber>ATU38516405</VATIdentificationNumber>
+    <FurtherIdentification IdentificationType="SupplierNumber">L-77</FurtherIdentification>
+    <FurtherIdentification IdentificationType="DVR">0000001</FurtherIdentification>
+    <OrderReference>`,
+		} {
+			if !strings.Contains(string(doc), want) {
+				t.Errorf("%s is missing %s", version, want)
+			}
+		}
+
+		parsed, err := ParseEbInterface(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parsed.Unmapped) > 0 {
+			t.Errorf("%s unmapped: %+v", version, parsed.Unmapped)
+		}
+		got := parsed.Invoice
+		if !slices.Equal(got.Biller.FurtherIdentifications, inv.Biller.FurtherIdentifications) ||
+			!slices.Equal(got.Recipient.FurtherIdentifications, inv.Recipient.FurtherIdentifications) {
+			t.Errorf("%s parsed as %+v and %+v", version, got.Biller.FurtherIdentifications, got.Recipient.FurtherIdentifications)
+		}
+	}
+}
@@ -0,0 +1,629 @@
+package main
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	registerOutputFormat(outputFormat{
+		Name:        "pdf",
+		ContentType: "application/pdf",
+		MediaTypes:  []string{"application/pdf"},
+		Render: func(inv InvoiceJSON, _ renderOptions) ([]byte, error) {
+			return RenderInvoicePDF(inv)
+		},
+	})
+}
+
+// A4 page geometry in points.
+const (
+	pdfPageWidth    = 595.28
+	pdfPageHeight   = 841.89
+	pdfMarginLeft   = 50.0
+	pdfMarginRight  = pdfPageWidth - 50
+	pdfMarginTop    = pdfPageHeight - 50
+	pdfMarginBottom = 70.0 // leaves room for the footer
+)
+
+// Line table columns: left edge of Pos and Description, right edges of the numbers.
+const (
+	colPos         = pdfMarginLeft
+	colDescription = pdfMarginLeft + 25
+	colQuantity    = 345.0
+	colUnitPrice   = 425.0
+	colTaxRate     = 470.0
+	colAmount      = pdfMarginRight
+	descWidth      = colQuantity - colDescription - 60 // leaves room for quantity and unit
+)
+
+// RenderInvoicePDF renders a human readable A4 PDF of the invoice. All amounts
+// come from computeTotals, so the PDF always agrees with the XML formats.
+func RenderInvoicePDF(inv InvoiceJSON) ([]byte, error) {
+	return renderInvoicePDF(inv, nil)
+}
+
+// renderInvoicePDF lays out the invoice and writes the PDF. With an attachment
+// the document is written as PDF/A-3b carrying the attached XML (see pdfa.go).
+func renderInvoicePDF(inv InvoiceJSON, att *pdfAttachment) ([]byte, error) {
+	regular, bold, err := loadPDFFonts()
+	if err != nil {
+		return nil, err
+	}
+	l := &invoiceLayout{regular: regular, bold: bold}
+	l.render(inv, computeTotals(inv))
+
+	d := &pdfDocument{}
+	catalog := d.reserve()
+	pagesRef := d.reserve()
+	fonts := fmt.Sprintf("<< /F1 %d 0 R /F2 %d 0 R >>", regular.embed(d), bold.embed(d))
+
+	kids := make([]string, 0, len(l.pages))
+	for _, page := range l.pages {
+		content := d.addStream("", page.buf.Bytes(), true)
+		ref := d.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font %s >> /Contents %d 0 R >>",
+			pagesRef, pdfNum(pdfPageWidth), pdfNum(pdfPageHeight), fonts, content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", ref))
+	}
+	d.set(pagesRef, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	meta := pdfMetadata{
+		Title:    pdfDocumentTitle(inv) + " " + inv.InvoiceNumber,
+		Author:   inv.Biller.Name,
+		Producer: generatingSystem,
+		Created:  time.Now().UTC(),
+	}
+	catalogExtra := ""
+	if att != nil {
+		catalogExtra = addPDFA3(d, meta, att)
+	}
+	d.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R%s >>", pagesRef, catalogExtra))
+
+	info := d.add(fmt.Sprintf("<< /Title %s /Author %s /Producer %s /CreationDate %s /ModDate %s >>",
+		pdfTextString(meta.Title), pdfTextString(meta.Author), pdfTextString(meta.Producer),
+		pdfDate(meta.Created), pdfDate(meta.Created)))
+	id := md5.Sum([]byte(inv.InvoiceNumber + meta.Created.String()))
+	return d.bytes(fmt.Sprintf("/Root %d 0 R /Info %d 0 R /ID [<%x> <%x>]", catalog, info, id, id)), nil
+}
+
+// pdfMetadata is the document information shared by the Info dictionary and,
+// for PDF/A, the XMP metadata; PDF/A requires both to agree.
+type pdfMetadata struct {
+	Title, Author, Producer string
+	Created                 time.Time
+}
+
+// pdfDate formats t as a PDF date string.
+func pdfDate(t time.Time) string {
+	return "(D:" + t.UTC().Format("20060102150405") + "+00'00')"
+}
+
+// invoiceLayout places the invoice on as many pages as the line table needs.
+type invoiceLayout struct {
+	regular, bold *pdfFont
+	pages         []*pdfCanvas
+	page          *pdfCanvas
+	y             float64 // baseline of the next line on the current page
+}
+
+func (l *invoiceLayout) newPage() {
+	l.page = &pdfCanvas{}
+	l.pages = append(l.pages, l.page)
+	l.y = pdfMarginTop
+}
+
+// fits reports whether h points still fit above the bottom margin.
+func (l *invoiceLayout) fits(h float64) bool {
+	return l.y-h >= pdfMarginBottom
+}
+
+func (l *invoiceLayout) render(inv InvoiceJSON, t invoiceTotals) {
+	l.newPage()
+	l.header(inv)
+	l.lineTable(inv, t)
+	l.totals(inv, t)
+	l.taxNotes(t)
+	l.payment(inv, t)
+	l.footers(inv)
+}
+
+func (l *invoiceLayout) header(inv InvoiceJSON) {
+	p, b := l.page, inv.Biller
+
+	// Biller block, top left
+	p.text(l.bold, 14, pdfMarginLeft, l.y, b.Name)
+	y := l.y - 14
+	for _, s := range []string{b.Address.Street, b.Address.ZIP + " " + b.Address.City, foreignCountryLine(b.Address, DefaultCountryCode), "UID: " + b.VATID, b.Email, b.Phone} {
+		if strings.TrimSpace(s) == "" || s == "UID: " {
+			continue
+		}
+		p.text(l.regular, 9, pdfMarginLeft, y, s)
+		y -= 11
+	}
+
+	// Recipient block with sender line, as for a window envelope
+	y = pdfPageHeight - 160
+	p.text(l.regular, 7, pdfMarginLeft, y, strings.Join([]string{b.Name, b.Address.Street, b.Address.ZIP + " " + b.Address.City}, " · "))
+	p.line(pdfMarginLeft, y-3, pdfMarginLeft+240, y-3, 0.3)
+	y -= 16
+	r := inv.Recipient
+	p.text(l.bold, 10, pdfMarginLeft, y, r.Name)
+	y -= 12
+	if r.ContactName != "" {
+		p.text(l.regular, 10, pdfMarginLeft, y, r.ContactName)
+		y -= 12
+	}
+	p.text(l.regular, 10, pdfMarginLeft, y, r.Address.Street)
+	y -= 12
+	p.text(l.regular, 10, pdfMarginLeft, y, r.Address.ZIP+" "+r.Address.City)
+	y -= 12
+	// Addresses abroad end with the destination country, in capitals
+	if country := foreignCountryLine(r.Address, addressCountry(b.Address)); country != "" {
+		p.text(l.regular, 10, pdfMarginLeft, y, country)
+		y -= 12
+	}
+	if r.VATID != "" {
+		p.text(l.regular, 9, pdfMarginLeft, y, "UID: "+r.VATID)
+	}
+	y -= 3
+	if op := inv.OrderingParty; op != nil {
+		y -= 11
+		p.text(l.regular, 8, pdfMarginLeft, y, "Auftraggeber: "+addressLineDE(op.Name, op.Address, addressCountry(b.Address))+", UID "+op.VATID)
+	}
+	if d := inv.Delivery; d != nil && d.Address != nil {
+		y -= 11
+		p.text(l.regular, 8, pdfMarginLeft, y, "Lieferadresse: "+addressLineDE(deliveryName(inv, *d), *d.Address, addressCountry(b.Address)))
+	}
+
+	// Document details, right column
+	details := [][2]string{
+		{"Nummer", inv.InvoiceNumber},
+		{"Datum", formatDateDE(inv.InvoiceDate)},
+	}
+	// Without a delivery date, the invoice date is the delivery date, unless
+	// the items state their own.
+	switch {
+	case inv.Delivery != nil:
+		label, value := deliveryDateDE(*inv.Delivery)
+		details = append(details, [2]string{label, value})
+	case !hasLineDeliveries(inv):
+		details = append(details, [2]string{"Lieferdatum", formatDateDE(inv.InvoiceDate)})
+	}
+	if r.OrderID != "" {
+		details = append(details, [2]string{"Auftragsreferenz", r.OrderID})
+	}
+	if numbers := supplierNumbers(b); len(numbers) > 0 {
+		details = append(details, [2]string{"Lieferantennummer", strings.Join(numbers, ", ")})
+	}
+	if ref := inv.OriginalInvoice; ref != nil {
+		details = append(details, [2]string{"Bezug", fmt.Sprintf("%s vom %s", ref.InvoiceNumber, formatDateDE(ref.InvoiceDate))})
+	}
+	y = pdfPageHeight - 160
+	for _, d := range details {
+		p.text(l.regular, 9, 340, y, d[0]+":")
+		p.textRight(l.regular, 9, pdfMarginRight, y, d[1])
+		y -= 12
+	}
+
+	l.y = pdfPageHeight - 290
+	p.text(l.bold, 16, pdfMarginLeft, l.y, pdfDocumentTitle(inv)+" "+inv.InvoiceNumber)
+	l.y -= 20
+	if ref := inv.OriginalInvoice; ref != nil && ref.Comment != "" {
+		for _, s := range wrapText(l.regular, 9, ref.Comment, pdfMarginRight-pdfMarginLeft) {
+			p.text(l.regular, 9, pdfMarginLeft, l.y, s)
+			l.y -= 11
+		}
+	}
+	l.y -= 10
+}
+
+func (l *invoiceLayout) tableHeader() {
+	p := l.page
+	p.fillRect(pdfMarginLeft-4, l.y-4, pdfMarginRight-pdfMarginLeft+8, 15, 0.9)
+	p.text(l.bold, 9, colPos, l.y, "Pos")
+	p.text(l.bold, 9, colDescription, l.y, "Beschreibung")
+	p.textRight(l.bold, 9, colQuantity, l.y, "Menge")
+	p.textRight(l.bold, 9, colUnitPrice, l.y, "Einzelpreis")
+	p.textRight(l.bold, 9, colTaxRate, l.y, "USt")
+	p.textRight(l.bold, 9, colAmount, l.y, "Betrag EUR")
+	l.y -= 18
+}
+
+func (l *invoiceLayout) lineTable(inv InvoiceJSON, t invoiceTotals) {
+	l.tableHeader()
+	for i, li := range inv.Items {
+		lt := t.Lines[i]
+		desc := wrapText(l.regular, 9, li.Description, descWidth)
+		refs := wrapText(l.regular, 7.5, lineReferencesDE(inv, i), descWidth)
+		if h := float64(len(desc)+len(refs)+len(lt.Adjustments))*11 + 4; !l.fits(h) {
+			l.newPage()
+			l.tableHeader()
+		}
+		p := l.page
+		p.text(l.regular, 9, colPos, l.y, fmt.Sprintf("%d", i+1))
+		p.textRight(l.regular, 9, colQuantity, l.y, strings.TrimSpace(formatQuantityDE(lt.Quantity)+" "+unitLabelDE(lt.UnitCode)))
+		p.textRight(l.regular, 9, colUnitPrice, l.y, formatPriceDE(lt.UnitPrice))
+		p.textRight(l.regular, 9, colTaxRate, l.y, formatRateDE(lt.TaxRate)+" %")
+		p.textRight(l.regular, 9, colAmount, l.y, formatCentsDE(lt.BaseCts))
+		for _, s := range desc {
+			p.text(l.regular, 9, colDescription, l.y, s)
+			l.y -= 11
+		}
+		for _, s := range refs {
+			p.text(l.regular, 7.5, colDescription, l.y, s)
+			l.y -= 11
+		}
+		// Reductions and surcharges follow as signed amounts, so the column sums up.
+		for _, at := range lt.Adjustments {
+			p.text(l.regular, 8.5, colDescription, l.y, adjustmentLabelDE(at))
+			p.textRight(l.regular, 8.5, colAmount, l.y, formatCentsDE(at.effectCts()))
+			l.y -= 11
+		}
+		l.y -= 4
+	}
+	l.page.line(pdfMarginLeft-4, l.y+8, pdfMarginRight+4, l.y+8, 0.5)
+	l.y -= 8
+}
+
+func (l *invoiceLayout) totals(inv InvoiceJSON, t invoiceTotals) {
+	var rows [][2]string
+	if len(t.Adjustments) > 0 {
+		rows = append(rows, [2]string{"Summe Positionen", formatCentsDE(t.LineNetCts)})
+		advances := false
+		for _, at := range t.Adjustments {
+			label := fmt.Sprintf("%s, USt %s %%", adjustmentLabelDE(at), formatRateDE(at.TaxRate))
+			if at.Advance != nil {
+				if !advances {
+					rows = append(rows, [2]string{"abzüglich Anzahlungsrechnungen", ""})
+					advances = true
+				}
+				// The deducted tax must be stated as well.
+				label = fmt.Sprintf("%s, %s %% (USt %s)", at.Advance.InvoiceNumber, formatRateDE(at.TaxRate), formatCentsDE(-at.TaxCts))
+			}
+			rows = append(rows, [2]string{label, formatCentsDE(at.effectCts())})
+		}
+	}
+	rows = append(rows, [2]string{"Summe netto", formatCentsDE(t.NetCts)})
+	for _, b := range t.Buckets {
+		label := fmt.Sprintf("USt %s %% auf %s", formatRateDE(b.Rate), formatCentsDE(b.TaxableCts))
+		if b.ExemptionReason != "" {
+			label = fmt.Sprintf("USt %s %% (%s) auf %s", formatRateDE(b.Rate), b.Category, formatCentsDE(b.TaxableCts))
+		}
+		rows = append(rows, [2]string{label, formatCentsDE(b.TaxCts)})
+	}
+	if !l.fits(float64(len(rows)+len(inv.Prepayments)+2) * 13) {
+		l.newPage()
+	}
+	p := l.page
+	for _, r := range rows {
+		p.text(l.regular, 9, 330, l.y, r[0])
+		p.textRight(l.regular, 9, colAmount, l.y, r[1])
+		l.y -= 13
+	}
+	p.line(330, l.y+9, pdfMarginRight+4, l.y+9, 0.5)
+	l.y -= 2
+	p.text(l.bold, 10, 330, l.y, "Gesamtbetrag EUR")
+	p.textRight(l.bold, 10, colAmount, l.y, formatCentsDE(t.GrossCts))
+	l.y -= 13
+	for _, pp := range inv.Prepayments {
+		label := "abzüglich Anzahlung"
+		if pp.InvoiceNumber != "" {
+			label += " " + pp.InvoiceNumber
+		}
+		p.text(l.regular, 9, 330, l.y, label)
+		p.textRight(l.regular, 9, colAmount, l.y, formatCentsDE(-pp.AmountCents))
+		l.y -= 13
+	}
+	if t.PayableCts != t.GrossCts {
+		p.text(l.bold, 10, 330, l.y, "Zahlbetrag EUR")
+		p.textRight(l.bold, 10, colAmount, l.y, formatCentsDE(t.PayableCts))
+		l.y -= 13
+	}
+	l.y -= 20
+}
+
+// taxNotes prints the legal notes of untaxed categories, e.g. reverse charge.
+func (l *invoiceLayout) taxNotes(t invoiceTotals) {
+	var lines []string
+	for _, b := range t.Buckets {
+		if b.ExemptionReason != "" {
+			lines = append(lines, wrapText(l.regular, 9, b.Category+": "+b.ExemptionReason, pdfMarginRight-pdfMarginLeft)...)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	if !l.fits(float64(len(lines)+2) * 12) {
+		l.newPage()
+	}
+	p := l.page
+	p.text(l.bold, 10, pdfMarginLeft, l.y, "Steuerhinweis")
+	l.y -= 14
+	for _, s := range lines {
+		p.text(l.regular, 9, pdfMarginLeft, l.y, s)
+		l.y -= 12
+	}
+	l.y -= 14
+}
+
+func (l *invoiceLayout) payment(inv InvoiceJSON, t invoiceTotals) {
+	rows := [][2]string{
+		{"Kontoinhaber", inv.Biller.Name},
+		{"IBAN", formatIBAN(inv.Payment.IBAN)},
+		{"BIC", inv.Payment.BIC},
+		{"Verwendungszweck", inv.InvoiceNumber},
+	}
+	var terms []string
+	if pt := t.PaymentTerms; pt != nil {
+		for _, sk := range pt.Skonto {
+			terms = append(terms, fmt.Sprintf("%s %% Skonto (%s EUR) bei Zahlung bis %s",
+				formatRateDE(sk.Percentage), formatCentsDE(sk.AmountCts), formatDateDE(sk.PaymentDate)))
+		}
+		due := "Zahlbar bis " + formatDateDE(pt.DueDate)
+		if len(pt.Skonto) > 0 {
+			due += " ohne Abzug"
+		}
+		terms = append(terms, due)
+	}
+	if !l.fits(float64(len(rows)+len(terms)+3) * 12) {
+		l.newPage()
+	}
+	p := l.page
+	p.text(l.bold, 10, pdfMarginLeft, l.y, "Zahlungsinformationen")
+	l.y -= 14
+	intro := fmt.Sprintf("Bitte überweisen Sie %s EUR auf folgendes Konto:", formatCentsDE(t.PayableCts))
+	if t.PayableCts < 0 {
+		intro = fmt.Sprintf("Der Betrag von %s EUR wird Ihnen gutgeschrieben. Unsere Bankverbindung:", formatCentsDE(-t.PayableCts))
+	}
+	p.text(l.regular, 9, pdfMarginLeft, l.y, intro)
+	l.y -= 13
+	for _, r := range rows {
+		p.text(l.regular, 9, pdfMarginLeft, l.y, r[0]+":")
+		p.text(l.regular, 9, pdfMarginLeft+90, l.y, r[1])
+		l.y -= 12
+	}
+	if len(terms) > 0 {
+		l.y -= 4
+		for _, line := range terms {
+			p.text(l.regular, 9, pdfMarginLeft, l.y, line)
+			l.y -= 12
+		}
+	}
+}
+
+// footers writes the biller identification and page numbers once the page count is known.
+func (l *invoiceLayout) footers(inv InvoiceJSON) {
+	for i, p := range l.pages {
+		p.line(pdfMarginLeft, 45, pdfMarginRight, 45, 0.3)
+		p.text(l.regular, 7.5, pdfMarginLeft, 34, billerFooterDE(inv.Biller))
+		p.textRight(l.regular, 7.5, pdfMarginRight, 34, fmt.Sprintf("Seite %d von %d", i+1, len(l.pages)))
+	}
+}
+
+// supplierNumbers lists biller_id and the further supplier numbers of the biller.
+func supplierNumbers(b BillerJSON) []string {
+	var numbers []string
+	if b.BillerID != "" {
+		numbers = append(numbers, b.BillerID)
+	}
+	for _, id := range b.FurtherIdentifications {
+		if id.Type == IdentificationSupplierNumber {
+			numbers = append(numbers, id.Value)
+		}
+	}
+	return numbers
+}
+
+// billerFooterDE states the biller's company details as § 14 UGB requires
+// them on business letters, e.g. "Your Startup GmbH · Sitz Wien ·
+// FN 123456a · Handelsgericht Wien · UID ATU13585627".
+func billerFooterDE(b BillerJSON) string {
+	parts := []string{b.Name}
+	add := func(label, typ string) {
+		if value := furtherIdentification(b.FurtherIdentifications, typ); value != "" {
+			parts = append(parts, label+value)
+		}
+	}
+	add("Sitz ", IdentificationFS)
+	add("FN ", IdentificationFN)
+	add("", IdentificationFBG)
+	parts = append(parts, "UID "+b.VATID)
+	add("GLN ", IdentificationGLN)
+	add("DVR ", IdentificationDVR)
+	return strings.Join(parts, " · ")
+}
+
+// adjustmentLabelDE describes a reduction or surcharge, e.g. "Nachlass 5 %: Mengenrabatt".
+func adjustmentLabelDE(at adjustmentTotals) string {
+	label := "Nachlass"
+	if at.Surcharge {
+		label = "Zuschlag"
+	}
+	if at.Percentage != 0 {
+		label += " " + formatRateDE(at.Percentage) + " %"
+	}
+	if at.Reason != "" {
+		label += ": " + at.Reason
+	}
+	return label
+}
+
+// pdfDocumentTitle is the German document title shown on the PDF.
+func pdfDocumentTitle(inv InvoiceJSON) string {
+	switch documentType(inv) {
+	case DocTypeCreditMemo:
+		return "Gutschrift"
+	case DocTypeCancellation:
+		return "Stornorechnung"
+	case DocTypeAdvancePayment:
+		return "Anzahlungsrechnung"
+	case DocTypeFinalSettlement:
+		return "Schlussrechnung"
+	default:
+		return "Rechnung"
+	}
+}
+
+// wrapText breaks s into lines no wider than width at word boundaries.
+// Words longer than a line are split by character.
+func wrapText(f *pdfFont, size float64, s string, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if f.width(candidate, size) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = word
+		for f.width(line, size) > width {
+			cut := len([]rune(line))
+			for cut > 1 && f.width(string([]rune(line)[:cut]), size) > width {
+				cut--
+			}
+			lines = append(lines, string([]rune(line)[:cut]))
+			line = string([]rune(line)[cut:])
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatCentsDE formats cents in Austrian notation, e.g. 123456 -> "1.234,56".
+func formatCentsDE(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	whole := fmt.Sprintf("%d", cents/100)
+	var grouped strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(c)
+	}
+	return fmt.Sprintf("%s%s,%02d", sign, grouped.String(), cents%100)
+}
+
+// formatRateDE formats a tax rate with a decimal comma, e.g. 5.5 -> "5,5".
+func formatRateDE(rate float64) string {
+	return strings.Replace(formatRate(rate), ".", ",", 1)
+}
+
+// formatQuantityDE formats a quantity with a decimal comma, e.g. 7.5 -> "7,5".
+func formatQuantityDE(q decimal.Decimal) string {
+	return strings.Replace(formatQuantity(q), ".", ",", 1)
+}
+
+// formatPriceDE formats a unit price like formatCentsDE, keeping sub-cent
+// decimals, e.g. 0.1234 -> "0,1234".
+func formatPriceDE(price decimal.Decimal) string {
+	_, frac, _ := strings.Cut(formatPrice(price), ".")
+	return formatCentsDE(price.Shift(2).IntPart()) + frac[2:]
+}
+
+// formatDateDE converts YYYY-MM-DD to DD.MM.YYYY; other input is returned unchanged.
+func formatDateDE(isoDate string) string {
+	d, err := time.Parse("2006-01-02", isoDate)
+	if err != nil {
+		return isoDate
+	}
+	return d.Format("02.01.2006")
+}
+
+// deliveryDateDE returns the label and value of a delivery date or period,
+// e.g. "Leistungszeitraum", "01.09.2026 – 30.09.2026".
+func deliveryDateDE(d DeliveryJSON) (string, string) {
+	if d.isPeriod() {
+		return "Leistungszeitraum", formatDateDE(d.FromDate) + " – " + formatDateDE(d.ToDate)
+	}
+	return "Lieferdatum", formatDateDE(d.Date)
+}
+
+// addressLineDE renders a name and address on one line, e.g.
+// "Lager Nord, Industriestraße 5, 4020 Linz".
+func addressLineDE(name string, a AddressJSON, senderCountry string) string {
+	parts := []string{name, a.Street, a.ZIP + " " + a.City}
+	if country := foreignCountryLine(a, senderCountry); country != "" {
+		parts = append(parts, country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hasLineDeliveries reports whether any item states its own delivery.
+func hasLineDeliveries(inv InvoiceJSON) bool {
+	for _, li := range inv.Items {
+		if li.Delivery != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// lineReferencesDE lists the article numbers of line i, its order reference
+// where it differs from the recipient's order and its own delivery, e.g.
+// "Art.-Nr. A-17 · GTIN 4006381333931 · Bestellung 4500012345, Pos. 10".
+func lineReferencesDE(inv InvoiceJSON, i int) string {
+	li := inv.Items[i]
+	labels := map[string]string{
+		ArticleNumberBiller:    "Art.-Nr.",
+		ArticleNumberRecipient: "Ihre Art.-Nr.",
+		ArticleNumberGTIN:      "GTIN",
+	}
+	var parts []string
+	for _, a := range lineArticleNumbers(li) {
+		parts = append(parts, labels[a.Type]+" "+a.Value)
+	}
+	if li.OrderID != "" || li.OrderPosition != "" {
+		orderID, position, _ := lineOrderReference(inv, i)
+		ref := "Bestellung " + orderID
+		if position != "" {
+			ref += ", Pos. " + position
+		}
+		parts = append(parts, ref)
+	}
+	if d := li.Delivery; d != nil {
+		label, value := deliveryDateDE(*d)
+		parts = append(parts, label+" "+value)
+		if d.Address != nil {
+			parts = append(parts, "Lieferadresse "+addressLineDE(deliveryName(inv, *d), *d.Address, addressCountry(inv.Biller.Address)))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// foreignCountryLine returns the country name of an address outside the
+// sender's country in capitals, e.g. DEUTSCHLAND, and "" otherwise.
+func foreignCountryLine(a AddressJSON, senderCountry string) string {
+	country := addressCountry(a)
+	if country == senderCountry {
+		return ""
+	}
+	return strings.ToUpper(countryNameDE(country))
+}
+
+// formatIBAN groups an IBAN in blocks of four for printing.
+func formatIBAN(iban string) string {
+	var b strings.Builder
+	for i, c := range strings.ReplaceAll(iban, " ", "") {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
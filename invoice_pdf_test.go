@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// missingPDFLines returns the wanted lines that do not appear, in order, among
+// the text lines of a page.
+func missingPDFLines(text string, want []string) []string {
+	lines := strings.Split(text, "\n")
+	var missing []string
+	i := 0
+	for _, w := range want {
+		j := i
+		for j < len(lines) && lines[j] != w {
+			j++
+		}
+		if j == len(lines) {
+			missing = append(missing, w)
+			continue
+		}
+		i = j + 1
+	}
+	return missing
+}
+
+func TestRenderInvoicePDF(t *testing.T) {
+	// test_invoice_small.json: one line of 4500.00 at 20 %, 900.00 tax, 5400.00 gross.
+	tests := []struct {
+		name      string
+		edit      func(inv *InvoiceJSON)
+		wantTitle string
+		wantLines []string // in order of appearance
+	}{
+		{"invoice", func(inv *InvoiceJSON) {}, "Rechnung 2026-001", []string{
+			"Rechnung 2026-001",
+			"Summe netto", "4.500,00",
+			"USt 20 % auf 4.500,00", "900,00",
+			"Gesamtbetrag EUR", "5.400,00",
+			"Bitte überweisen Sie 5.400,00 EUR auf folgendes Konto:",
+			"AT61 1904 3002 3457 3201",
+		}},
+		{"credit memo", func(inv *InvoiceJSON) {
+			inv.DocumentType = DocTypeCreditMemo
+			inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02"}
+		}, "Gutschrift 2026-001", []string{
+			"Bezug:", "2025-100 vom 02.01.2026",
+			"Gutschrift 2026-001",
+			"Summe netto", "-4.500,00",
+			"USt 20 % auf -4.500,00", "-900,00",
+			"Gesamtbetrag EUR", "-5.400,00",
+			"Der Betrag von 5.400,00 EUR wird Ihnen gutgeschrieben. Unsere Bankverbindung:",
+		}},
+		{"cancellation", func(inv *InvoiceJSON) {
+			inv.DocumentType = DocTypeCancellation
+			inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02", Comment: "Storno wegen falscher Anschrift"}
+		}, "Stornorechnung 2026-001", []string{
+			"Stornorechnung 2026-001",
+			"Storno wegen falscher Anschrift",
+			"Gesamtbetrag EUR", "-5.400,00",
+			"Der Betrag von 5.400,00 EUR wird Ihnen gutgeschrieben. Unsere Bankverbindung:",
+		}},
+		{"advance payment", func(inv *InvoiceJSON) { inv.DocumentType = DocTypeAdvancePayment }, "Anzahlungsrechnung 2026-001", []string{
+			"Anzahlungsrechnung 2026-001",
+			"Gesamtbetrag EUR", "5.400,00",
+		}},
+		{"final settlement", func(inv *InvoiceJSON) {
+			inv.DocumentType = DocTypeFinalSettlement
+			inv.AdvanceInvoices = []AdvanceInvoiceJSON{{
+				InvoiceNumber: "AR-1",
+				InvoiceDate:   "2025-12-01",
+				Taxes:         []AdvanceTaxJSON{{TaxRate: 20, NetCents: 150000, TaxCents: 30000}},
+			}}
+			inv.Prepayments = []PrepaymentJSON{{AmountCents: 60000, InvoiceNumber: "AR-0"}}
+		}, "Schlussrechnung 2026-001", []string{
+			"Schlussrechnung 2026-001",
+			"Summe Positionen", "4.500,00",
+			"abzüglich Anzahlungsrechnungen",
+			"AR-1, 20 % (USt 300,00)", "-1.500,00",
+			"Summe netto", "3.000,00",
+			"Gesamtbetrag EUR", "3.600,00",
+			"abzüglich Anzahlung AR-0", "-600,00",
+			"Zahlbetrag EUR", "3.000,00",
+			"Bitte überweisen Sie 3.000,00 EUR auf folgendes Konto:",
+		}},
+		{"reverse charge", func(inv *InvoiceJSON) {
+			inv.Items[0].TaxRate = 0
+			inv.Items[0].TaxCategory = TaxCategoryReverseCharge
+		}, "Rechnung 2026-001", []string{
+			"USt 0 % (AE) auf 4.500,00", "0,00",
+			"Gesamtbetrag EUR", "4.500,00",
+			"Steuerhinweis",
+			"AE: Übergang der Steuerschuld auf den Leistungsempfänger (Reverse Charge)",
+		}},
+		{"payment terms", func(inv *InvoiceJSON) {
+			inv.PaymentTerms = &PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2, Days: 14}}}
+		}, "Rechnung 2026-001", []string{
+			"Gesamtbetrag EUR", "5.400,00",
+			"2 % Skonto (108,00 EUR) bei Zahlung bis 22.01.2026",
+			"Zahlbar bis 07.02.2026 ohne Abzug",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			tt.edit(&inv)
+			if err := validateInvoice(inv); err != nil {
+				t.Fatal(err)
+			}
+			data, err := RenderInvoicePDF(inv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := parseTestPDF(t, data)
+			pages := p.pageTexts(t)
+			if len(pages) != 1 {
+				t.Fatalf("%d pages, want 1", len(pages))
+			}
+			if missing := missingPDFLines(pages[0], tt.wantLines); len(missing) > 0 {
+				t.Errorf("missing %q in\n%s", missing, pages[0])
+			}
+			if want := "Seite 1 von 1"; !strings.HasSuffix(pages[0], "\n"+want) {
+				t.Errorf("page does not end with %s", want)
+			}
+			info := p.dict(t, pdfRef(p.trailer, "/Info"))
+			if want := "/Title " + pdfTextString(tt.wantTitle) + " "; !strings.Contains(info, want) {
+				t.Errorf("info %s lacks %s", info, want)
+			}
+			if want := "/Author " + pdfTextString(inv.Biller.Name) + " "; !strings.Contains(info, want) {
+				t.Errorf("info %s lacks %s", info, want)
+			}
+			// A plain PDF carries no PDF/A parts.
+			if catalog := p.dict(t, pdfRef(p.trailer, "/Root")); strings.Contains(catalog, "/Metadata") || strings.Contains(catalog, "/AF") {
+				t.Errorf("catalog %s", catalog)
+			}
+		})
+	}
+}
+
+func TestRenderInvoicePDFPages(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Items = nil
+	for i := 1; i <= 80; i++ {
+		inv.Items = append(inv.Items, LineItemJSON{Description: fmt.Sprintf("Leistung %d", i), Quantity: quantity("1"), UnitPriceCents: 1000, TaxRate: 20})
+	}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+	f := outputFormats["pdf"]
+	if f.ContentType != "application/pdf" {
+		t.Errorf("content type %q", f.ContentType)
+	}
+	data, err := f.Render(inv, renderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := parseTestPDF(t, data).pageTexts(t)
+	if len(pages) < 2 {
+		t.Fatalf("%d pages for 80 lines", len(pages))
+	}
+	for i, text := range pages {
+		if want := fmt.Sprintf("Seite %d von %d", i+1, len(pages)); !strings.HasSuffix(text, "\n"+want) {
+			t.Errorf("page %d does not end with %s", i+1, want)
+		}
+		// Every page repeats the table header.
+		if missing := missingPDFLines(text, []string{"Pos", "Beschreibung", "Menge", "Einzelpreis", "USt", "Betrag EUR"}); len(missing) > 0 && i < len(pages)-1 {
+			t.Errorf("page %d lacks the table header", i+1)
+		}
+	}
+	all := strings.Join(pages, "\n")
+	if missing := missingPDFLines(all, []string{"Leistung 1", "Leistung 80", "Summe netto", "800,00", "USt 20 % auf 800,00", "160,00", "Gesamtbetrag EUR", "960,00"}); len(missing) > 0 {
+		t.Errorf("missing %q", missing)
+	}
+}
+
+func TestFormatCentsDE(t *testing.T) {
+	tests := []struct {
+		cents int64
+		want  string
+	}{
+		{0, "0,00"}, {5, "0,05"}, {99999, "999,99"}, {100000, "1.000,00"}, {123456789, "1.234.567,89"}, {-540000, "-5.400,00"},
+	}
+	for _, tt := range tests {
+		if got := formatCentsDE(tt.cents); got != tt.want {
+			t.Errorf("formatCentsDE(%d) = %s, want %s", tt.cents, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Article number types (ebInterface ArticleNumberType) stated per line item.
+const (
+	ArticleNumberBiller    = "BillersArticleNumber"
+	ArticleNumberRecipient = "InvoiceRecipientsArticleNumber"
+	ArticleNumberGTIN      = "GTIN"
+)
+
+// articleNumber is one article number of a line item with its type.
+type articleNumber struct {
+	Type  string
+	Value string
+}
+
+// gtinSchemeID identifies GTINs in UBL and CII (ISO 6523 ICD 0160, GS1).
+const gtinSchemeID = "0160"
+
+var gtinRegex = regexp.MustCompile(`^(\d{8}|\d{12,14})$`)
+
+// lineArticleNumbers lists the article numbers given for a line item.
+func lineArticleNumbers(li LineItemJSON) []articleNumber {
+	var numbers []articleNumber
+	for _, a := range []articleNumber{
+		{ArticleNumberBiller, li.BillerArticleNumber},
+		{ArticleNumberRecipient, li.RecipientArticleNumber},
+		{ArticleNumberGTIN, li.GTIN},
+	} {
+		if a.Value != "" {
+			numbers = append(numbers, a)
+		}
+	}
+	return numbers
+}
+
+// lineOrderReference returns the order and position line i refers to. Lines
+// without their own reference fall back to recipient.order_id and their line
+// number where the profile links lines to order positions; ok is false if the
+// line refers to no order.
+func lineOrderReference(inv InvoiceJSON, i int) (orderID, position string, ok bool) {
+	li := inv.Items[i]
+	orderID = li.OrderID
+	if orderID == "" {
+		orderID = inv.Recipient.OrderID
+	}
+	if orderID == "" {
+		return "", "", false
+	}
+	if li.OrderID == "" && li.OrderPosition == "" {
+		if !profileOf(inv).LineOrderReferences {
+			return "", "", false
+		}
+		return orderID, strconv.Itoa(i + 1), true
+	}
+	return orderID, li.OrderPosition, true
+}
+
+// lineOrderPosition returns the position of recipient.order_id that line i
+// refers to, or "". UBL and CII reference a single order per document, so
+// lines of other orders have no position there.
+func lineOrderPosition(inv InvoiceJSON, i int) string {
+	orderID, position, _ := lineOrderReference(inv, i)
+	if orderID != inv.Recipient.OrderID {
+		return ""
+	}
+	return position
+}
+
+// validateLineReferences checks the order references and article numbers of
+// the line items.
+func validateLineReferences(inv InvoiceJSON) error {
+	for i, li := range inv.Items {
+		if li.OrderPosition != "" && li.OrderID == "" && inv.Recipient.OrderID == "" {
+			return fmt.Errorf("items[%d].order_position requires items[%d].order_id or recipient.order_id", i, i)
+		}
+		// Federal purchase orders are invoiced by position.
+		if li.OrderID != "" && li.OrderPosition == "" && profileName(inv.Profile) == ProfileB2GFederal {
+			return fmt.Errorf("items[%d].order_position is required with items[%d].order_id for profile %s", i, i, ProfileB2GFederal)
+		}
+		if li.GTIN != "" && !gtinRegex.MatchString(li.GTIN) {
+			return fmt.Errorf("items[%d].gtin must have 8, 12, 13 or 14 digits", i)
+		}
+		if li.GTIN != "" && !checkGTIN(li.GTIN) {
+			return fmt.Errorf("items[%d].gtin %s has an invalid check digit", i, li.GTIN)
+		}
+	}
+	return nil
+}
+
+// checkGTIN verifies the GS1 mod-10 check digit: from the right, the digits
+// before the check digit are weighted 3, 1, 3, ...
+func checkGTIN(gtin string) bool {
+	d := digits(gtin)
+	sum := 0
+	for i := len(d) - 2; i >= 0; i-- {
+		if (len(d)-2-i)%2 == 0 {
+			sum += 3 * d[i]
+		} else {
+			sum += d[i]
+		}
+	}
+	return (10-sum%10)%10 == d[len(d)-1]
+}
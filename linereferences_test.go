@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckGTIN(t *testing.T) {
+	tests := []struct {
+		gtin string
+		want bool
+	}{
+		{"96385074", true},       // GTIN-8
+		{"036000291452", true},   // GTIN-12 (UPC-A)
+		{"4006381333931", true},  // GTIN-13
+		{"10012345678902", true}, // GTIN-14
+		{"96385075", false},
+		{"036000291453", false},
+		{"4006381333932", false},
+		{"10012345678903", false},
+	}
+	for _, tt := range tests {
+		if got := checkGTIN(tt.gtin); got != tt.want {
+			t.Errorf("checkGTIN(%q) = %v, want %v", tt.gtin, got, tt.want)
+		}
+	}
+}
+
+func TestValidateLineReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"article numbers", func(inv *InvoiceJSON) {
+			inv.Items[0].BillerArticleNumber = "ART-1"
+			inv.Items[0].RecipientArticleNumber = "M-4711"
+			inv.Items[0].GTIN = "4006381333931"
+		}, ""},
+		{"gtin length", func(inv *InvoiceJSON) { inv.Items[0].GTIN = "400638133393123" }, "items[0].gtin must have 8, 12, 13 or 14 digits"},
+		{"gtin letters", func(inv *InvoiceJSON) { inv.Items[0].GTIN = "400638133393A" }, "items[0].gtin must have 8, 12, 13 or 14 digits"},
+		{"gtin check digit", func(inv *InvoiceJSON) { inv.Items[0].GTIN = "4006381333932" }, "items[0].gtin 4006381333932 has an invalid check digit"},
+		{"federal order position", func(inv *InvoiceJSON) {
+			inv.Items[0].OrderID = "4500012345"
+			inv.Items[0].OrderPosition = "10"
+		}, ""},
+		{"federal order without position", func(inv *InvoiceJSON) { inv.Items[0].OrderID = "4500012345" }, "items[0].order_position is required with items[0].order_id for profile b2g_federal"},
+		{"b2b order without position", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Items[0].OrderID = "4500012345"
+		}, ""},
+		{"position without order", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.OrderID = ""
+			inv.Items[0].OrderPosition = "10"
+		}, "items[0].order_position requires items[0].order_id or recipient.order_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			tt.edit(&inv)
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLineOrderReference(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json") // recipient.order_id 1234567890
+	inv.Items = append(inv.Items,
+		LineItemJSON{Description: "Wartung", Quantity: quantity("1"), UnitPriceCents: 100, TaxRate: 20, OrderPosition: "20"},
+		LineItemJSON{Description: "Hosting", Quantity: quantity("1"), UnitPriceCents: 100, TaxRate: 20, OrderID: "4500012345", OrderPosition: "3"},
+	)
+	tests := []struct {
+		profile         string
+		line            int
+		wantOrder       string
+		wantPosition    string
+		wantOK          bool
+		wantUBLPosition string
+	}{
+		{ProfileB2GFederal, 0, "1234567890", "1", true, "1"}, // line number by default
+		{ProfileB2GFederal, 1, "1234567890", "20", true, "20"},
+		{ProfileB2GFederal, 2, "4500012345", "3", true, ""}, // other order, not in UBL
+		{ProfileB2B, 0, "", "", false, ""},
+		{ProfileB2B, 1, "1234567890", "20", true, "20"},
+	}
+	for _, tt := range tests {
+		inv.Profile = tt.profile
+		orderID, position, ok := lineOrderReference(inv, tt.line)
+		if orderID != tt.wantOrder || position != tt.wantPosition || ok != tt.wantOK {
+			t.Errorf("%s line %d: %q, %q, %v; want %q, %q, %v", tt.profile, tt.line, orderID, position, ok, tt.wantOrder, tt.wantPosition, tt.wantOK)
+		}
+		if got := lineOrderPosition(inv, tt.line); got != tt.wantUBLPosition {
+			t.Errorf("%s line %d: UBL position %q, want %q", tt.profile, tt.line, got, tt.wantUBLPosition)
+		}
+	}
+}
+
+func TestArticleNumberOutput(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Items[0].BillerArticleNumber = "ART-1"
+	inv.Items[0].RecipientArticleNumber = "M-4711"
+	inv.Items[0].GTIN = "4006381333931"
+	for _, version := range supportedEbInterfaceVersions() {
+		doc, err := TransformToEbInterfaceVersion(inv, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateEbInterface(doc); err != nil {
+			t.Errorf("%s schema: %v", version, err)
+		}
+		for _, want := range []string{
+			`<ArticleNumber ArticleNumberType="BillersArticleNumber">ART-1</ArticleNumber>`,
+			`<ArticleNumber ArticleNumberType="InvoiceRecipientsArticleNumber">M-4711</ArticleNumber>`,
+			`<ArticleNumber ArticleNumberType="GTIN">4006381333931</ArticleNumber>`,
+		} {
+			if !strings.Contains(string(doc), want) {
+				t.Errorf("%s is missing %s", version, want)
+			}
+		}
+	}
+
+	ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<cbc:ID schemeID="0160">4006381333931</cbc:ID>`; !strings.Contains(string(ubl), want) {
+		t.Errorf("UBL is missing %s", want)
+	}
+	cii, err := TransformToCII(inv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<ram:GlobalID schemeID="0160">4006381333931</ram:GlobalID>`; !strings.Contains(string(cii), want) {
+		t.Errorf("CII is missing %s", want)
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/stripe/stripe-go/v76"
 )
@@ -66,6 +70,8 @@ func main() {
 
 	// Protected endpoints (require Stripe API key + rate limiting)
 	mux.Handle("/generate", RateLimitMiddleware(StripeAuthMiddleware(http.HandlerFunc(generateHandler))))
+	mux.Handle("/validate-xml", RateLimitMiddleware(StripeAuthMiddleware(http.HandlerFunc(validateXMLHandler))))
+	mux.Handle("/parse", RateLimitMiddleware(StripeAuthMiddleware(http.HandlerFunc(parseXMLHandler))))
 
 	addr := ":8080"
 	if v := os.Getenv("PORT"); v != "" {
@@ -74,7 +80,9 @@ func main() {
 
 	log.Printf("Starting Austrian Invoice API service on %s\n", addr)
 	log.Printf("Endpoints:")
-	log.Printf("  POST /generate - Generate invoice (requires X-API-KEY)")
+	log.Printf("  POST /generate - Generate invoice, ?format=ebinterface|ubl|xrechnung|cii|pdf|pdfa&version=5.0|6.0|6.1&cii_profile=en16931|xrechnung&embed=ebinterface|cii (requires X-API-KEY)")
+	log.Printf("  POST /validate-xml - Validate ebInterface XML (requires X-API-KEY)")
+	log.Printf("  POST /parse - Parse ebInterface XML into invoice JSON (requires X-API-KEY)")
 	log.Printf("  GET  /buy - Subscribe to service")
 	log.Printf("  POST /webhook - Stripe webhook handler")
 
@@ -89,12 +97,49 @@ func generateHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, ErrCodeInvalidJSON, "Invalid JSON payload", err.Error())
 		return
 	}
+	// The account's VAT rounding and profile apply unless the request chooses them.
+	settings := accountSettingsFromContext(r.Context())
+	if in.VATRounding == "" {
+		in.VATRounding = settings.VATRounding
+	}
+	if in.Profile == "" {
+		in.Profile = settings.Profile
+	}
 
 	if err := validateInvoice(in); err != nil {
+		var checksum *errVATIDChecksum
+		if errors.As(err, &checksum) {
+			writeError(w, http.StatusBadRequest, ErrCodeVATIDChecksum, "VAT ID check digit is invalid", err.Error())
+			return
+		}
 		writeError(w, http.StatusBadRequest, ErrCodeValidationError, "Validation failed", err.Error())
 		return
 	}
 
+	// ?format= or the Accept header selects the output syntax; default is ebInterface.
+	format, err := negotiateOutputFormat(r)
+	if err != nil {
+		var notAcceptable *errNotAcceptable
+		if errors.As(err, &notAcceptable) {
+			writeError(w, http.StatusNotAcceptable, ErrCodeNotAcceptable, "Requested media type is not supported", err.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, ErrCodeValidationError, "Validation failed", err.Error())
+		return
+	}
+	// ?version= (ebInterface), ?cii_profile= (CII) and ?embed= (PDF/A-3) refine the chosen format.
+	opts, err := renderOptionsFromRequest(r, format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeValidationError, "Validation failed", err.Error())
+		return
+	}
+	if format.Check != nil {
+		if err := format.Check(in, opts); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeValidationError, "Validation failed", err.Error())
+			return
+		}
+	}
+
 	// Check if free tier and increment usage
 	apiKey := r.Header.Get("X-API-KEY")
 	if len(apiKey) > 7 && apiKey[:7] == "at_test_" {
@@ -109,14 +154,159 @@ func generateHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	xmlBytes, err := TransformToEbInterface(in)
+	doc, err := format.Render(in, opts)
 	if err != nil {
+		// Formats that embed ebInterface (PDF/A-3) validate it while rendering.
+		var schemaErr *SchemaValidationError
+		if errors.As(err, &schemaErr) {
+			writeViolations(w, http.StatusUnprocessableEntity, ErrCodeSchemaValidation, "Generated invoice is not schema-valid", schemaErr.Violations)
+			return
+		}
 		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "Failed to generate invoice", err.Error())
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	if _, err := w.Write(xmlBytes); err != nil {
+	// Never hand out a document the portal would reject on schema grounds.
+	if format.Validate != nil {
+		if err := format.Validate(doc); err != nil {
+			var schemaErr *SchemaValidationError
+			if errors.As(err, &schemaErr) {
+				writeViolations(w, http.StatusUnprocessableEntity, ErrCodeSchemaValidation, "Generated invoice is not schema-valid", schemaErr.Violations)
+				return
+			}
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "Failed to validate invoice", err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("X-VAT-Rounding", vatRounding(in))
+	w.Header().Set("X-Invoice-Profile", profileName(in.Profile))
+	if _, err := w.Write(doc); err != nil {
+		log.Printf("write response error: %v", err)
+	}
+}
+
+// maxXMLUploadBytes limits the size of documents accepted by /validate-xml.
+const maxXMLUploadBytes = 5 << 20
+
+// XMLValidationResult is the response of /validate-xml.
+type XMLValidationResult struct {
+	Valid      bool        `json:"valid"`
+	Namespace  string      `json:"namespace"`
+	Violations []Violation `json:"violations"`
+}
+
+// readXMLUpload reads an uploaded XML document, given as the raw request body
+// or as the "file" field of a multipart form. On failure it writes the error
+// response and returns false.
+func readXMLUpload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxXMLUploadBytes)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidXML, "Missing XML upload", err.Error())
+			return nil, false
+		}
+		defer file.Close()
+		body = file
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidXML, "Failed to read XML upload", err.Error())
+		return nil, false
+	}
+	return data, true
+}
+
+// validateXMLHandler checks an uploaded ebInterface document against the schema
+// and the business rules applied to JSON input, reporting every finding.
+// The document is accepted as the raw request body or as the "file" field of a
+// multipart form.
+func validateXMLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeInternalError, "Method not allowed", "Only POST is allowed")
+		return
+	}
+
+	data, ok := readXMLUpload(w, r)
+	if !ok {
+		return
+	}
+
+	root, err := parseXMLTree(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidXML, "Invalid XML document", err.Error())
+		return
+	}
+	violations, err := schemaViolations(root)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidXML, "Unsupported document", err.Error())
+		return
+	}
+	// ?profile= or the account's profile selects the rules, as for /generate.
+	profile := r.URL.Query().Get("profile")
+	if profile == "" {
+		profile = accountSettingsFromContext(r.Context()).Profile
+	}
+	if err := validateProfile(profile); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeValidationError, "Validation failed", err.Error())
+		return
+	}
+	violations = append(violations, checkEbInterfaceRules(root, profile)...)
+
+	result := XMLValidationResult{
+		Valid:      len(violations) == 0,
+		Namespace:  root.Name.Space,
+		Violations: violations,
+	}
+	if result.Violations == nil {
+		result.Violations = []Violation{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("write response error: %v", err)
+	}
+}
+
+// parseXMLHandler reads an incoming ebInterface 5.0/6.x invoice back into the
+// JSON model (see ParseEbInterface). The document must be schema-valid; content
+// without a JSON equivalent is listed in the response's unmapped field.
+func parseXMLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeInternalError, "Method not allowed", "Only POST is allowed")
+		return
+	}
+
+	data, ok := readXMLUpload(w, r)
+	if !ok {
+		return
+	}
+
+	root, err := parseXMLTree(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidXML, "Invalid XML document", err.Error())
+		return
+	}
+	violations, err := schemaViolations(root)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidXML, "Unsupported document", err.Error())
+		return
+	}
+	if len(violations) > 0 {
+		writeViolations(w, http.StatusUnprocessableEntity, ErrCodeSchemaValidation, "Document is not schema-valid", violations)
+		return
+	}
+	parsed, err := parseEbInterfaceTree(root)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidXML, "Unsupported document", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(parsed); err != nil {
 		log.Printf("write response error: %v", err)
 	}
 }
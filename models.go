@@ -7,19 +7,86 @@ import (
 	"io"
 	"math"
 	"regexp"
-	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // -------- JSON input models (aligned with tests/golden_test.json) --------
 
+// DecimalJSON is a decimal number in the JSON models. It is written as a JSON
+// number, matching the *_cents fields, and read from a number or a string.
+type DecimalJSON struct {
+	decimal.Decimal
+}
+
+func (d DecimalJSON) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *DecimalJSON) UnmarshalJSON(data []byte) error {
+	return d.Decimal.UnmarshalJSON(data)
+}
+
 type InvoiceJSON struct {
-	InvoiceNumber string         `json:"invoice_number"`
-	InvoiceDate   string         `json:"invoice_date"` // ISO-8601 (YYYY-MM-DD)
-	Biller        BillerJSON     `json:"biller"`
-	Recipient     RecipientJSON  `json:"recipient"`
-	Items         []LineItemJSON `json:"items"`
-	Payment       PaymentDetails `json:"payment"`
+	DocumentType    string                 `json:"document_type,omitempty"` // invoice (default), credit_memo, cancellation, advance_payment or final_settlement
+	InvoiceNumber   string                 `json:"invoice_number"`
+	InvoiceDate     string                 `json:"invoice_date"`               // ISO-8601 (YYYY-MM-DD)
+	OriginalInvoice *DocumentReferenceJSON `json:"original_invoice,omitempty"` // Required for credit_memo and cancellation
+	Biller          BillerJSON             `json:"biller"`
+	Recipient       RecipientJSON          `json:"recipient"`
+	OrderingParty   *OrderingPartyJSON     `json:"ordering_party,omitempty"` // Where another party than the recipient placed the order
+	Delivery        *DeliveryJSON          `json:"delivery,omitempty"`       // Delivery date or period and address; items may state their own
+	Items           []LineItemJSON         `json:"items"`
+	Adjustments     []AdjustmentJSON       `json:"adjustments,omitempty"`      // Document level reductions and surcharges
+	Prepayments     []PrepaymentJSON       `json:"prepayments,omitempty"`      // Deducted from the payable amount
+	AdvanceInvoices []AdvanceInvoiceJSON   `json:"advance_invoices,omitempty"` // final_settlement only
+	Payment         PaymentDetails         `json:"payment"`
+	PaymentTerms    *PaymentTermsJSON      `json:"payment_terms,omitempty"`
+	VATRounding     string                 `json:"vat_rounding,omitempty"` // line (default) or bucket; the account setting applies when empty
+	Profile         string                 `json:"profile,omitempty"`      // b2g_federal (default), b2g_state or b2b; the account setting applies when empty
+}
+
+// Supported values for InvoiceJSON.DocumentType.
+const (
+	DocTypeInvoice      = "invoice"
+	DocTypeCreditMemo   = "credit_memo"
+	DocTypeCancellation = "cancellation"
+
+	DocTypeAdvancePayment  = "advance_payment"  // Anzahlungsrechnung
+	DocTypeFinalSettlement = "final_settlement" // Schlussrechnung
+)
+
+// Supported values for InvoiceJSON.VATRounding.
+const (
+	VATRoundingLine   = "line"   // Tax is rounded per line and per document level adjustment, then summed
+	VATRoundingBucket = "bucket" // Tax is rounded once per tax rate and category on the bucket total
+
+	DefaultVATRounding = VATRoundingLine
+)
+
+// vatRounding returns the rounding strategy of an invoice.
+func vatRounding(inv InvoiceJSON) string {
+	if inv.VATRounding == "" {
+		return DefaultVATRounding
+	}
+	return inv.VATRounding
+}
+
+// validateVATRounding rejects unknown rounding strategies; empty selects the default.
+func validateVATRounding(strategy string) error {
+	switch strategy {
+	case "", VATRoundingLine, VATRoundingBucket:
+		return nil
+	}
+	return fmt.Errorf("vat_rounding %q is not supported (supported: %s, %s)", strategy, VATRoundingLine, VATRoundingBucket)
+}
+
+// DocumentReferenceJSON points at a previously issued invoice.
+type DocumentReferenceJSON struct {
+	InvoiceNumber string `json:"invoice_number"`
+	InvoiceDate   string `json:"invoice_date"` // ISO-8601 (YYYY-MM-DD)
+	Comment       string `json:"comment,omitempty"`
 }
 
 type BillerJSON struct {
@@ -27,8 +94,11 @@ type BillerJSON struct {
 	VATID       string      `json:"vat_id"`
 	BillerID    string      `json:"biller_id"`
 	Email       string      `json:"email,omitempty"`
+	Phone       string      `json:"phone,omitempty"` // Required for XRechnung output
 	ContactName string      `json:"contact_name,omitempty"`
 	Address     AddressJSON `json:"address"`
+
+	FurtherIdentifications []FurtherIdentificationJSON `json:"further_identifications,omitempty"` // Firmenbuchnummer and court, GLN, DVR, supplier numbers
 }
 
 type RecipientJSON struct {
@@ -38,19 +108,96 @@ type RecipientJSON struct {
 	Email       string      `json:"email,omitempty"`
 	ContactName string      `json:"contact_name,omitempty"`
 	Address     AddressJSON `json:"address"`
+
+	FurtherIdentifications []FurtherIdentificationJSON `json:"further_identifications,omitempty"` // e.g. GLN
+}
+
+// OrderingPartyJSON is the party that placed the order where it differs from
+// the invoice recipient, e.g. one department of a municipality ordering and
+// another paying. EN 16931 has no ordering party, so only ebInterface states it.
+type OrderingPartyJSON struct {
+	Name        string      `json:"name"`
+	VATID       string      `json:"vat_id"`
+	CustomerID  string      `json:"customer_id"` // Biller's customer number of the ordering party
+	Email       string      `json:"email,omitempty"`
+	ContactName string      `json:"contact_name,omitempty"` // Required with email
+	Address     AddressJSON `json:"address"`
 }
 
 type AddressJSON struct {
-	Street string `json:"street"`
-	ZIP    string `json:"zip"`
-	City   string `json:"city"`
+	Street  string `json:"street"`
+	ZIP     string `json:"zip"`
+	City    string `json:"city"`
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code; default AT
 }
 
 type LineItemJSON struct {
-	Description    string  `json:"description"`
-	Quantity       int64   `json:"quantity"`
-	UnitPriceCents int64   `json:"unit_price_cents"`
-	TaxRate        float64 `json:"tax_rate"`
+	Description        string           `json:"description"`
+	Quantity           DecimalJSON      `json:"quantity"`       // Up to 4 decimals, e.g. 7.5
+	Unit               string           `json:"unit,omitempty"` // UN/ECE Rec 20 code or alias such as h, day, kg; default C62
+	UnitPriceCents     int64            `json:"unit_price_cents,omitempty"`
+	UnitPrice          *DecimalJSON     `json:"unit_price,omitempty"` // EUR with up to 4 decimals, e.g. 0.1234; alternative to unit_price_cents
+	TaxRate            float64          `json:"tax_rate"`
+	TaxCategory        string           `json:"tax_category,omitempty"`         // S, Z, AE, K, G or E; derived from tax_rate when empty
+	TaxExemptionReason string           `json:"tax_exemption_reason,omitempty"` // Legal note for AE, K, G and E; AE, K and G default to the statutory text
+	Adjustments        []AdjustmentJSON `json:"adjustments,omitempty"`          // Applied to quantity x unit price
+
+	OrderID                string `json:"order_id,omitempty"`                 // Recipient's order of this line; default recipient.order_id
+	OrderPosition          string `json:"order_position,omitempty"`           // Position in that order; default the line number where the profile links lines
+	BillerArticleNumber    string `json:"biller_article_number,omitempty"`    // Biller's own article number
+	RecipientArticleNumber string `json:"recipient_article_number,omitempty"` // Recipient's article number
+	GTIN                   string `json:"gtin,omitempty"`                     // GTIN-8, -12, -13 or -14 with check digit
+
+	Delivery *DeliveryJSON `json:"delivery,omitempty"` // Where delivery of this line differs from the document
+}
+
+// unitPrice returns the unit price in EUR.
+func (li LineItemJSON) unitPrice() decimal.Decimal {
+	if li.UnitPrice != nil {
+		return li.UnitPrice.Decimal
+	}
+	return decimal.New(li.UnitPriceCents, -2)
+}
+
+// AdjustmentJSON is a reduction (discount, rebate) or a surcharge (e.g. a
+// shipping fee), given either as a percentage or as a fixed amount.
+type AdjustmentJSON struct {
+	Type        string   `json:"type"`                   // reduction or surcharge
+	Percentage  float64  `json:"percentage,omitempty"`   // 0.01-100, at most two decimals
+	AmountCents int64    `json:"amount_cents,omitempty"` // Fixed amount, exclusive of VAT
+	Reason      string   `json:"reason,omitempty"`
+	TaxRate     *float64 `json:"tax_rate,omitempty"`     // Document level only: the tax rate whose line amounts are adjusted
+	TaxCategory string   `json:"tax_category,omitempty"` // Document level only, narrows tax_rate; derived from it when empty
+}
+
+// Supported values for AdjustmentJSON.Type.
+const (
+	AdjustmentReduction = "reduction"
+	AdjustmentSurcharge = "surcharge"
+)
+
+// AdvanceInvoiceJSON is an advance payment invoice settled by a final
+// settlement. Its net and tax amounts per rate are deducted as invoiced.
+type AdvanceInvoiceJSON struct {
+	InvoiceNumber string           `json:"invoice_number"`
+	InvoiceDate   string           `json:"invoice_date"` // ISO-8601 (YYYY-MM-DD)
+	Taxes         []AdvanceTaxJSON `json:"taxes"`
+}
+
+// AdvanceTaxJSON is the net amount and tax an advance invoice stated for one tax rate.
+type AdvanceTaxJSON struct {
+	TaxRate     float64 `json:"tax_rate"`
+	TaxCategory string  `json:"tax_category,omitempty"` // Derived from tax_rate when empty
+	NetCents    int64   `json:"net_cents"`
+	TaxCents    int64   `json:"tax_cents"`
+}
+
+// PrepaymentJSON is an amount already paid, typically settling an earlier
+// advance invoice which is then referenced.
+type PrepaymentJSON struct {
+	AmountCents   int64  `json:"amount_cents"`             // Gross amount
+	InvoiceNumber string `json:"invoice_number,omitempty"` // Advance invoice settled by the payment
+	InvoiceDate   string `json:"invoice_date,omitempty"`   // ISO-8601 (YYYY-MM-DD)
 }
 
 type PaymentDetails struct {
@@ -58,37 +205,40 @@ type PaymentDetails struct {
 	BIC  string `json:"bic"`
 }
 
-// -------- ebInterface 6.1 XML models (simplified) --------
-
-// EbTax represents the top-level tax element (required after Details).
-// Contains a list of TaxItem elements with full tax information.
-type EbTax struct {
-	TaxItems []EbTaxItemSummary `xml:"TaxItem"` // TaxItem elements with TaxableAmount, TaxPercent, TaxAmount
-}
-
-// EbInterfaceInvoice represents a minimal ebInterface 6.1 invoice.
-// Field order here defines the element order in the generated XML.
-// Correct order based on official ebInterface 6.1 example:
-// InvoiceNumber, InvoiceDate, Biller, InvoiceRecipient, Details, Tax, TotalGrossAmount, PayableAmount, PaymentMethod
-// Note: There is NO InvoiceSummary element in ebInterface 6.1 - tax summary is in Tax element
-type EbInterfaceInvoice struct {
-	XMLName          xml.Name        `xml:"http://www.ebinterface.at/schema/6p1/ Invoice"`
-	GeneratingSystem string          `xml:"GeneratingSystem,attr"`
-	DocumentType     string          `xml:"DocumentType,attr"`
-	InvoiceCurrency  string          `xml:"InvoiceCurrency,attr"`
-	Language         string          `xml:"Language,attr"`
-	InvoiceNumber    string          `xml:"InvoiceNumber"`
-	InvoiceDate      string          `xml:"InvoiceDate"`
-	Delivery         *EbDelivery     `xml:"Delivery,omitempty"` // Optional delivery information
-	Biller           EbBiller        `xml:"Biller"`
-	InvoiceRecipient EbRecipient     `xml:"InvoiceRecipient"`
-	Details          EbDetails       `xml:"Details"`
-	Tax              EbTax           `xml:"Tax"`              // REQUIRED after Details - contains tax summary
-	TotalGrossAmount string          `xml:"TotalGrossAmount"` // Direct child of Invoice
-	PayableAmount    string          `xml:"PayableAmount"`    // Direct child of Invoice
-	PaymentMethod    EbPaymentMethod `xml:"PaymentMethod"`    // PaymentMethod (not PaymentInstructions)
-	// Note: Extensions removed - not in official ebInterface 6.1 example
-	// Optional elements after PaymentMethod: PaymentConditions, Comment, Extension (singular)
+// PaymentTermsJSON states when the invoice is due, either as a date or as a
+// number of days after invoice_date, and optional early payment discounts.
+type PaymentTermsJSON struct {
+	DueDate string       `json:"due_date,omitempty"` // ISO-8601 (YYYY-MM-DD)
+	NetDays int          `json:"net_days,omitempty"`
+	Skonto  []SkontoJSON `json:"skonto,omitempty"` // Ordered by deadline
+}
+
+// SkontoJSON is an early payment discount tier (Skonto). The deadline is
+// either a date or a number of days after invoice_date.
+type SkontoJSON struct {
+	Percentage float64 `json:"percentage"`
+	Days       int     `json:"days,omitempty"`
+	Date       string  `json:"date,omitempty"` // ISO-8601 (YYYY-MM-DD)
+}
+
+// -------- ebInterface XML models shared by all versions --------
+
+// EbCancelledOriginalDocument identifies the invoice that is cancelled (Storno).
+// Element order: InvoiceNumber, InvoiceDate, DocumentType, Comment
+type EbCancelledOriginalDocument struct {
+	InvoiceNumber string `xml:"InvoiceNumber"`
+	InvoiceDate   string `xml:"InvoiceDate"`
+	DocumentType  string `xml:"DocumentType"`
+	Comment       string `xml:"Comment,omitempty"`
+}
+
+// EbRelatedDocument references an earlier document, e.g. the invoice corrected by a credit memo.
+// Element order: InvoiceNumber, InvoiceDate, DocumentType, Comment
+type EbRelatedDocument struct {
+	InvoiceNumber string `xml:"InvoiceNumber"`
+	InvoiceDate   string `xml:"InvoiceDate,omitempty"`
+	DocumentType  string `xml:"DocumentType,omitempty"`
+	Comment       string `xml:"Comment,omitempty"`
 }
 
 // EbAddress models the structured postal address required by ebInterface.
@@ -115,86 +265,92 @@ type EbContact struct {
 	Email string `xml:"Email,omitempty"`
 }
 
-// EbDelivery represents delivery information (optional but recommended for B2G).
+// EbDelivery states the delivery date or period and where the goods were
+// delivered, for the whole invoice or a single line item.
+// Element order: Date or Period, Address, Contact
 type EbDelivery struct {
-	Date    string    `xml:"Date"`    // Delivery date (same as invoice date if not specified)
-	Address EbAddress `xml:"Address"` // Delivery address (usually same as biller address)
-	Contact EbContact `xml:"Contact,omitempty"`
+	Date    string     `xml:"Date,omitempty"`
+	Period  *EbPeriod  `xml:"Period,omitempty"`
+	Address *EbAddress `xml:"Address,omitempty"`
+	Contact *EbContact `xml:"Contact,omitempty"`
+}
+
+// EbPeriod is a delivery period. Element order: FromDate, ToDate
+type EbPeriod struct {
+	FromDate string `xml:"FromDate"`
+	ToDate   string `xml:"ToDate"`
 }
 
-// EbBiller follows strict element order: VATID, Address, Contact, InvoiceRecipientsBillerID.
+// EbBiller follows strict element order: VATID, FurtherIdentification, Address, Contact, InvoiceRecipientsBillerID.
 type EbBiller struct {
-	VATID                     string    `xml:"VATIdentificationNumber"`
-	Address                   EbAddress `xml:"Address"`
-	Contact                   EbContact `xml:"Contact"`
-	InvoiceRecipientsBillerID string    `xml:"InvoiceRecipientsBillerID,omitempty"`
+	VATID                     string                    `xml:"VATIdentificationNumber"`
+	FurtherIdentification     []EbFurtherIdentification `xml:"FurtherIdentification,omitempty"`
+	Address                   EbAddress                 `xml:"Address"`
+	Contact                   EbContact                 `xml:"Contact"`
+	InvoiceRecipientsBillerID string                    `xml:"InvoiceRecipientsBillerID,omitempty"`
 }
 
-// EbRecipient follows strict element order: VATID, OrderReference, Address, Contact.
+// EbRecipient follows strict element order: VATID, FurtherIdentification, OrderReference, Address, Contact.
 type EbRecipient struct {
-	VATID          string           `xml:"VATIdentificationNumber"`
-	OrderReference EbOrderReference `xml:"OrderReference"`
-	Address        EbAddress        `xml:"Address"`
-	Contact        EbContact        `xml:"Contact"`
+	VATID                 string                    `xml:"VATIdentificationNumber"`
+	FurtherIdentification []EbFurtherIdentification `xml:"FurtherIdentification,omitempty"`
+	OrderReference        *EbOrderReference         `xml:"OrderReference,omitempty"`
+	Address               EbAddress                 `xml:"Address"`
+	Contact               EbContact                 `xml:"Contact"`
 }
 
-// EbOrderReference wraps the Austrian B2G order number in an OrderID element.
-type EbOrderReference struct {
-	OrderID string `xml:"OrderID"`
+// EbFurtherIdentification is an identifier of a party besides its VAT ID;
+// Type names it, e.g. FN for the Firmenbuchnummer.
+type EbFurtherIdentification struct {
+	Type  string `xml:"IdentificationType,attr"`
+	Value string `xml:",chardata"`
 }
 
-type EbDetails struct {
-	ItemList EbItemList `xml:"ItemList"`
+// EbOrderingParty follows strict element order: VATID, Address, Contact, BillersOrderingPartyID.
+type EbOrderingParty struct {
+	VATID                  string     `xml:"VATIdentificationNumber"`
+	Address                EbAddress  `xml:"Address"`
+	Contact                *EbContact `xml:"Contact,omitempty"`
+	BillersOrderingPartyID string     `xml:"BillersOrderingPartyID"`
 }
 
-type EbItemList struct {
-	Items []EbItem `xml:"ListLineItem"`
+// EbOrderReference wraps the Austrian B2G order number in an OrderID element.
+type EbOrderReference struct {
+	OrderID string `xml:"OrderID"`
 }
 
 // EbQuantity wraps the quantity value and its mandatory unit attribute.
 type EbQuantity struct {
-	Unit  string  `xml:"Unit,attr"`
-	Value float64 `xml:",chardata"`
+	Unit  string `xml:"Unit,attr"`
+	Value string `xml:",chardata"` // Decimal4Type
 }
 
-// EbOrderReferenceItem represents order reference for a line item.
-type EbOrderReferenceItem struct {
-	OrderID             string `xml:"OrderID"`             // Order ID from recipient
-	OrderPositionNumber string `xml:"OrderPositionNumber"` // Position number in order (required if OrderID is present)
-}
-
-// EbItem represents a single line item in the invoice.
-// Element order: Description, Quantity, UnitPrice, InvoiceRecipientsOrderReference (optional), TaxItem, LineItemAmount
-type EbItem struct {
-	Description                     string                `xml:"Description"`
-	Quantity                        EbQuantity            `xml:"Quantity"`
-	UnitPrice                       string                `xml:"UnitPrice"` // Decimal string (e.g., "120.00")
-	InvoiceRecipientsOrderReference *EbOrderReferenceItem `xml:"InvoiceRecipientsOrderReference,omitempty"`
-	TaxItem                         EbTaxItem             `xml:"TaxItem"`
-	LineItemAmount                  string                `xml:"LineItemAmount"` // Decimal string (e.g., "1200.00") - MUST come after TaxItem
+// EbReductionAndSurchargeListLineItemDetails lists the reductions and surcharges of a line item.
+type EbReductionAndSurchargeListLineItemDetails struct {
+	Entries []EbReductionAndSurchargeBase // ReductionListLineItem or SurchargeListLineItem
 }
 
-// EbTaxPercent represents the tax rate with category code as an attribute.
-type EbTaxPercent struct {
-	TaxCategoryCode string  `xml:"TaxCategoryCode,attr"` // e.g., S
-	Value           float64 `xml:",chardata"`            // e.g., 20
+// EbReductionAndSurchargeBase is a line level reduction or surcharge; XMLName selects the kind.
+// Element order: BaseAmount, Percentage, Amount, Comment
+type EbReductionAndSurchargeBase struct {
+	XMLName    xml.Name
+	BaseAmount string `xml:"BaseAmount"`
+	Percentage string `xml:"Percentage,omitempty"`
+	Amount     string `xml:"Amount"`
+	Comment    string `xml:"Comment,omitempty"`
 }
 
-// EbTaxItem represents tax information for a line item (inside Details/ListLineItem).
-// Element order: TaxableAmount, TaxPercent
-// Note: In Details, TaxItem does NOT have TaxAmount - only TaxableAmount and TaxPercent
-type EbTaxItem struct {
-	TaxableAmount string       `xml:"TaxableAmount"` // Decimal string (e.g., "1200.00") - net amount for the line
-	TaxPercent    EbTaxPercent `xml:"TaxPercent"`    // Tax rate with category code attribute (e.g., <TaxPercent TaxCategoryCode="S">20</TaxPercent>)
+// EbArticleNumber is an article number of a line item; Type tells whose
+// number it is, e.g. BillersArticleNumber or GTIN.
+type EbArticleNumber struct {
+	Type  string `xml:"ArticleNumberType,attr"`
+	Value string `xml:",chardata"`
 }
 
-// EbTaxItemSummary represents tax summary information (inside Tax element).
-// Element order: TaxableAmount, TaxPercent, TaxAmount
-// Note: In Tax element, TaxItem includes TaxAmount
-type EbTaxItemSummary struct {
-	TaxableAmount string       `xml:"TaxableAmount"` // Decimal string (e.g., "1200.00") - MUST be FIRST
-	TaxPercent    EbTaxPercent `xml:"TaxPercent"`    // MUST be SECOND - Tax rate with category code attribute
-	TaxAmount     string       `xml:"TaxAmount"`     // Decimal string (e.g., "240.00") - MUST be THIRD
+// EbOrderReferenceItem represents order reference for a line item.
+type EbOrderReferenceItem struct {
+	OrderID             string `xml:"OrderID"`                       // Order ID from recipient
+	OrderPositionNumber string `xml:"OrderPositionNumber,omitempty"` // Position number in that order
 }
 
 // EbPaymentMethod represents payment method information (required after PayableAmount).
@@ -217,6 +373,21 @@ type EbBeneficiaryAccount struct {
 	BankAccountOwner string `xml:"BankAccountOwner"` // Account owner name - MUST be THIRD
 }
 
+// EbPaymentConditions carries the due date and early payment discounts
+// (follows PaymentMethod). Element order: DueDate, Discount
+type EbPaymentConditions struct {
+	DueDate  string       `xml:"DueDate"`
+	Discount []EbDiscount `xml:"Discount,omitempty"`
+}
+
+// EbDiscount is a Skonto tier. Element order: PaymentDate, BaseAmount, Percentage, Amount
+type EbDiscount struct {
+	PaymentDate string `xml:"PaymentDate"`
+	BaseAmount  string `xml:"BaseAmount"`
+	Percentage  string `xml:"Percentage"`
+	Amount      string `xml:"Amount"`
+}
+
 // EbSimpleExtensions allows carrying the original JSON invoice number for debugging.
 type EbSimpleExtensions struct {
 	OriginalInvoiceNumber string `xml:"OriginalInvoiceNumber,omitempty"`
@@ -232,18 +403,9 @@ func decodeJSON(r io.Reader, v any) error {
 
 // Validation helper functions
 var (
-	vatIDRegex = regexp.MustCompile(`^ATU\d{8}$`)
-	bicRegex   = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
-	ibanRegex  = regexp.MustCompile(`^AT\d{2}\d{16}$`)
+	bicRegex = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
 )
 
-func validateVATID(vatID string) error {
-	if !vatIDRegex.MatchString(vatID) {
-		return fmt.Errorf("vat_id must be in format ATU followed by 8 digits (e.g., ATU13585627)")
-	}
-	return nil
-}
-
 func validateBIC(bic string) error {
 	if !bicRegex.MatchString(bic) {
 		return fmt.Errorf("bic must be 8 or 11 characters (e.g., BKAUATWW)")
@@ -251,18 +413,65 @@ func validateBIC(bic string) error {
 	return nil
 }
 
-func validateIBAN(iban string) error {
-	// Remove spaces for validation
-	iban = strings.ReplaceAll(iban, " ", "")
-	if !ibanRegex.MatchString(iban) {
-		return fmt.Errorf("iban must be in Austrian format: AT followed by 20 digits (e.g., AT123400000000005678)")
+func validateDate(dateStr string) error {
+	if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+		return fmt.Errorf("date must be in YYYY-MM-DD format")
 	}
 	return nil
 }
 
-func validateDate(dateStr string) error {
-	if _, err := time.Parse("2006-01-02", dateStr); err != nil {
-		return fmt.Errorf("date must be in YYYY-MM-DD format")
+// paymentDate resolves a payment deadline given either as an ISO date or as a
+// number of days after invoiceDate. It returns "" if invoiceDate is invalid.
+func paymentDate(invoiceDate, date string, days int) string {
+	if date != "" {
+		return date
+	}
+	d, err := time.Parse("2006-01-02", invoiceDate)
+	if err != nil {
+		return ""
+	}
+	return d.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// documentType returns the normalized document type, defaulting to a regular invoice.
+func documentType(inv InvoiceJSON) string {
+	if inv.DocumentType == "" {
+		return DocTypeInvoice
+	}
+	return inv.DocumentType
+}
+
+// validateDocumentType enforces the rules specific to each document type.
+// Credit memos and cancellations must reference the original invoice, which
+// must have been issued on or before the correcting document.
+func validateDocumentType(inv InvoiceJSON) error {
+	if len(inv.AdvanceInvoices) > 0 && documentType(inv) != DocTypeFinalSettlement {
+		return fmt.Errorf("advance_invoices are only allowed for final_settlement")
+	}
+	switch documentType(inv) {
+	case DocTypeInvoice, DocTypeAdvancePayment, DocTypeFinalSettlement:
+		if inv.OriginalInvoice != nil {
+			return fmt.Errorf("original_invoice is only allowed for credit_memo and cancellation")
+		}
+		return nil
+	case DocTypeCreditMemo, DocTypeCancellation:
+	default:
+		return fmt.Errorf("document_type must be one of invoice, credit_memo, cancellation, advance_payment, final_settlement")
+	}
+
+	ref := inv.OriginalInvoice
+	if ref == nil || ref.InvoiceNumber == "" || ref.InvoiceDate == "" {
+		return fmt.Errorf("original_invoice.invoice_number and original_invoice.invoice_date are required for %s", documentType(inv))
+	}
+	if err := validateDate(ref.InvoiceDate); err != nil {
+		return fmt.Errorf("original_invoice.invoice_date: %w", err)
+	}
+	if ref.InvoiceNumber == inv.InvoiceNumber {
+		return fmt.Errorf("original_invoice.invoice_number must differ from invoice_number")
+	}
+	// Both dates are validated YYYY-MM-DD strings, so they compare lexically.
+	if ref.InvoiceDate > inv.InvoiceDate {
+		return fmt.Errorf("original_invoice.invoice_date must not be after invoice_date")
 	}
 	return nil
 }
@@ -277,45 +486,100 @@ func validateInvoice(inv InvoiceJSON) error {
 	if err := validateDate(inv.InvoiceDate); err != nil {
 		return fmt.Errorf("invoice_date: %w", err)
 	}
+	if err := validateDocumentType(inv); err != nil {
+		return err
+	}
+	if err := validateVATRounding(inv.VATRounding); err != nil {
+		return err
+	}
+	if err := validateProfile(inv.Profile); err != nil {
+		return err
+	}
 	if inv.Biller.Name == "" || inv.Biller.VATID == "" {
 		return fmt.Errorf("biller.name and biller.vat_id are required")
 	}
-	if err := validateVATID(inv.Biller.VATID); err != nil {
+	if err := validateAddress(inv.Biller.Address); err != nil {
+		return fmt.Errorf("biller.address: %w", err)
+	}
+	if err := validatePartyVATID(inv.Biller.VATID, addressCountry(inv.Biller.Address)); err != nil {
 		return fmt.Errorf("biller.vat_id: %w", err)
 	}
-	if inv.Biller.Address.Street == "" || inv.Biller.Address.ZIP == "" || inv.Biller.Address.City == "" {
-		return fmt.Errorf("biller.address: street, zip and city are required")
+	if err := validateFurtherIdentifications(inv.Biller.FurtherIdentifications, "biller.further_identifications"); err != nil {
+		return err
 	}
-	if inv.Recipient.Name == "" || inv.Recipient.VATID == "" {
-		return fmt.Errorf("recipient.name and recipient.vat_id are required")
+	if inv.Recipient.Name == "" {
+		return fmt.Errorf("recipient.name is required")
 	}
-	if err := validateVATID(inv.Recipient.VATID); err != nil {
-		return fmt.Errorf("recipient.vat_id: %w", err)
+	if err := validateAddress(inv.Recipient.Address); err != nil {
+		return fmt.Errorf("recipient.address: %w", err)
+	}
+	// Whether recipient.vat_id may be left out depends on the profile.
+	if inv.Recipient.VATID != "" {
+		if err := validatePartyVATID(inv.Recipient.VATID, addressCountry(inv.Recipient.Address)); err != nil {
+			return fmt.Errorf("recipient.vat_id: %w", err)
+		}
 	}
-	if inv.Recipient.Address.Street == "" || inv.Recipient.Address.ZIP == "" || inv.Recipient.Address.City == "" {
-		return fmt.Errorf("recipient.address: street, zip and city are required")
+	if err := validateFurtherIdentifications(inv.Recipient.FurtherIdentifications, "recipient.further_identifications"); err != nil {
+		return err
 	}
-	// B2G: OrderReference mandatory
-	if inv.Recipient.OrderID == "" {
-		return fmt.Errorf("recipient.order_id is required for B2G")
+	if err := validateOrderingParty(inv.OrderingParty); err != nil {
+		return err
 	}
 	if len(inv.Items) == 0 {
 		return fmt.Errorf("at least one line item is required")
 	}
 	for i, d := range inv.Items {
-		if d.Quantity <= 0 {
+		if d.Quantity.Sign() <= 0 {
 			return fmt.Errorf("items[%d].quantity must be > 0", i)
 		}
+		if !hasMaxDecimals(d.Quantity.Decimal, maxQuantityDecimals) {
+			return fmt.Errorf("items[%d].quantity must have at most %d decimals", i, maxQuantityDecimals)
+		}
 		if d.Description == "" {
 			return fmt.Errorf("items[%d].description is required", i)
 		}
+		if _, ok := resolveUnitCode(d.Unit); !ok {
+			return fmt.Errorf("items[%d].unit %q is not a known UN/ECE Recommendation 20 code", i, d.Unit)
+		}
 		if d.UnitPriceCents < 0 {
 			return fmt.Errorf("items[%d].unit_price_cents must be >= 0", i)
 		}
+		if d.UnitPrice != nil {
+			if d.UnitPriceCents != 0 {
+				return fmt.Errorf("items[%d]: only one of unit_price and unit_price_cents is allowed", i)
+			}
+			if d.UnitPrice.Sign() < 0 || !hasMaxDecimals(d.UnitPrice.Decimal, maxUnitPriceDecimals) {
+				return fmt.Errorf("items[%d].unit_price must be >= 0 with at most %d decimals", i, maxUnitPriceDecimals)
+			}
+		}
 		if d.TaxRate < 0 || d.TaxRate > 100 {
 			return fmt.Errorf("items[%d].tax_rate must be between 0 and 100", i)
 		}
 	}
+	if err := validateTaxCategories(inv); err != nil {
+		return err
+	}
+	if err := validateLineReferences(inv); err != nil {
+		return err
+	}
+	if err := validateDeliveries(inv); err != nil {
+		return err
+	}
+	if err := validateAdjustments(inv); err != nil {
+		return err
+	}
+	if err := validateAdvanceInvoices(inv); err != nil {
+		return err
+	}
+	if err := validatePrepayments(inv); err != nil {
+		return err
+	}
+	if err := validatePaymentTerms(inv); err != nil {
+		return err
+	}
+	if err := validateProfileRules(inv); err != nil {
+		return err
+	}
 	if inv.Payment.IBAN == "" || inv.Payment.BIC == "" {
 		return fmt.Errorf("payment.iban and payment.bic are required")
 	}
@@ -325,9 +589,256 @@ func validateInvoice(inv InvoiceJSON) error {
 	if err := validateBIC(inv.Payment.BIC); err != nil {
 		return fmt.Errorf("payment.bic: %w", err)
 	}
+	if err := validateBICCountry(inv.Payment.BIC, inv.Payment.IBAN); err != nil {
+		return fmt.Errorf("payment.bic: %w", err)
+	}
+	return nil
+}
+
+// validateOrderingParty checks the ordering party if the invoice names one.
+func validateOrderingParty(op *OrderingPartyJSON) error {
+	if op == nil {
+		return nil
+	}
+	if op.Name == "" || op.VATID == "" || op.CustomerID == "" {
+		return fmt.Errorf("ordering_party.name, ordering_party.vat_id and ordering_party.customer_id are required")
+	}
+	if err := validateAddress(op.Address); err != nil {
+		return fmt.Errorf("ordering_party.address: %w", err)
+	}
+	if err := validatePartyVATID(op.VATID, addressCountry(op.Address)); err != nil {
+		return fmt.Errorf("ordering_party.vat_id: %w", err)
+	}
+	if op.Email != "" && op.ContactName == "" {
+		return fmt.Errorf("ordering_party.email requires ordering_party.contact_name")
+	}
+	return nil
+}
+
+// validateAdjustment checks a single reduction or surcharge; field is its
+// JSON path for error messages.
+func validateAdjustment(a AdjustmentJSON, field string) error {
+	if a.Type != AdjustmentReduction && a.Type != AdjustmentSurcharge {
+		return fmt.Errorf("%s.type must be reduction or surcharge", field)
+	}
+	if (a.Percentage != 0) == (a.AmountCents != 0) {
+		return fmt.Errorf("%s: exactly one of percentage and amount_cents is required", field)
+	}
+	if a.Percentage != 0 && !validPercentage(a.Percentage) {
+		return fmt.Errorf("%s.percentage must be between 0.01 and 100 with at most two decimals", field)
+	}
+	// With a percentage, amount_cents 0 means it is not given.
+	if a.Percentage == 0 && a.AmountCents <= 0 {
+		return fmt.Errorf("%s.amount_cents must be > 0", field)
+	}
 	return nil
 }
 
+// Precision accepted for line item quantities (ebInterface Decimal4Type) and unit prices.
+const (
+	maxQuantityDecimals  = 4
+	maxUnitPriceDecimals = 4
+)
+
+// hasMaxDecimals reports whether d has at most places decimals.
+func hasMaxDecimals(d decimal.Decimal, places int32) bool {
+	return d.Equal(d.Truncate(places))
+}
+
+// validPercentage reports whether p fits the ebInterface PercentageType:
+// 0.01 to 100 with at most two decimals.
+func validPercentage(p float64) bool {
+	return p > 0 && p <= 100 && math.Abs(p*100-math.Round(p*100)) < 1e-9
+}
+
+// validateAdvanceInvoices checks the advance invoices deducted by a final
+// settlement. Per tax rate they must not exceed the invoiced net and tax.
+func validateAdvanceInvoices(inv InvoiceJSON) error {
+	if len(inv.AdvanceInvoices) == 0 {
+		return nil
+	}
+	buckets := itemTaxBuckets(inv)
+	numbers := map[string]bool{}
+	for i, ai := range inv.AdvanceInvoices {
+		field := fmt.Sprintf("advance_invoices[%d]", i)
+		if ai.InvoiceNumber == "" || ai.InvoiceDate == "" {
+			return fmt.Errorf("%s.invoice_number and %s.invoice_date are required", field, field)
+		}
+		if err := validateDate(ai.InvoiceDate); err != nil {
+			return fmt.Errorf("%s.invoice_date: %w", field, err)
+		}
+		if ai.InvoiceDate > inv.InvoiceDate {
+			return fmt.Errorf("%s.invoice_date must not be after invoice_date", field)
+		}
+		if ai.InvoiceNumber == inv.InvoiceNumber || numbers[ai.InvoiceNumber] {
+			return fmt.Errorf("%s.invoice_number must be unique and differ from invoice_number", field)
+		}
+		numbers[ai.InvoiceNumber] = true
+		if len(ai.Taxes) == 0 {
+			return fmt.Errorf("%s.taxes must list at least one tax rate", field)
+		}
+		for j, at := range ai.Taxes {
+			if !buckets[taxBucketKey{rate: at.TaxRate, category: taxCategory(at.TaxCategory, at.TaxRate)}] {
+				return fmt.Errorf("%s.taxes[%d]: tax_rate and tax_category must match at least one item", field, j)
+			}
+			if at.NetCents <= 0 || at.TaxCents < 0 {
+				return fmt.Errorf("%s.taxes[%d]: net_cents must be > 0 and tax_cents >= 0", field, j)
+			}
+			if at.TaxRate == 0 && at.TaxCents != 0 {
+				return fmt.Errorf("%s.taxes[%d].tax_cents must be 0 for tax rate 0", field, j)
+			}
+		}
+	}
+	for i, pp := range inv.Prepayments {
+		if pp.InvoiceNumber != "" && numbers[pp.InvoiceNumber] {
+			return fmt.Errorf("prepayments[%d] settles advance invoice %s which is already deducted", i, pp.InvoiceNumber)
+		}
+	}
+	for _, b := range computeTotals(inv).Buckets {
+		if b.TaxableCts < 0 || b.TaxCts < 0 {
+			return fmt.Errorf("advance_invoices exceed the amounts invoiced at %s%%", formatRate(b.Rate))
+		}
+	}
+	return nil
+}
+
+// validatePrepayments checks the prepaid amounts and their references; in
+// total they must not exceed the gross amount.
+func validatePrepayments(inv InvoiceJSON) error {
+	if len(inv.Prepayments) == 0 {
+		return nil
+	}
+	if documentSign(inv) < 0 {
+		return fmt.Errorf("prepayments are not allowed for %s", documentType(inv))
+	}
+	for i, pp := range inv.Prepayments {
+		if pp.AmountCents <= 0 {
+			return fmt.Errorf("prepayments[%d].amount_cents must be > 0", i)
+		}
+		if pp.InvoiceDate != "" {
+			if pp.InvoiceNumber == "" {
+				return fmt.Errorf("prepayments[%d].invoice_date requires invoice_number", i)
+			}
+			if err := validateDate(pp.InvoiceDate); err != nil {
+				return fmt.Errorf("prepayments[%d].invoice_date: %w", i, err)
+			}
+		}
+	}
+	t := computeTotals(inv)
+	if t.PrepaidCts > t.GrossCts {
+		return fmt.Errorf("prepayments total %s exceeds the gross amount %s",
+			formatCentsAsDecimal(t.PrepaidCts), formatCentsAsDecimal(t.GrossCts))
+	}
+	return nil
+}
+
+// validatePaymentTerms checks that exactly one of due_date and net_days is
+// given and that the Skonto deadlines increase and end by the due date.
+func validatePaymentTerms(inv InvoiceJSON) error {
+	pt := inv.PaymentTerms
+	if pt == nil {
+		return nil
+	}
+	if (pt.DueDate != "") == (pt.NetDays != 0) {
+		return fmt.Errorf("payment_terms: exactly one of due_date and net_days is required")
+	}
+	// With a due_date, net_days 0 means it is not given.
+	if pt.DueDate == "" && pt.NetDays <= 0 {
+		return fmt.Errorf("payment_terms.net_days must be > 0")
+	}
+	if pt.DueDate != "" {
+		if err := validateDate(pt.DueDate); err != nil {
+			return fmt.Errorf("payment_terms.due_date: %w", err)
+		}
+	}
+	due := paymentDate(inv.InvoiceDate, pt.DueDate, pt.NetDays)
+	if due < inv.InvoiceDate {
+		return fmt.Errorf("payment_terms.due_date must not be before invoice_date")
+	}
+	if len(pt.Skonto) > 0 && documentSign(inv) < 0 {
+		return fmt.Errorf("payment_terms.skonto is not allowed for %s", documentType(inv))
+	}
+	previous := ""
+	for i, sk := range pt.Skonto {
+		if !validPercentage(sk.Percentage) {
+			return fmt.Errorf("payment_terms.skonto[%d].percentage must be between 0.01 and 100 with at most two decimals", i)
+		}
+		if (sk.Date != "") == (sk.Days != 0) || sk.Days < 0 {
+			return fmt.Errorf("payment_terms.skonto[%d]: exactly one of date and days (> 0) is required", i)
+		}
+		if sk.Date != "" {
+			if err := validateDate(sk.Date); err != nil {
+				return fmt.Errorf("payment_terms.skonto[%d].date: %w", i, err)
+			}
+		}
+		date := paymentDate(inv.InvoiceDate, sk.Date, sk.Days)
+		if date < inv.InvoiceDate || date > due {
+			return fmt.Errorf("payment_terms.skonto[%d] deadline must be between invoice_date and the due date", i)
+		}
+		if date <= previous {
+			return fmt.Errorf("payment_terms.skonto[%d] deadline must be after the previous tier", i)
+		}
+		previous = date
+	}
+	return nil
+}
+
+// validateAdjustments checks line and document level reductions and
+// surcharges. Document level entries name the tax rate they adjust, and no
+// line or tax base may turn negative.
+func validateAdjustments(inv InvoiceJSON) error {
+	buckets := itemTaxBuckets(inv)
+	for i, li := range inv.Items {
+		for j, a := range li.Adjustments {
+			field := fmt.Sprintf("items[%d].adjustments[%d]", i, j)
+			if err := validateAdjustment(a, field); err != nil {
+				return err
+			}
+			if a.TaxRate != nil || a.TaxCategory != "" {
+				return fmt.Errorf("%s: tax_rate and tax_category are only allowed for document level adjustments", field)
+			}
+		}
+	}
+	for i, a := range inv.Adjustments {
+		field := fmt.Sprintf("adjustments[%d]", i)
+		if err := validateAdjustment(a, field); err != nil {
+			return err
+		}
+		if a.TaxRate == nil {
+			return fmt.Errorf("%s.tax_rate is required", field)
+		}
+		if !buckets[taxBucketKey{rate: *a.TaxRate, category: taxCategory(a.TaxCategory, *a.TaxRate)}] {
+			return fmt.Errorf("%s: tax_rate and tax_category must match at least one item", field)
+		}
+	}
+
+	// Advance invoices are checked separately (validateAdvanceInvoices).
+	plain := inv
+	plain.AdvanceInvoices = nil
+	t := computeTotals(plain)
+	sign := documentSign(inv)
+	for i, lt := range t.Lines {
+		if sign*lt.NetCts < 0 {
+			return fmt.Errorf("items[%d]: reductions exceed the line amount", i)
+		}
+	}
+	for _, b := range t.Buckets {
+		if sign*b.TaxableCts < 0 {
+			return fmt.Errorf("adjustments: reductions exceed the line amounts taxed at %s%%", formatRate(b.Rate))
+		}
+	}
+	return nil
+}
+
+// itemTaxBuckets returns the tax rates and categories used by the items.
+func itemTaxBuckets(inv InvoiceJSON) map[taxBucketKey]bool {
+	buckets := map[taxBucketKey]bool{}
+	for _, li := range inv.Items {
+		buckets[taxBucketKey{rate: li.TaxRate, category: taxCategory(li.TaxCategory, li.TaxRate)}] = true
+	}
+	return buckets
+}
+
 func toCents(amount float64) int64 {
 	return int64(math.Round(amount * 100))
 }
@@ -341,8 +852,8 @@ func composeEbAddress(name string, a AddressJSON) EbAddress {
 		ZIP:    a.ZIP,
 		Town:   a.City,
 		Country: EbCountry{
-			CountryCode: "AT",
-			Name:        "Österreich",
+			CountryCode: addressCountry(a),
+			Name:        countryNameDE(addressCountry(a)),
 		},
 	}
 }
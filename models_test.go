@@ -0,0 +1,514 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDecimalJSON(t *testing.T) {
+	for _, in := range []string{`7.5`, `"7.5"`, `7.50`} {
+		var d DecimalJSON
+		if err := json.Unmarshal([]byte(in), &d); err != nil {
+			t.Fatalf("unmarshal %s: %v", in, err)
+		}
+		if !d.Equal(decimal.RequireFromString("7.5")) {
+			t.Errorf("unmarshal %s = %s, want 7.5", in, d)
+		}
+	}
+
+	price := DecimalJSON{decimal.RequireFromString("0.1234")}
+	out, err := json.Marshal(LineItemJSON{Description: "A", Quantity: DecimalJSON{decimal.NewFromInt(2)}, UnitPrice: &price})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"description":"A","quantity":2,"unit_price":0.1234,"tax_rate":0}`; string(out) != want {
+		t.Errorf("marshal = %s, want %s", out, want)
+	}
+	// Other decimals keep the library's default encoding.
+	if decimal.MarshalJSONWithoutQuotes {
+		t.Error("decimal.MarshalJSONWithoutQuotes is set globally")
+	}
+}
+
+func TestValidateDocumentType(t *testing.T) {
+	original := &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02"}
+	tests := []struct {
+		name     string
+		docType  string
+		original *DocumentReferenceJSON
+		wantErr  string
+	}{
+		{"invoice", "", nil, ""},
+		{"credit memo", DocTypeCreditMemo, original, ""},
+		{"cancellation", DocTypeCancellation, original, ""},
+		{"credit memo without original", DocTypeCreditMemo, nil, "original_invoice.invoice_number and original_invoice.invoice_date are required for credit_memo"},
+		{"cancellation without date", DocTypeCancellation, &DocumentReferenceJSON{InvoiceNumber: "2025-100"}, "required for cancellation"},
+		{"invoice with original", DocTypeInvoice, original, "original_invoice is only allowed for credit_memo and cancellation"},
+		{"same number", DocTypeCreditMemo, &DocumentReferenceJSON{InvoiceNumber: "2026-001", InvoiceDate: "2026-01-02"}, "must differ from invoice_number"},
+		{"original after document", DocTypeCreditMemo, &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-02-01"}, "must not be after invoice_date"},
+		{"invalid original date", DocTypeCreditMemo, &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "02.01.2026"}, "original_invoice.invoice_date"},
+		{"unknown type", "storno", nil, "document_type must be one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json") // 2026-001 of 2026-01-08
+			inv.DocumentType = tt.docType
+			inv.OriginalInvoice = tt.original
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCorrectingDocumentOutput(t *testing.T) {
+	tests := []struct {
+		docType string
+		wantEb  []string
+	}{
+		{DocTypeCreditMemo, []string{`DocumentType="CreditMemo"`, "<RelatedDocument>", "<InvoiceNumber>2025-100</InvoiceNumber>", "<Quantity Unit=\"C62\">-1</Quantity>"}},
+		{DocTypeCancellation, []string{`DocumentType="CreditMemo"`, "<CancelledOriginalDocument>", "<InvoiceNumber>2025-100</InvoiceNumber>", "<TotalGrossAmount>-5400.00</TotalGrossAmount>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.docType, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			inv.DocumentType = tt.docType
+			inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02"}
+			doc, err := TransformToEbInterface(inv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateEbInterface(doc); err != nil {
+				t.Fatalf("schema: %v", err)
+			}
+			for _, want := range tt.wantEb {
+				if !strings.Contains(string(doc), want) {
+					t.Errorf("ebInterface is missing %s", want)
+				}
+			}
+
+			// UBL states the correction as a CreditNote with positive amounts.
+			ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"<CreditNote ", "<cbc:CreditNoteTypeCode>381</cbc:CreditNoteTypeCode>", `<cbc:PayableAmount currencyID="EUR">5400.00</cbc:PayableAmount>`} {
+				if !strings.Contains(string(ubl), want) {
+					t.Errorf("UBL is missing %s", want)
+				}
+			}
+			if strings.Contains(string(ubl), ">-") {
+				t.Errorf("UBL has a negative amount:\n%s", ubl)
+			}
+		})
+	}
+}
+
+func TestValidateAdvanceInvoicesAndPrepayments(t *testing.T) {
+	advance := func(number, date string, taxes ...AdvanceTaxJSON) AdvanceInvoiceJSON {
+		return AdvanceInvoiceJSON{InvoiceNumber: number, InvoiceDate: date, Taxes: taxes}
+	}
+	tax20 := AdvanceTaxJSON{TaxRate: 20, NetCents: 150000, TaxCents: 30000}
+	// test_invoice_small.json: 2026-001 of 2026-01-08, 4,500.00 EUR net at 20 %.
+	tests := []struct {
+		name        string
+		docType     string
+		advances    []AdvanceInvoiceJSON
+		prepayments []PrepaymentJSON
+		wantErr     string
+	}{
+		{"final settlement", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", tax20)}, nil, ""},
+		{"two advance invoices", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", tax20), advance("AR-2", "2026-01-02", tax20)}, nil, ""},
+		{"advance payment with prepayment", DocTypeAdvancePayment, nil, []PrepaymentJSON{{AmountCents: 100000}}, ""},
+		{"invoice settling an advance invoice", DocTypeInvoice, nil, []PrepaymentJSON{{AmountCents: 180000, InvoiceNumber: "AR-1", InvoiceDate: "2025-12-01"}}, ""},
+		{"advance invoices on an invoice", DocTypeInvoice, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", tax20)}, nil, "advance_invoices are only allowed for final_settlement"},
+		{"without number", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("", "2025-12-01", tax20)}, nil, "advance_invoices[0].invoice_number and advance_invoices[0].invoice_date are required"},
+		{"after the settlement", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2026-02-01", tax20)}, nil, "advance_invoices[0].invoice_date must not be after invoice_date"},
+		{"duplicate number", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", tax20), advance("AR-1", "2025-12-01", tax20)}, nil, "advance_invoices[1].invoice_number must be unique"},
+		{"without taxes", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01")}, nil, "taxes must list at least one tax rate"},
+		{"other tax rate", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", AdvanceTaxJSON{TaxRate: 10, NetCents: 100, TaxCents: 10})}, nil, "must match at least one item"},
+		{"negative tax", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", AdvanceTaxJSON{TaxRate: 20, NetCents: 100, TaxCents: -20})}, nil, "net_cents must be > 0 and tax_cents >= 0"},
+		{"exceeding the invoiced amount", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", tax20), advance("AR-2", "2025-12-02", tax20), advance("AR-3", "2025-12-03", tax20), advance("AR-4", "2025-12-04", tax20)}, nil, "advance_invoices exceed the amounts invoiced at 20%"},
+		{"prepayment of a deducted advance invoice", DocTypeFinalSettlement, []AdvanceInvoiceJSON{advance("AR-1", "2025-12-01", tax20)}, []PrepaymentJSON{{AmountCents: 180000, InvoiceNumber: "AR-1"}}, "prepayments[0] settles advance invoice AR-1 which is already deducted"},
+		{"prepayment on a credit memo", DocTypeCreditMemo, nil, []PrepaymentJSON{{AmountCents: 100}}, "prepayments are not allowed for credit_memo"},
+		{"zero prepayment", DocTypeInvoice, nil, []PrepaymentJSON{{AmountCents: 0}}, "prepayments[0].amount_cents must be > 0"},
+		{"prepayment date without number", DocTypeInvoice, nil, []PrepaymentJSON{{AmountCents: 100, InvoiceDate: "2025-12-01"}}, "prepayments[0].invoice_date requires invoice_number"},
+		{"prepayments over gross", DocTypeInvoice, nil, []PrepaymentJSON{{AmountCents: 540001}}, "prepayments total 5400.01 exceeds the gross amount 5400.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			inv.DocumentType = tt.docType
+			if tt.docType == DocTypeCreditMemo {
+				inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02"}
+			}
+			inv.AdvanceInvoices = tt.advances
+			inv.Prepayments = tt.prepayments
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFinalSettlementOutput(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.DocumentType = DocTypeFinalSettlement
+	inv.AdvanceInvoices = []AdvanceInvoiceJSON{{
+		InvoiceNumber: "AR-1",
+		InvoiceDate:   "2025-12-01",
+		Taxes:         []AdvanceTaxJSON{{TaxRate: 20, NetCents: 150000, TaxCents: 30000}},
+	}}
+	inv.Prepayments = []PrepaymentJSON{{AmountCents: 60000, InvoiceNumber: "AR-0", InvoiceDate: "2025-11-01"}}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range supportedEbInterfaceVersions() {
+		doc, err := TransformToEbInterfaceVersion(inv, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateEbInterface(doc); err != nil {
+			t.Errorf("%s schema: %v", version, err)
+		}
+		for _, want := range []string{
+			`DocumentType="FinalSettlement"`,
+			"<InvoiceNumber>AR-1</InvoiceNumber>",
+			"<Comment>Anzahlung 600.00 EUR</Comment>",
+			"<TotalGrossAmount>3600.00</TotalGrossAmount>",
+			"<PayableAmount>3000.00</PayableAmount>",
+		} {
+			if !strings.Contains(string(doc), want) {
+				t.Errorf("%s is missing %s", version, want)
+			}
+		}
+	}
+
+	ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<cbc:InvoiceTypeCode>380</cbc:InvoiceTypeCode>",
+		`<cbc:PrepaidAmount currencyID="EUR">600.00</cbc:PrepaidAmount>`,
+		`<cbc:PayableAmount currencyID="EUR">3000.00</cbc:PayableAmount>`,
+	} {
+		if !strings.Contains(string(ubl), want) {
+			t.Errorf("UBL is missing %s", want)
+		}
+	}
+}
+
+func TestValidateAdjustments(t *testing.T) {
+	rate20, rate10 := 20.0, 10.0
+	// test_invoice_small.json: one line of 4,500.00 EUR at 20 %.
+	tests := []struct {
+		name    string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"line percentage", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 2.5, Reason: "Rabatt"}}
+		}, ""},
+		{"line amount", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentSurcharge, AmountCents: 1500}}
+		}, ""},
+		{"document percentage", func(inv *InvoiceJSON) {
+			inv.Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 3, TaxRate: &rate20}}
+		}, ""},
+		{"unknown type", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: "discount", Percentage: 3}}
+		}, "items[0].adjustments[0].type must be reduction or surcharge"},
+		{"neither percentage nor amount", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction}}
+		}, "items[0].adjustments[0]: exactly one of percentage and amount_cents is required"},
+		{"percentage and amount", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 3, AmountCents: 100}}
+		}, "exactly one of percentage and amount_cents is required"},
+		{"three decimals", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 2.125}}
+		}, "items[0].adjustments[0].percentage must be between 0.01 and 100"},
+		{"over 100 percent", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentSurcharge, Percentage: 100.5}}
+		}, "percentage must be between 0.01 and 100"},
+		{"negative amount", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, AmountCents: -100}}
+		}, "items[0].adjustments[0].amount_cents must be > 0"},
+		{"tax rate on a line", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 3, TaxRate: &rate20}}
+		}, "items[0].adjustments[0]: tax_rate and tax_category are only allowed for document level adjustments"},
+		{"document without tax rate", func(inv *InvoiceJSON) {
+			inv.Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 3}}
+		}, "adjustments[0].tax_rate is required"},
+		{"document tax rate without items", func(inv *InvoiceJSON) {
+			inv.Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 3, TaxRate: &rate10}}
+		}, "adjustments[0]: tax_rate and tax_category must match at least one item"},
+		{"line reductions exceed the line", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{
+				{Type: AdjustmentReduction, Percentage: 60},
+				{Type: AdjustmentReduction, Percentage: 50},
+			}
+		}, "items[0]: reductions exceed the line amount"},
+		{"document reductions exceed the bucket", func(inv *InvoiceJSON) {
+			inv.Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, AmountCents: 450001, TaxRate: &rate20}}
+		}, "adjustments: reductions exceed the line amounts taxed at 20%"},
+		{"credit memo reduction", func(inv *InvoiceJSON) {
+			inv.DocumentType = DocTypeCreditMemo
+			inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02"}
+			inv.Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, AmountCents: 450000, TaxRate: &rate20}}
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			tt.edit(&inv)
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePaymentTerms(t *testing.T) {
+	// test_invoice_small.json is dated 2026-01-08.
+	tests := []struct {
+		name    string
+		terms   PaymentTermsJSON
+		wantErr string
+	}{
+		{"net days", PaymentTermsJSON{NetDays: 30}, ""},
+		{"due date", PaymentTermsJSON{DueDate: "2026-02-07"}, ""},
+		{"due on the invoice date", PaymentTermsJSON{DueDate: "2026-01-08"}, ""},
+		{"skonto tiers", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 3, Days: 7}, {Percentage: 2, Date: "2026-01-22"}}}, ""},
+		{"skonto on the due date", PaymentTermsJSON{DueDate: "2026-02-07", Skonto: []SkontoJSON{{Percentage: 2, Date: "2026-02-07"}}}, ""},
+		{"neither", PaymentTermsJSON{}, "payment_terms: exactly one of due_date and net_days is required"},
+		{"both", PaymentTermsJSON{DueDate: "2026-02-07", NetDays: 30}, "exactly one of due_date and net_days is required"},
+		{"negative net days", PaymentTermsJSON{NetDays: -5}, "payment_terms.net_days must be > 0"},
+		{"invalid due date", PaymentTermsJSON{DueDate: "07.02.2026"}, "payment_terms.due_date"},
+		{"due before the invoice", PaymentTermsJSON{DueDate: "2026-01-07"}, "payment_terms.due_date must not be before invoice_date"},
+		{"skonto percentage", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 0, Days: 7}}}, "payment_terms.skonto[0].percentage must be between 0.01 and 100"},
+		{"skonto three decimals", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2.125, Days: 7}}}, "payment_terms.skonto[0].percentage"},
+		{"skonto without deadline", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2}}}, "payment_terms.skonto[0]: exactly one of date and days (> 0) is required"},
+		{"skonto with date and days", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2, Days: 7, Date: "2026-01-15"}}}, "exactly one of date and days"},
+		{"skonto negative days", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2, Days: -7}}}, "exactly one of date and days (> 0)"},
+		{"skonto invalid date", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2, Date: "2026-13-01"}}}, "payment_terms.skonto[0].date"},
+		{"skonto before the invoice", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2, Date: "2026-01-07"}}}, "payment_terms.skonto[0] deadline must be between invoice_date and the due date"},
+		{"skonto after the due date", PaymentTermsJSON{NetDays: 14, Skonto: []SkontoJSON{{Percentage: 2, Days: 15}}}, "payment_terms.skonto[0] deadline must be between invoice_date and the due date"},
+		{"skonto tiers out of order", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 3, Days: 14}, {Percentage: 2, Days: 7}}}, "payment_terms.skonto[1] deadline must be after the previous tier"},
+		{"skonto tiers on the same day", PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 3, Days: 7}, {Percentage: 2, Date: "2026-01-15"}}}, "payment_terms.skonto[1] deadline must be after the previous tier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			inv.PaymentTerms = &tt.terms
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("skonto on a credit memo", func(t *testing.T) {
+		inv := readTestInvoice(t, "test_invoice_small.json")
+		inv.DocumentType = DocTypeCreditMemo
+		inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2026-01-02"}
+		inv.PaymentTerms = &PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 2, Days: 7}}}
+		if err := validateInvoice(inv); err == nil || !strings.Contains(err.Error(), "payment_terms.skonto is not allowed for credit_memo") {
+			t.Errorf("got %v", err)
+		}
+	})
+}
+
+func TestComputePaymentTerms(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json") // 2026-01-08, 5,400.00 EUR gross
+	inv.Items[0].UnitPriceCents = 123456                 // 1,234.56 net, 1,481.47 gross
+	inv.PaymentTerms = &PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{
+		{Percentage: 3, Days: 7},
+		{Percentage: 1.5, Date: "2026-01-29"},
+	}}
+	inv.Prepayments = []PrepaymentJSON{{AmountCents: 48147}} // Skonto applies to the payable 1,000.00
+	terms := computeTotals(inv).PaymentTerms
+	if terms == nil || terms.DueDate != "2026-02-07" || len(terms.Skonto) != 2 {
+		t.Fatalf("terms %+v", terms)
+	}
+	want := []skontoTotals{
+		{PaymentDate: "2026-01-15", Percentage: 3, BaseCts: 100000, AmountCts: 3000},
+		{PaymentDate: "2026-01-29", Percentage: 1.5, BaseCts: 100000, AmountCts: 1500},
+	}
+	for i, sk := range want {
+		if terms.Skonto[i] != sk {
+			t.Errorf("skonto %d = %+v, want %+v", i, terms.Skonto[i], sk)
+		}
+	}
+
+	inv.PaymentTerms = &PaymentTermsJSON{DueDate: "2026-03-31"}
+	if terms := computeTotals(inv).PaymentTerms; terms.DueDate != "2026-03-31" || terms.Skonto != nil {
+		t.Errorf("terms %+v", terms)
+	}
+	inv.PaymentTerms = nil
+	if terms := computeTotals(inv).PaymentTerms; terms != nil {
+		t.Errorf("terms %+v without payment_terms", terms)
+	}
+	if got := skontoAmount(33333, 2.5); got != 833 { // 8.33325
+		t.Errorf("skontoAmount = %d, want 833", got)
+	}
+}
+
+func TestValidateOrderingParty(t *testing.T) {
+	department := func() *OrderingPartyJSON {
+		return &OrderingPartyJSON{
+			Name:       "Stadt Graz Bauamt",
+			VATID:      "ATU13585627",
+			CustomerID: "K-1",
+			Address:    AddressJSON{Street: "Europaplatz 20", ZIP: "8020", City: "Graz"},
+		}
+	}
+	tests := []struct {
+		name    string
+		edit    func(op *OrderingPartyJSON)
+		wantErr string
+	}{
+		{"valid", func(op *OrderingPartyJSON) {}, ""},
+		{"contact", func(op *OrderingPartyJSON) {
+			op.ContactName = "Max Muster"
+			op.Email = "max@example.at"
+		}, ""},
+		{"foreign", func(op *OrderingPartyJSON) {
+			op.VATID = "DE136695976"
+			op.Address = AddressJSON{Street: "Marienplatz 8", ZIP: "80331", City: "München", Country: "DE"}
+		}, ""},
+		{"outside the EU", func(op *OrderingPartyJSON) {
+			op.VATID = "CHE-116.281.710 MWST"
+			op.Address = AddressJSON{Street: "Bahnhofstrasse 1", ZIP: "8001", City: "Zürich", Country: "CH"}
+		}, ""},
+		{"no name", func(op *OrderingPartyJSON) { op.Name = "" }, "ordering_party.name, ordering_party.vat_id and ordering_party.customer_id are required"},
+		{"no vat id", func(op *OrderingPartyJSON) { op.VATID = "" }, "ordering_party.name, ordering_party.vat_id and ordering_party.customer_id are required"},
+		{"no customer id", func(op *OrderingPartyJSON) { op.CustomerID = "" }, "ordering_party.name, ordering_party.vat_id and ordering_party.customer_id are required"},
+		{"incomplete address", func(op *OrderingPartyJSON) { op.Address.ZIP = "" }, "ordering_party.address: street, zip and city are required"},
+		{"address country", func(op *OrderingPartyJSON) { op.Address.Country = "Österreich" }, `ordering_party.address: country "Österreich" is not an ISO 3166-1 alpha-2 code`},
+		{"vat id format", func(op *OrderingPartyJSON) { op.VATID = "ATU1358562" }, "ordering_party.vat_id: vat_id must be in format"},
+		{"vat id check digit", func(op *OrderingPartyJSON) { op.VATID = "ATU13585628" }, "ordering_party.vat_id: vat_id ATU13585628 has an invalid check digit"},
+		{"local tax number in the EU", func(op *OrderingPartyJSON) { op.VATID = "123/4567" }, "ordering_party.vat_id: vat_id must start with the prefix of an EU member state"},
+		{"email without contact", func(op *OrderingPartyJSON) { op.Email = "max@example.at" }, "ordering_party.email requires ordering_party.contact_name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			inv.OrderingParty = department()
+			tt.edit(inv.OrderingParty)
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderingPartyOutput(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.OrderingParty = &OrderingPartyJSON{
+		Name:        "Stadt Graz Bauamt",
+		VATID:       "ATU13585627",
+		CustomerID:  "K-1",
+		ContactName: "Max Muster",
+		Email:       "max@example.at",
+		Address:     AddressJSON{Street: "Europaplatz 20", ZIP: "8020", City: "Graz"},
+	}
+	if err := validateInvoice(inv); err != nil {
+		t.Fatal(err)
+	}
+	// The ordering party follows the recipient and holds its VAT ID, address,
+	// contact (6.x; in the address in 5.0) and the biller's customer number.
+	wants := map[string]string{
+		"6.1": `</InvoiceRecipient>
+  <OrderingParty>
+    <VATIdentificationNumber>ATU13585627</VATIdentificationNumber>
+    <Address>
+      <Name>Stadt Graz Bauamt</Name>
+      <Street>Europaplatz 20</Street>
+      <Town>Graz</Town>
+      <ZIP>8020</ZIP>
+      <Country CountryCode="AT">Österreich</Country>
+    </Address>
+    <Contact>
+      <Name>Max Muster</Name>
+      <Email>max@example.at</Email>
+    </Contact>
+    <BillersOrderingPartyID>K-1</BillersOrderingPartyID>
+  </OrderingParty>
+  <Details>`,
+		"5.0": `</InvoiceRecipient>
+  <OrderingParty>
+    <VATIdentificationNumber>ATU13585627</VATIdentificationNumber>
+    <Address>
+      <Name>Stadt Graz Bauamt</Name>
+      <Street>Europaplatz 20</Street>
+      <Town>Graz</Town>
+      <ZIP>8020</ZIP>
+      <Country CountryCode="AT">Österreich</Country>
+      <Email>max@example.at</Email>
+      <Contact>Max Muster</Contact>
+    </Address>
+    <BillersOrderingPartyID>K-1</BillersOrderingPartyID>
+  </OrderingParty>
+  <Details>`,
+	}
+	for _, version := range supportedEbInterfaceVersions() {
+		doc, err := TransformToEbInterfaceVersion(inv, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateEbInterface(doc); err != nil {
+			t.Errorf("%s schema: %v", version, err)
+		}
+		if want, ok := wants[version]; ok && !strings.Contains(string(doc), want) {
+			t.Errorf("%s is missing %s", version, want)
+		}
+		parsed, err := ParseEbInterface(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parsed.Invoice.OrderingParty; got == nil || *got != *inv.OrderingParty {
+			t.Errorf("%s ordering party parsed as %+v", version, got)
+		}
+	}
+
+	// EN 16931 has no ordering party; the recipient stays the buyer.
+	ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cii, err := TransformToCII(inv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for format, doc := range map[string]string{"UBL": string(ubl), "CII": string(cii)} {
+		if strings.Contains(doc, "Stadt Graz Bauamt") || strings.Contains(doc, "Europaplatz") {
+			t.Errorf("%s states the ordering party", format)
+		}
+		if !strings.Contains(doc, inv.Recipient.Name) {
+			t.Errorf("%s is missing the recipient %s", format, inv.Recipient.Name)
+		}
+	}
+}
@@ -0,0 +1,772 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ParsedInvoice is an ebInterface document read back into the JSON model.
+// Invoice holds everything InvoiceJSON can express, the remaining fields carry
+// what the document states beyond it (currency, computed amounts, tax
+// categories), and Unmapped lists all content that fits neither.
+type ParsedInvoice struct {
+	Version          string          `json:"version"` // ebInterface version of the source document
+	Invoice          InvoiceJSON     `json:"invoice"`
+	Currency         string          `json:"currency"`
+	Language         string          `json:"language,omitempty"`
+	GeneratingSystem string          `json:"generating_system,omitempty"`
+	Lines            []ParsedLine    `json:"lines"` // Parallel to Invoice.Items
+	TaxSummary       []ParsedTaxItem `json:"tax_summary"`
+	TotalGrossCents  int64           `json:"total_gross_cents"`
+	PayableCents     int64           `json:"payable_cents"`
+	Unmapped         []UnmappedField `json:"unmapped"`
+}
+
+// ParsedLine carries the amounts of a line item as stated in the document.
+// Amounts are signed like the document (negative for credit memos).
+type ParsedLine struct {
+	NetCents    int64  `json:"net_cents"`
+	TaxCategory string `json:"tax_category,omitempty"`
+}
+
+// ParsedTaxItem is one entry of the document's tax summary.
+type ParsedTaxItem struct {
+	TaxRate         float64 `json:"tax_rate"`
+	TaxCategory     string  `json:"tax_category,omitempty"`
+	TaxableCents    int64   `json:"taxable_cents"`
+	TaxCents        int64   `json:"tax_cents"`
+	ExemptionReason string  `json:"exemption_reason,omitempty"`
+}
+
+// UnmappedField is document content that the parsed result does not represent.
+type UnmappedField struct {
+	XPath  string `json:"xpath"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+const unmappedReasonNoField = "not represented in InvoiceJSON"
+
+// ParseEbInterface reads an ebInterface 5.0 or 6.x invoice into the JSON
+// model. The document must be schema-valid; otherwise a
+// *SchemaValidationError lists the violations.
+//
+// Documents produced by TransformToEbInterfaceVersion parse back into an
+// InvoiceJSON that renders the same document. What the document only states
+// in resolved form comes back normalised rather than as given: unit aliases
+// as their Rec 20 code (h as HUR), payment_terms.net_days and skonto days as
+// dates, and vat_rounding only as bucket where line rounding would give a
+// different tax summary. Nothing of the document is lost, so these are not
+// listed in Unmapped.
+func ParseEbInterface(doc []byte) (*ParsedInvoice, error) {
+	root, err := parseXMLTree(doc)
+	if err != nil {
+		return nil, fmt.Errorf("parse XML: %w", err)
+	}
+	violations, err := schemaViolations(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		return nil, &SchemaValidationError{Namespace: root.Name.Space, Violations: violations}
+	}
+	return parseEbInterfaceTree(root)
+}
+
+// ebParser records which elements and attributes were consumed while mapping,
+// so that everything else can be reported as unmapped.
+type ebParser struct {
+	used     map[*xmlNode]bool
+	usedAttr map[*xmlNode]map[string]bool
+	unmapped []UnmappedField
+}
+
+// text returns the text of the element at the given path below c and marks
+// it as mapped. String content is kept verbatim; decimals are trimmed by the callers.
+func (p *ebParser) text(c xmlCursor, locals ...string) string {
+	el, ok := c.path(locals...)
+	if !ok {
+		return ""
+	}
+	p.used[el.node] = true
+	return el.node.Text
+}
+
+// expect marks the element at the given path as mapped if its text equals
+// want, i.e. if it merely restates data the generator derives from InvoiceJSON.
+// Differing values stay unmapped.
+func (p *ebParser) expect(c xmlCursor, want string, locals ...string) {
+	if el, ok := c.path(locals...); ok && el.node.Text == want {
+		p.used[el.node] = true
+	}
+}
+
+// attr returns attribute name of c and marks it as mapped.
+func (p *ebParser) attr(c xmlCursor, name string) string {
+	if c.node == nil {
+		return ""
+	}
+	v, ok := c.node.attr(name)
+	if ok {
+		if p.usedAttr[c.node] == nil {
+			p.usedAttr[c.node] = map[string]bool{}
+		}
+		p.usedAttr[c.node][name] = true
+	}
+	return v
+}
+
+// expectAttr marks attribute name of c as mapped if its value equals want.
+func (p *ebParser) expectAttr(c xmlCursor, name, want string) {
+	if v, ok := c.node.attr(name); ok && v == want {
+		p.attr(c, name)
+	}
+}
+
+// lossy reports content that was mapped only approximately.
+func (p *ebParser) lossy(xpath, value, reason string) {
+	p.unmapped = append(p.unmapped, UnmappedField{XPath: xpath, Value: value, Reason: reason})
+}
+
+// amount reads the decimal amount at the given path below c as cents.
+func (p *ebParser) amount(c xmlCursor, locals ...string) int64 {
+	r, ok := parseDecimalRat(strings.TrimSpace(p.text(c, locals...)))
+	if !ok {
+		return 0
+	}
+	return ratToCents(r)
+}
+
+// rate reads a tax rate element (TaxPercent or VATRate), its category code and
+// the exemption reason. 6.x states the reason as the TaxItem's Comment, 5.0
+// replaces the VATRate by a TaxExemption coded with the category.
+func (p *ebParser) rate(c xmlCursor, rateName string) (float64, string, string) {
+	el, ok := c.child(rateName)
+	if !ok {
+		if ex, ok := c.child("TaxExemption"); ok && rateName == vatItemFields.rate {
+			return 0, p.attr(ex, "TaxExemptionCode"), p.text(ex)
+		}
+		return 0, "", ""
+	}
+	rate, _ := strconv.ParseFloat(strings.TrimSpace(p.text(el)), 64)
+	var reason string
+	if rateName == taxItemFields.rate {
+		reason = p.text(c, "Comment")
+	}
+	return rate, p.attr(el, "TaxCategoryCode"), reason
+}
+
+// explicitTaxCategory returns category unless it follows from the rate.
+func explicitTaxCategory(category string, rate float64) string {
+	if category == taxCategoryFromRate(rate) {
+		return ""
+	}
+	return category
+}
+
+// parseEbInterfaceTree maps an already parsed and validated document.
+func parseEbInterfaceTree(root *xmlNode) (*ParsedInvoice, error) {
+	v, ok := lookupEbInterfaceNamespace(root.Name.Space)
+	if !ok || root.Name.Local != "Invoice" {
+		return nil, fmt.Errorf("unsupported document {%s}%s", root.Name.Space, root.Name.Local)
+	}
+	p := &ebParser{used: map[*xmlNode]bool{}, usedAttr: map[*xmlNode]map[string]bool{}}
+	c := xmlCursor{node: root, xpath: "/" + root.Name.Local}
+	out := &ParsedInvoice{
+		Version:          v.Version,
+		Currency:         p.attr(c, "InvoiceCurrency"),
+		Language:         p.attr(c, "Language"),
+		GeneratingSystem: p.attr(c, "GeneratingSystem"),
+	}
+	inv := &out.Invoice
+	inv.InvoiceNumber = p.text(c, "InvoiceNumber")
+	inv.InvoiceDate = p.text(c, "InvoiceDate")
+
+	sign := int64(1)
+	switch docType := p.attr(c, "DocumentType"); docType {
+	case "Invoice":
+	case "InvoiceForAdvancePayment":
+		inv.DocumentType = DocTypeAdvancePayment
+	case "FinalSettlement":
+		inv.DocumentType = DocTypeFinalSettlement
+	case "CreditMemo":
+		sign = -1
+		inv.DocumentType = DocTypeCreditMemo
+		ref, ok := c.child("CancelledOriginalDocument")
+		if ok {
+			inv.DocumentType = DocTypeCancellation
+		} else {
+			ref, ok = c.child("RelatedDocument")
+		}
+		if ok {
+			inv.OriginalInvoice = &DocumentReferenceJSON{
+				InvoiceNumber: p.text(ref, "InvoiceNumber"),
+				InvoiceDate:   p.text(ref, "InvoiceDate"),
+				Comment:       p.text(ref, "Comment"),
+			}
+			p.expect(ref, "Invoice", "DocumentType")
+		}
+	default:
+		p.lossy(c.xpath+"/@DocumentType", docType, "document type has no InvoiceJSON equivalent, read as invoice")
+	}
+
+	p.parseBiller(c, inv)
+	p.parseRecipient(c, inv)
+	p.parseOrderingParty(c, inv)
+	inv.Delivery = p.delivery(c, *inv)
+
+	p.parseLines(c, sign, out)
+	inv.Profile = detectProfile(c, *inv)
+	p.parseAdjustments(c, sign, out)
+	p.parseTax(c, out)
+	inv.VATRounding = detectVATRounding(*inv, out.TaxSummary)
+	out.TotalGrossCents = p.amount(c, "TotalGrossAmount")
+	out.PayableCents = p.amount(c, "PayableAmount")
+	if sign > 0 {
+		p.parsePrepayments(c, out)
+	}
+
+	if acct, ok := c.path("PaymentMethod", "UniversalBankTransaction", "BeneficiaryAccount"); ok {
+		inv.Payment.BIC = p.text(acct, "BIC")
+		inv.Payment.IBAN = p.text(acct, "IBAN")
+		p.expect(acct, inv.Biller.Name, "BankAccountOwner")
+	}
+	p.parsePaymentConditions(c, out)
+
+	p.collectUnmapped(c)
+	if out.Unmapped = p.unmapped; out.Unmapped == nil {
+		out.Unmapped = []UnmappedField{}
+	}
+	return out, nil
+}
+
+func (p *ebParser) parseBiller(c xmlCursor, inv *InvoiceJSON) {
+	b, ok := c.child("Biller")
+	if !ok {
+		return
+	}
+	inv.Biller.VATID = p.text(b, "VATIdentificationNumber")
+	inv.Biller.FurtherIdentifications = p.furtherIdentifications(b)
+	inv.Biller.BillerID = p.text(b, "InvoiceRecipientsBillerID")
+	inv.Biller.Name, inv.Biller.Address = p.address(b)
+	if ct, name, ok := p.contact(b); ok {
+		inv.Biller.ContactName = contactNameFromDefault(name, "Billing Department")
+		inv.Biller.Phone = p.text(ct, "Phone")
+		inv.Biller.Email = p.text(ct, "Email")
+	}
+}
+
+func (p *ebParser) parseRecipient(c xmlCursor, inv *InvoiceJSON) {
+	r, ok := c.child("InvoiceRecipient")
+	if !ok {
+		return
+	}
+	if vatID := p.text(r, "VATIdentificationNumber"); vatID != ebNoVATID {
+		inv.Recipient.VATID = vatID
+	}
+	inv.Recipient.FurtherIdentifications = p.furtherIdentifications(r)
+	inv.Recipient.OrderID = p.text(r, "OrderReference", "OrderID")
+	inv.Recipient.Name, inv.Recipient.Address = p.address(r)
+	if ct, name, ok := p.contact(r); ok {
+		inv.Recipient.ContactName = contactNameFromDefault(name, "Accounting")
+		inv.Recipient.Email = p.text(ct, "Email")
+	}
+}
+
+func (p *ebParser) parseOrderingParty(c xmlCursor, inv *InvoiceJSON) {
+	o, ok := c.child("OrderingParty")
+	if !ok {
+		return
+	}
+	op := &OrderingPartyJSON{
+		VATID:      p.text(o, "VATIdentificationNumber"),
+		CustomerID: p.text(o, "BillersOrderingPartyID"),
+	}
+	op.Name, op.Address = p.address(o)
+	if ct, name, ok := p.contact(o); ok {
+		op.ContactName = name
+		op.Email = p.text(ct, "Email")
+	}
+	inv.OrderingParty = op
+}
+
+// furtherIdentifications maps the FurtherIdentification children of a party.
+// Unsupported types, invalid values and repeated types have no JSON
+// equivalent and remain unmapped.
+func (p *ebParser) furtherIdentifications(party xmlCursor) []FurtherIdentificationJSON {
+	var ids []FurtherIdentificationJSON
+	seen := map[string]bool{}
+	for _, f := range party.all("FurtherIdentification") {
+		typ, _ := f.node.attr("IdentificationType")
+		id := FurtherIdentificationJSON{Type: typ, Value: f.text()}
+		if !isSupportedIdentificationType(typ) || validateIdentificationValue(id) != nil || (seen[typ] && typ != IdentificationSupplierNumber) {
+			continue
+		}
+		p.attr(f, "IdentificationType")
+		p.text(f)
+		seen[typ] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// detectProfile infers the profile from the fields the profiles make
+// mandatory: B2B invoices may omit the order reference and the recipient's
+// VAT ID, and state invoices the supplier number and the links of every line
+// to an order position. Documents stating all of them read as the default
+// profile.
+func detectProfile(c xmlCursor, inv InvoiceJSON) string {
+	switch {
+	case inv.Recipient.OrderID == "" || inv.Recipient.VATID == "":
+		return ProfileB2B
+	case inv.Biller.BillerID == "":
+		return ProfileB2GState
+	}
+	details, _ := c.child("Details")
+	for _, list := range details.all("ItemList") {
+		for _, line := range list.all("ListLineItem") {
+			if _, ok := line.child("InvoiceRecipientsOrderReference"); !ok {
+				return ProfileB2GState
+			}
+		}
+	}
+	return ""
+}
+
+// delivery maps the Delivery child of the document or a line. An address
+// name equal to the recipient's reads back as empty, the default.
+func (p *ebParser) delivery(parent xmlCursor, inv InvoiceJSON) *DeliveryJSON {
+	d, ok := parent.child("Delivery")
+	if !ok {
+		return nil
+	}
+	delivery := &DeliveryJSON{
+		Date:     p.text(d, "Date"),
+		FromDate: p.text(d, "Period", "FromDate"),
+		ToDate:   p.text(d, "Period", "ToDate"),
+	}
+	if _, ok := d.child("Address"); ok {
+		name, addr := p.address(d)
+		if name != inv.Recipient.Name {
+			delivery.Name = name
+		}
+		delivery.Address = &addr
+	}
+	if ct, name, ok := p.contact(d); ok {
+		delivery.ContactName = name
+		delivery.Email = p.text(ct, "Email")
+	}
+	return delivery
+}
+
+// contact finds the contact person of a party or delivery and returns its
+// name with the element holding its Email and Phone: the Contact element in
+// 6.x, the Address in 5.0, where the person is the Address/Contact text.
+func (p *ebParser) contact(party xmlCursor) (xmlCursor, string, bool) {
+	if ct, ok := party.child("Contact"); ok {
+		return ct, p.text(ct, "Name"), true
+	}
+	a, ok := party.child("Address")
+	if !ok {
+		return xmlCursor{}, "", false
+	}
+	if _, ok := a.child("Contact"); !ok {
+		return xmlCursor{}, "", false
+	}
+	return a, p.text(a, "Contact"), true
+}
+
+// contactNameFromDefault reverses getContactName: the placeholder the
+// generator inserts for a missing contact reads back as empty.
+func contactNameFromDefault(name, defaultName string) string {
+	if name == defaultName {
+		return ""
+	}
+	return name
+}
+
+// address reads the Address child of a party. Country codes outside the
+// bundled ISO 3166-1 list are not expressible in InvoiceJSON and remain unmapped.
+func (p *ebParser) address(party xmlCursor) (string, AddressJSON) {
+	a, ok := party.child("Address")
+	if !ok {
+		return "", AddressJSON{}
+	}
+	name := p.text(a, "Name")
+	addr := AddressJSON{
+		Street: p.text(a, "Street"),
+		ZIP:    p.text(a, "ZIP"),
+		City:   p.text(a, "Town"),
+	}
+	if country, ok := a.child("Country"); ok {
+		if code, _ := country.node.attr("CountryCode"); code != DefaultCountryCode && countryNamesDE[code] != "" {
+			addr.Country = code
+		}
+	}
+	p.expectCountry(a, addr)
+	return name, addr
+}
+
+// expectCountry marks the Country of an address as mapped where it equals
+// the code and German name composeEbAddress derives from addr.
+func (p *ebParser) expectCountry(a xmlCursor, addr AddressJSON) {
+	country, ok := a.child("Country")
+	if !ok {
+		return
+	}
+	want := composeEbAddress("", addr).Country
+	if code, _ := country.node.attr("CountryCode"); code == want.CountryCode {
+		p.attr(country, "CountryCode")
+		p.expect(country, want.Name)
+	}
+}
+
+// articleNumbers maps the article numbers of a line. PZNs, repeated types and
+// invalid GTINs have no JSON equivalent and remain unmapped.
+func (p *ebParser) articleNumbers(line xmlCursor, item *LineItemJSON) {
+	for _, a := range line.all("ArticleNumber") {
+		typ, _ := a.node.attr("ArticleNumberType")
+		value := a.text()
+		target := map[string]*string{
+			ArticleNumberBiller:    &item.BillerArticleNumber,
+			ArticleNumberRecipient: &item.RecipientArticleNumber,
+			ArticleNumberGTIN:      &item.GTIN,
+		}[typ]
+		if target == nil || *target != "" || (typ == ArticleNumberGTIN && !(gtinRegex.MatchString(value) && checkGTIN(value))) {
+			continue
+		}
+		p.attr(a, "ArticleNumberType")
+		*target = p.text(a)
+	}
+}
+
+// parseLines maps the line items of all item lists. Credit memos carry
+// negative quantities, which sign turns back into positive JSON quantities.
+func (p *ebParser) parseLines(c xmlCursor, sign int64, out *ParsedInvoice) {
+	details, _ := c.child("Details")
+	for _, list := range details.all("ItemList") {
+		for _, line := range list.all("ListLineItem") {
+			var item LineItemJSON
+			var parsed ParsedLine
+			item.Description = p.text(line, "Description")
+			p.articleNumbers(line, &item)
+
+			if qty, ok := line.child("Quantity"); ok {
+				if unit := p.attr(qty, "Unit"); rec20Units[unit] == "" {
+					p.lossy(qty.xpath+"/@Unit", unit, "unit is not in the bundled UN/ECE Recommendation 20 list")
+				} else if unit != DefaultUnitCode {
+					item.Unit = unit
+				}
+				q, err := decimal.NewFromString(strings.TrimSpace(p.text(qty)))
+				if q = q.Mul(decimal.NewFromInt(sign)); err == nil && q.Sign() > 0 && hasMaxDecimals(q, maxQuantityDecimals) {
+					item.Quantity = DecimalJSON{q}
+				} else {
+					p.lossy(qty.xpath, qty.text(), "quantity is not a positive number with at most 4 decimals for this document type")
+				}
+			}
+			if price, ok := line.child("UnitPrice"); ok {
+				if d, err := decimal.NewFromString(strings.TrimSpace(p.text(price))); err == nil {
+					switch {
+					case hasMaxDecimals(d, 2):
+						item.UnitPriceCents = d.Shift(2).IntPart()
+					case hasMaxDecimals(d, maxUnitPriceDecimals):
+						item.UnitPrice = &DecimalJSON{d}
+					default:
+						rounded := d.Round(maxUnitPriceDecimals)
+						item.UnitPrice = &DecimalJSON{rounded}
+						p.lossy(price.xpath, price.text(), "unit price rounded to 4 decimals")
+					}
+				}
+			}
+
+			if details, ok := line.child("ReductionAndSurchargeListLineItemDetails"); ok {
+				baseCts := decimalToCents(item.Quantity.Mul(decimal.NewFromInt(sign)).Mul(item.unitPrice()))
+				for _, entry := range details.children() {
+					item.Adjustments = append(item.Adjustments, p.adjustment(entry, baseCts, sign))
+				}
+			}
+
+			// 6.x lines carry a TaxItem, 5.0 lines a VATRate applying to LineItemAmount.
+			var reason string
+			if taxItem, ok := line.child("TaxItem"); ok {
+				item.TaxRate, parsed.TaxCategory, reason = p.rate(taxItem, "TaxPercent")
+				if amount, ok := line.child("LineItemAmount"); ok {
+					p.expect(taxItem, amount.node.Text, "TaxableAmount")
+				}
+			} else {
+				item.TaxRate, parsed.TaxCategory, reason = p.rate(line, "VATRate")
+			}
+			item.TaxCategory = explicitTaxCategory(parsed.TaxCategory, item.TaxRate)
+			if reason != defaultTaxExemptionReasons[parsed.TaxCategory] {
+				item.TaxExemptionReason = reason
+			}
+			parsed.NetCents = p.amount(line, "LineItemAmount")
+
+			item.Delivery = p.delivery(line, out.Invoice)
+
+			// By default the generator links lines to the recipient's order by line number.
+			if ref, ok := line.child("InvoiceRecipientsOrderReference"); ok {
+				orderID, position := p.text(ref, "OrderID"), p.text(ref, "OrderPositionNumber")
+				if orderID != out.Invoice.Recipient.OrderID {
+					item.OrderID, item.OrderPosition = orderID, position
+				} else if position != strconv.Itoa(len(out.Invoice.Items)+1) {
+					item.OrderPosition = position
+				}
+			}
+
+			out.Invoice.Items = append(out.Invoice.Items, item)
+			out.Lines = append(out.Lines, parsed)
+		}
+	}
+}
+
+// parseAdjustments reads the document level reductions and surcharges. Their
+// tax rate is given by a TaxItem in 6.x and a VATRate in 5.0.
+func (p *ebParser) parseAdjustments(c xmlCursor, sign int64, out *ParsedInvoice) {
+	details, ok := c.child("ReductionAndSurchargeDetails")
+	if !ok {
+		return
+	}
+	// Percentages refer to the line amounts of the adjusted tax rate.
+	lineBases := map[taxBucketKey]int64{}
+	for i, li := range out.Invoice.Items {
+		lineBases[taxBucketKey{rate: li.TaxRate, category: out.Lines[i].TaxCategory}] += out.Lines[i].NetCents
+	}
+	for _, entry := range details.children() {
+		var key taxBucketKey
+		// The exemption reason restates the one of the adjusted items.
+		if taxItem, ok := entry.child("TaxItem"); ok {
+			key.rate, key.category, _ = p.rate(taxItem, "TaxPercent")
+			if amount, ok := entry.child("Amount"); ok {
+				p.expect(taxItem, amount.node.Text, "TaxableAmount")
+			}
+		} else {
+			key.rate, key.category, _ = p.rate(entry, "VATRate")
+		}
+		if out.Invoice.DocumentType == DocTypeFinalSettlement && p.advance(c, entry, key, lineBases[key], &out.Invoice) {
+			continue
+		}
+		a := p.adjustment(entry, lineBases[key], sign)
+		a.TaxRate = &key.rate
+		a.TaxCategory = explicitTaxCategory(key.category, key.rate)
+		out.Invoice.Adjustments = append(out.Invoice.Adjustments, a)
+	}
+}
+
+// advanceCommentPattern matches the reduction comment written by advanceComment.
+var advanceCommentPattern = regexp.MustCompile(`^Anzahlungsrechnung (.+) vom (\d{4}-\d{2}-\d{2}), USt (\d+\.\d{2}) ` + invoiceCurrency + `$`)
+
+// advance reads the deduction of an advance invoice by a final settlement,
+// together with the RelatedDocument referencing it. It reports false for
+// entries that are ordinary reductions.
+func (p *ebParser) advance(c, entry xmlCursor, key taxBucketKey, baseCts int64, inv *InvoiceJSON) bool {
+	comment, _ := entry.child("Comment")
+	m := advanceCommentPattern.FindStringSubmatch(comment.text())
+	if entry.node.Name.Local != "Reduction" || m == nil {
+		return false
+	}
+	if _, ok := entry.child("Percentage"); ok {
+		return false
+	}
+	r, _ := parseDecimalRat(m[3])
+	tax := AdvanceTaxJSON{
+		TaxRate:     key.rate,
+		TaxCategory: explicitTaxCategory(key.category, key.rate),
+		NetCents:    p.amount(entry, "Amount"),
+		TaxCents:    ratToCents(r),
+	}
+	p.text(entry, "Comment")
+	p.expect(entry, formatCentsAsDecimal(baseCts), "BaseAmount")
+
+	if n := len(inv.AdvanceInvoices); n > 0 && inv.AdvanceInvoices[n-1].InvoiceNumber == m[1] && inv.AdvanceInvoices[n-1].InvoiceDate == m[2] {
+		inv.AdvanceInvoices[n-1].Taxes = append(inv.AdvanceInvoices[n-1].Taxes, tax)
+		return true
+	}
+	inv.AdvanceInvoices = append(inv.AdvanceInvoices, AdvanceInvoiceJSON{InvoiceNumber: m[1], InvoiceDate: m[2], Taxes: []AdvanceTaxJSON{tax}})
+	for _, ref := range c.all("RelatedDocument") {
+		if num, ok := ref.child("InvoiceNumber"); ok && num.node.Text == m[1] && !p.used[num.node] {
+			p.used[num.node] = true
+			p.expect(ref, m[2], "InvoiceDate")
+			p.expect(ref, "InvoiceForAdvancePayment", "DocumentType")
+			break
+		}
+	}
+	return true
+}
+
+// adjustment reads a reduction or surcharge (document or line level) applied
+// to baseCts. BaseAmount and, for percentages, Amount restate computed values.
+func (p *ebParser) adjustment(entry xmlCursor, baseCts, sign int64) AdjustmentJSON {
+	a := AdjustmentJSON{Type: AdjustmentReduction, Reason: p.text(entry, "Comment")}
+	if strings.HasPrefix(entry.node.Name.Local, "Surcharge") {
+		a.Type = AdjustmentSurcharge
+	}
+	p.expect(entry, formatCentsAsDecimal(baseCts), "BaseAmount")
+
+	if pct, ok := entry.child("Percentage"); ok {
+		a.Percentage, _ = strconv.ParseFloat(strings.TrimSpace(p.text(pct)), 64)
+		p.expect(entry, formatCentsAsDecimal(computeAdjustment(a, baseCts, sign).AmountCts), "Amount")
+	} else if amount, ok := entry.child("Amount"); ok {
+		cts := p.amount(amount)
+		if sign*cts > 0 {
+			a.AmountCents = sign * cts
+		} else {
+			p.lossy(amount.xpath, amount.text(), "amount is not positive for this document type")
+		}
+	}
+	return a
+}
+
+// prepaymentCommentPattern matches the RelatedDocument comment written by prepaymentComment.
+var prepaymentCommentPattern = regexp.MustCompile(`^Anzahlung (\d+\.\d{2}) ` + invoiceCurrency + `$`)
+
+// parsePrepayments reads the advance invoices referenced with their amount
+// (see buildEbAdvanceReferences). The part of PrepaidAmount not covered by
+// them becomes a prepayment without reference.
+func (p *ebParser) parsePrepayments(c xmlCursor, out *ParsedInvoice) {
+	var referencedCts int64
+	for _, ref := range c.all("RelatedDocument") {
+		dt, _ := ref.child("DocumentType")
+		comment, _ := ref.child("Comment")
+		m := prepaymentCommentPattern.FindStringSubmatch(comment.text())
+		if dt.text() != "InvoiceForAdvancePayment" || m == nil {
+			continue
+		}
+		r, _ := parseDecimalRat(m[1])
+		pp := PrepaymentJSON{
+			AmountCents:   ratToCents(r),
+			InvoiceNumber: p.text(ref, "InvoiceNumber"),
+			InvoiceDate:   p.text(ref, "InvoiceDate"),
+		}
+		p.text(ref, "DocumentType")
+		p.text(ref, "Comment")
+		referencedCts += pp.AmountCents
+		out.Invoice.Prepayments = append(out.Invoice.Prepayments, pp)
+	}
+	prepaid, ok := c.child("PrepaidAmount")
+	if !ok {
+		return
+	}
+	r, ok := parseDecimalRat(strings.TrimSpace(prepaid.text()))
+	if !ok {
+		return
+	}
+	switch rest := ratToCents(r) - referencedCts; {
+	case rest == 0:
+		p.used[prepaid.node] = true
+	case rest > 0:
+		p.used[prepaid.node] = true
+		out.Invoice.Prepayments = append(out.Invoice.Prepayments, PrepaymentJSON{AmountCents: rest})
+	}
+}
+
+// parsePaymentConditions reads the due date and Skonto tiers. Deadlines are
+// returned as dates; BaseAmount and Amount restate the computed discount.
+// Without a DueDate the conditions have no InvoiceJSON equivalent.
+func (p *ebParser) parsePaymentConditions(c xmlCursor, out *ParsedInvoice) {
+	pc, ok := c.child("PaymentConditions")
+	if !ok {
+		return
+	}
+	if _, ok := pc.child("DueDate"); !ok {
+		return
+	}
+	terms := &PaymentTermsJSON{DueDate: p.text(pc, "DueDate")}
+	for _, d := range pc.all("Discount") {
+		pct, ok := d.child("Percentage")
+		if !ok {
+			continue
+		}
+		sk := SkontoJSON{Date: p.text(d, "PaymentDate")}
+		sk.Percentage, _ = strconv.ParseFloat(strings.TrimSpace(p.text(pct)), 64)
+		p.expect(d, formatCentsAsDecimal(out.PayableCents), "BaseAmount")
+		p.expect(d, formatCentsAsDecimal(skontoAmount(out.PayableCents, sk.Percentage)), "Amount")
+		terms.Skonto = append(terms.Skonto, sk)
+	}
+	out.Invoice.PaymentTerms = terms
+}
+
+// parseTax reads the tax summary of 6.x (Tax/TaxItem) or 5.0 (Tax/VAT/VATItem).
+func (p *ebParser) parseTax(c xmlCursor, out *ParsedInvoice) {
+	tax, _ := c.child("Tax")
+	type summaryItem struct {
+		cursor xmlCursor
+		fields taxFields
+	}
+	var items []summaryItem
+	for _, item := range tax.all("TaxItem") {
+		items = append(items, summaryItem{item, taxItemFields})
+	}
+	if vat, ok := tax.child("VAT"); ok {
+		for _, item := range vat.all("VATItem") {
+			items = append(items, summaryItem{item, vatItemFields})
+		}
+	}
+	for _, si := range items {
+		var ti ParsedTaxItem
+		ti.TaxRate, ti.TaxCategory, ti.ExemptionReason = p.rate(si.cursor, si.fields.rate)
+		ti.TaxableCents = p.amount(si.cursor, si.fields.taxable)
+		ti.TaxCents = p.amount(si.cursor, si.fields.amount)
+		out.TaxSummary = append(out.TaxSummary, ti)
+	}
+}
+
+// detectVATRounding reports bucket rounding when the tax summary was rounded
+// per bucket and differs from line rounding; otherwise the default applies.
+func detectVATRounding(inv InvoiceJSON, summary []ParsedTaxItem) string {
+	matches := func(strategy string) bool {
+		inv.VATRounding = strategy
+		t := computeTotals(inv)
+		if len(t.Buckets) != len(summary) {
+			return false
+		}
+		for i, b := range t.Buckets {
+			if summary[i].TaxRate != b.Rate || summary[i].TaxCents != b.TaxCts {
+				return false
+			}
+		}
+		return true
+	}
+	if !matches(VATRoundingLine) && matches(VATRoundingBucket) {
+		return VATRoundingBucket
+	}
+	return ""
+}
+
+// collectUnmapped reports every attribute and leaf element below c that was
+// not consumed. Namespace declarations and xsi attributes are not content.
+func (p *ebParser) collectUnmapped(c xmlCursor) {
+	for _, a := range c.node.Attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") || a.Name.Space == xsiNamespace {
+			continue
+		}
+		if a.Name.Space == "" && p.usedAttr[c.node][a.Name.Local] {
+			continue
+		}
+		p.unmapped = append(p.unmapped, UnmappedField{XPath: c.xpath + "/@" + attrQName(a.Name), Value: a.Value, Reason: unmappedReasonNoField})
+	}
+	if p.used[c.node] {
+		return
+	}
+	if len(c.node.Children) == 0 {
+		p.unmapped = append(p.unmapped, UnmappedField{XPath: c.xpath, Value: c.text(), Reason: unmappedReasonNoField})
+		return
+	}
+	paths := childXPaths(c.node, c.xpath)
+	for i, n := range c.node.Children {
+		p.collectUnmapped(xmlCursor{node: n, xpath: paths[i]})
+	}
+}
+
+// attrQName renders an attribute name for an XPath, keeping the xml: prefix.
+func attrQName(n xml.Name) string {
+	if n.Space == "http://www.w3.org/XML/1998/namespace" {
+		return "xml:" + n.Local
+	}
+	return n.Local
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// assertRoundTrip renders inv in the given version, parses it back and checks
+// that the parsed invoice renders the identical document without unmapped
+// content. It returns the parsed result for further checks.
+func assertRoundTrip(t *testing.T, inv InvoiceJSON, version string) *ParsedInvoice {
+	t.Helper()
+	doc, err := TransformToEbInterfaceVersion(inv, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseEbInterface(doc)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for _, u := range parsed.Unmapped {
+		t.Errorf("unmapped: %+v", u)
+	}
+	if parsed.Version != version {
+		t.Errorf("version = %q, want %q", parsed.Version, version)
+	}
+	again, err := TransformToEbInterfaceVersion(parsed.Invoice, version)
+	if err != nil {
+		t.Fatalf("render parsed invoice: %v", err)
+	}
+	if !bytes.Equal(doc, again) {
+		t.Errorf("parsed invoice renders a different document\n%s\n---\n%s", doc, again)
+	}
+	return parsed
+}
+
+func TestParseEbInterfaceRoundTrip(t *testing.T) {
+	for _, name := range []string{"golden_test.json", "test_invoice_2.json", "test_invoice_3.json", "test_invoice_small.json"} {
+		for _, version := range supportedEbInterfaceVersions() {
+			t.Run(name+"/"+version, func(t *testing.T) {
+				inv := readTestInvoice(t, name)
+				parsed := assertRoundTrip(t, inv, version)
+				want, _ := json.Marshal(inv)
+				got, _ := json.Marshal(parsed.Invoice)
+				if !bytes.Equal(want, got) {
+					t.Errorf("round trip changed the invoice\n%s\n%s", want, got)
+				}
+			})
+		}
+	}
+}
+
+// TestParseEbInterfaceNormalisations covers the input the parser returns in
+// resolved form, as documented on ParseEbInterface.
+func TestParseEbInterfaceNormalisations(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(inv *InvoiceJSON)
+		check func(t *testing.T, got InvoiceJSON)
+	}{
+		{"unit alias", func(inv *InvoiceJSON) { inv.Items[0].Unit = "h" }, func(t *testing.T, got InvoiceJSON) {
+			if got.Items[0].Unit != "HUR" {
+				t.Errorf("unit = %q, want HUR", got.Items[0].Unit)
+			}
+		}},
+		{"default unit", func(inv *InvoiceJSON) { inv.Items[0].Unit = "C62" }, func(t *testing.T, got InvoiceJSON) {
+			if got.Items[0].Unit != "" {
+				t.Errorf("unit = %q, want empty", got.Items[0].Unit)
+			}
+		}},
+		{"net days", func(inv *InvoiceJSON) {
+			inv.PaymentTerms = &PaymentTermsJSON{NetDays: 30, Skonto: []SkontoJSON{{Percentage: 3, Days: 10}}}
+		}, func(t *testing.T, got InvoiceJSON) {
+			want := PaymentTermsJSON{DueDate: "2026-02-06", Skonto: []SkontoJSON{{Percentage: 3, Date: "2026-01-17"}}}
+			if got.PaymentTerms == nil || got.PaymentTerms.DueDate != want.DueDate || got.PaymentTerms.NetDays != 0 ||
+				len(got.PaymentTerms.Skonto) != 1 || got.PaymentTerms.Skonto[0] != want.Skonto[0] {
+				t.Errorf("payment terms = %+v, want %+v", got.PaymentTerms, want)
+			}
+		}},
+		{"line rounding", func(inv *InvoiceJSON) { inv.VATRounding = VATRoundingLine }, func(t *testing.T, got InvoiceJSON) {
+			if got.VATRounding != "" {
+				t.Errorf("vat_rounding = %q, want the default", got.VATRounding)
+			}
+		}},
+		{"indistinguishable bucket rounding", func(inv *InvoiceJSON) { inv.VATRounding = VATRoundingBucket }, func(t *testing.T, got InvoiceJSON) {
+			if got.VATRounding != "" {
+				t.Errorf("vat_rounding = %q, want the default", got.VATRounding)
+			}
+		}},
+		{"bucket rounding", func(inv *InvoiceJSON) {
+			inv.VATRounding = VATRoundingBucket
+			inv.Items = []LineItemJSON{
+				{Description: "A", Quantity: DecimalJSON{decimal.NewFromInt(1)}, UnitPriceCents: 3, TaxRate: 20},
+				{Description: "B", Quantity: DecimalJSON{decimal.NewFromInt(1)}, UnitPriceCents: 3, TaxRate: 20},
+				{Description: "C", Quantity: DecimalJSON{decimal.NewFromInt(1)}, UnitPriceCents: 3, TaxRate: 20},
+			}
+		}, func(t *testing.T, got InvoiceJSON) {
+			if got.VATRounding != VATRoundingBucket {
+				t.Errorf("vat_rounding = %q, want bucket", got.VATRounding)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		for _, version := range supportedEbInterfaceVersions() {
+			t.Run(tt.name+"/"+version, func(t *testing.T) {
+				inv := readGoldenInvoice(t)
+				tt.edit(&inv)
+				if err := validateInvoice(inv); err != nil {
+					t.Fatal(err)
+				}
+				tt.check(t, assertRoundTrip(t, inv, version).Invoice)
+			})
+		}
+	}
+}
+
+func TestParseEbInterfaceReportsUnknownUnit(t *testing.T) {
+	inv := readGoldenInvoice(t)
+	inv.Items[0].Unit = "HUR"
+	doc, err := TransformToEbInterface(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = bytes.Replace(doc, []byte(`Unit="HUR"`), []byte(`Unit="STUNDE"`), 1)
+	parsed, err := ParseEbInterface(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Unmapped) != 1 || !strings.HasSuffix(parsed.Unmapped[0].XPath, "/Quantity/@Unit") || parsed.Unmapped[0].Value != "STUNDE" {
+		t.Errorf("unmapped = %+v, want the unit", parsed.Unmapped)
+	}
+	if parsed.Invoice.Items[0].Unit != "" {
+		t.Errorf("unit = %q, want empty", parsed.Invoice.Items[0].Unit)
+	}
+}
+
+// TestParseEbInterfaceDocuments round-trips the document types, profiles and
+// optional parts beyond the sample invoices.
+func TestParseEbInterfaceDocuments(t *testing.T) {
+	rate20 := 20.0
+	tests := []struct {
+		name string
+		edit func(inv *InvoiceJSON)
+	}{
+		{"credit memo", func(inv *InvoiceJSON) {
+			inv.DocumentType = DocTypeCreditMemo
+			inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2025-12-01"}
+		}},
+		{"cancellation", func(inv *InvoiceJSON) {
+			inv.DocumentType = DocTypeCancellation
+			inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2025-12-01", Comment: "Storno"}
+		}},
+		{"advance payment", func(inv *InvoiceJSON) { inv.DocumentType = DocTypeAdvancePayment }},
+		{"final settlement", func(inv *InvoiceJSON) {
+			inv.DocumentType = DocTypeFinalSettlement
+			inv.AdvanceInvoices = []AdvanceInvoiceJSON{{InvoiceNumber: "AR-1", InvoiceDate: "2025-12-01",
+				Taxes: []AdvanceTaxJSON{{TaxRate: 20, NetCents: 100000, TaxCents: 20000}}}}
+			inv.Prepayments = []PrepaymentJSON{{AmountCents: 12000, InvoiceNumber: "AR-0", InvoiceDate: "2025-11-01"}}
+		}},
+		{"state profile", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2GState
+			inv.Biller.BillerID = ""
+		}},
+		{"b2b profile", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.OrderID = ""
+		}},
+		{"adjustments", func(inv *InvoiceJSON) {
+			inv.Items[0].Adjustments = []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 10, Reason: "Rabatt"}}
+			inv.Adjustments = []AdjustmentJSON{{Type: AdjustmentSurcharge, AmountCents: 1500, Reason: "Versand", TaxRate: &rate20}}
+		}},
+		{"delivery period", func(inv *InvoiceJSON) {
+			inv.Delivery = &DeliveryJSON{FromDate: "2025-12-01", ToDate: "2025-12-31"}
+		}},
+		{"article numbers", func(inv *InvoiceJSON) {
+			inv.Items[0].BillerArticleNumber = "ART-1"
+			inv.Items[0].GTIN = "4006381333931"
+		}},
+	}
+	for _, tt := range tests {
+		for _, version := range supportedEbInterfaceVersions() {
+			t.Run(tt.name+"/"+version, func(t *testing.T) {
+				inv := readGoldenInvoice(t)
+				tt.edit(&inv)
+				if err := validateInvoice(inv); err != nil {
+					t.Fatal(err)
+				}
+				parsed := assertRoundTrip(t, inv, version)
+				want, _ := json.Marshal(inv)
+				got, _ := json.Marshal(parsed.Invoice)
+				if !bytes.Equal(want, got) {
+					t.Errorf("round trip changed the invoice\n%s\n%s", want, got)
+				}
+				totals := computeTotals(inv)
+				if parsed.TotalGrossCents != totals.GrossCts || parsed.PayableCents != totals.PayableCts {
+					t.Errorf("gross %d, payable %d; want %d, %d", parsed.TotalGrossCents, parsed.PayableCents, totals.GrossCts, totals.PayableCts)
+				}
+			})
+		}
+	}
+}
+
+func TestParseEbInterfaceErrors(t *testing.T) {
+	doc, err := TransformToEbInterface(readGoldenInvoice(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseEbInterface([]byte("<Invoice")); err == nil || !strings.Contains(err.Error(), "parse XML") {
+		t.Errorf("malformed XML: got %v", err)
+	}
+	invalid := bytes.Replace(doc, []byte("<InvoiceNumber>"), []byte("<InvoiceNo>"), 1)
+	invalid = bytes.Replace(invalid, []byte("</InvoiceNumber>"), []byte("</InvoiceNo>"), 1)
+	var schemaErr *SchemaValidationError
+	if _, err := ParseEbInterface(invalid); !errors.As(err, &schemaErr) {
+		t.Errorf("schema-invalid document: got %v, want a *SchemaValidationError", err)
+	}
+	if _, err := ParseEbInterface([]byte(`<Invoice xmlns="urn:example"/>`)); err == nil {
+		t.Error("unknown namespace accepted")
+	}
+}
+
+func TestParseEbInterfaceReportsUnmappedAttribute(t *testing.T) {
+	doc, err := TransformToEbInterface(readGoldenInvoice(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = bytes.Replace(doc, []byte(` DocumentType=`), []byte(` ManualProcessing="true" DocumentType=`), 1)
+	parsed, err := ParseEbInterface(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Unmapped) != 1 || !strings.HasSuffix(parsed.Unmapped[0].XPath, "/@ManualProcessing") || parsed.Unmapped[0].Reason != unmappedReasonNoField {
+		t.Errorf("unmapped = %+v, want ManualProcessing", parsed.Unmapped)
+	}
+}
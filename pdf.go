@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// pdfDocument is a minimal PDF 1.7 writer: numbered objects, optionally
+// compressed streams and a classic cross-reference table. It covers what the
+// invoice rendering needs without any external tool.
+type pdfDocument struct {
+	objects [][]byte // objects[i] is the body of object i+1
+}
+
+// reserve allocates an object number whose body is set later, which allows
+// forward references such as page -> parent.
+func (d *pdfDocument) reserve() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects)
+}
+
+func (d *pdfDocument) set(ref int, body string) {
+	d.objects[ref-1] = []byte(body)
+}
+
+// add appends an object and returns its number.
+func (d *pdfDocument) add(body string) int {
+	ref := d.reserve()
+	d.set(ref, body)
+	return ref
+}
+
+// addStream appends a stream object. extra holds additional dictionary
+// entries; Length and, when compressed, Filter are added here.
+func (d *pdfDocument) addStream(extra string, data []byte, compress bool) int {
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		extra += " /Filter /FlateDecode"
+	}
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "<< %s /Length %d >>\nstream\n", strings.TrimSpace(extra), len(data))
+	body.Write(data)
+	body.WriteString("\nendstream")
+	ref := d.reserve()
+	d.objects[ref-1] = body.Bytes()
+	return ref
+}
+
+// bytes serializes the document. trailer holds the trailer entries besides
+// Size, e.g. "/Root 1 0 R /Info 2 0 R".
+func (d *pdfDocument) bytes(trailer string) []byte {
+	var out bytes.Buffer
+	// The binary comment marks the file as binary for transfer tools (and PDF/A).
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(d.objects))
+	for i, body := range d.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n", len(d.objects)+1)
+	out.WriteString("0000000000 65535 f \n")
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, trailer, xref)
+	return out.Bytes()
+}
+
+// pdfString encodes s as a literal PDF string. s must already be in the
+// target encoding (WinAnsi for page text, PDFDocEncoding for metadata).
+func pdfString(s []byte) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range s {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// pdfTextString encodes metadata text (Info dictionary, file names) as UTF-16BE
+// with byte order mark, which represents any character.
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range s {
+		if r > 0xFFFF {
+			r -= 0x10000
+			fmt.Fprintf(&b, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfCanvas collects the content stream of one page.
+type pdfCanvas struct {
+	buf bytes.Buffer
+}
+
+// text draws s with its baseline starting at (x, y).
+func (c *pdfCanvas) text(f *pdfFont, size, x, y float64, s string) {
+	fmt.Fprintf(&c.buf, "BT /%s %s Tf %s %s Td %s Tj ET\n", f.resource, pdfNum(size), pdfNum(x), pdfNum(y), pdfString(encodeWinAnsi(s)))
+}
+
+// textRight draws s so that it ends at x.
+func (c *pdfCanvas) textRight(f *pdfFont, size, x, y float64, s string) {
+	c.text(f, size, x-f.width(s, size), y, s)
+}
+
+// line strokes a line of the given width in the current stroke color.
+func (c *pdfCanvas) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&c.buf, "%s w %s %s m %s %s l S\n", pdfNum(width), pdfNum(x1), pdfNum(y1), pdfNum(x2), pdfNum(y2))
+}
+
+// fillRect fills a rectangle with a gray level (0 black, 1 white) and resets the fill to black.
+func (c *pdfCanvas) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&c.buf, "%s g %s %s %s %s re f 0 g\n", pdfNum(gray), pdfNum(x), pdfNum(y), pdfNum(w), pdfNum(h))
+}
+
+// pdfNum formats a coordinate with at most two decimals.
+func pdfNum(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
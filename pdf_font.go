@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfFont is an embedded TrueType font used as a simple font with
+// WinAnsiEncoding, which covers German text and the euro sign. The Go fonts
+// ship with golang.org/x/image, so rendering needs no system fonts.
+type pdfFont struct {
+	resource string // resource name in page dictionaries, e.g. F1
+	baseFont string
+	ttf      []byte
+	widths   [256]int // advance widths in 1/1000 em, indexed by WinAnsi code
+
+	ascent, descent, capHeight int
+	bbox                       [4]int
+}
+
+var (
+	pdfFontsOnce        sync.Once
+	pdfRegular, pdfBold *pdfFont
+	pdfFontsErr         error
+)
+
+// loadPDFFonts parses the bundled fonts once.
+func loadPDFFonts() (regular, bold *pdfFont, err error) {
+	pdfFontsOnce.Do(func() {
+		if pdfRegular, pdfFontsErr = newPDFFont("F1", "GoRegular", goregular.TTF); pdfFontsErr != nil {
+			return
+		}
+		pdfBold, pdfFontsErr = newPDFFont("F2", "GoBold", gobold.TTF)
+	})
+	return pdfRegular, pdfBold, pdfFontsErr
+}
+
+func newPDFFont(resource, baseFont string, ttf []byte) (*pdfFont, error) {
+	f, err := sfnt.Parse(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("parse font %s: %w", baseFont, err)
+	}
+	var buf sfnt.Buffer
+	unitsPerEm := int(f.UnitsPerEm())
+	ppem := fixed.I(unitsPerEm) // 26.6 values are then in font units
+	scale := func(v fixed.Int26_6) int { return int(math.Round(float64(v) / 64 * 1000 / float64(unitsPerEm))) }
+
+	pf := &pdfFont{resource: resource, baseFont: baseFont, ttf: ttf}
+	for code := 32; code < 256; code++ {
+		r := winAnsiRune(byte(code))
+		idx, err := f.GlyphIndex(&buf, r)
+		if err != nil || idx == 0 {
+			continue
+		}
+		adv, err := f.GlyphAdvance(&buf, idx, ppem, font.HintingNone)
+		if err != nil {
+			continue
+		}
+		pf.widths[code] = scale(adv)
+	}
+
+	m, err := f.Metrics(&buf, ppem, font.HintingNone)
+	if err != nil {
+		return nil, fmt.Errorf("font metrics %s: %w", baseFont, err)
+	}
+	pf.ascent, pf.descent, pf.capHeight = scale(m.Ascent), -scale(m.Descent), scale(m.CapHeight)
+	b, err := f.Bounds(&buf, ppem, font.HintingNone)
+	if err != nil {
+		return nil, fmt.Errorf("font bounds %s: %w", baseFont, err)
+	}
+	// sfnt uses a y-down coordinate system; PDF is y-up.
+	pf.bbox = [4]int{scale(b.Min.X), -scale(b.Max.Y), scale(b.Max.X), -scale(b.Min.Y)}
+	return pf, nil
+}
+
+// width returns the advance width of s in points at the given size.
+func (f *pdfFont) width(s string, size float64) float64 {
+	total := 0
+	for _, c := range encodeWinAnsi(s) {
+		total += f.widths[c]
+	}
+	return float64(total) * size / 1000
+}
+
+// embed writes the font program, descriptor and font dictionary and returns
+// the font dictionary's object number.
+func (f *pdfFont) embed(d *pdfDocument) int {
+	file := d.addStream(fmt.Sprintf("/Length1 %d", len(f.ttf)), f.ttf, true)
+	descriptor := d.add(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.baseFont, f.bbox[0], f.bbox[1], f.bbox[2], f.bbox[3], f.ascent, f.descent, f.capHeight, file))
+	widths := make([]string, 0, 224)
+	for code := 32; code < 256; code++ {
+		widths = append(widths, fmt.Sprintf("%d", f.widths[code]))
+	}
+	return d.add(fmt.Sprintf("<< /Type /Font /Subtype /TrueType /BaseFont /%s /FirstChar 32 /LastChar 255 /Widths [%s] /Encoding /WinAnsiEncoding /FontDescriptor %d 0 R >>",
+		f.baseFont, strings.Join(widths, " "), descriptor))
+}
+
+// winAnsi80 maps the WinAnsi (cp1252) codes 0x80-0x9F; all other codes from
+// 0x20 are identical to Latin-1. Zero marks an unused code.
+var winAnsi80 = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0x0178,
+}
+
+func winAnsiRune(c byte) rune {
+	if c >= 0x80 && c < 0xA0 {
+		return winAnsi80[c-0x80]
+	}
+	return rune(c)
+}
+
+// encodeWinAnsi converts s to WinAnsi. Characters outside the encoding become '?'.
+func encodeWinAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r >= 0x20 && r < 0x80, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			c := byte('?')
+			for i, w := range winAnsi80 {
+				if w != 0 && w == r {
+					c = byte(0x80 + i)
+					break
+				}
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testPDF is a PDF read back through its cross-reference table, just far
+// enough to check the structure and the text the invoice writer produces.
+type testPDF struct {
+	objects map[int][]byte // object bodies between "N 0 obj" and "endobj"
+	trailer string
+}
+
+var (
+	pdfStartXRefRe = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	pdfLengthRe    = regexp.MustCompile(`/Length (\d+)`)
+	pdfShowTextRe  = regexp.MustCompile(`\(((?:[^()\\]|\\.)*)\) Tj`)
+)
+
+// parseTestPDF checks the header, the cross-reference table and the trailer
+// and returns the objects they point to.
+func parseTestPDF(t *testing.T, data []byte) *testPDF {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.7\n")) {
+		t.Fatalf("no PDF header: %q", data[:min(len(data), 16)])
+	}
+	m := pdfStartXRefRe.FindSubmatch(data)
+	if m == nil {
+		t.Fatal("no startxref at the end of the file")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	var size int
+	if _, err := fmt.Sscanf(string(data[xref:]), "xref\n0 %d\n", &size); err != nil {
+		t.Fatalf("startxref %d does not point to a cross-reference table: %v", xref, err)
+	}
+	entries := data[bytes.IndexByte(data[xref+5:], '\n')+xref+6:]
+	if !bytes.HasPrefix(entries, []byte("0000000000 65535 f \n")) {
+		t.Fatalf("xref entry 0: %q", entries[:20])
+	}
+
+	p := &testPDF{objects: map[int][]byte{}}
+	for i := 1; i < size; i++ {
+		entry := string(entries[i*20 : (i+1)*20])
+		var offset, generation int
+		var kind string
+		if _, err := fmt.Sscanf(entry, "%010d %05d %s", &offset, &generation, &kind); err != nil || kind != "n" {
+			t.Fatalf("xref entry %d: %q", i, entry)
+		}
+		header := fmt.Sprintf("%d 0 obj\n", i)
+		if !bytes.HasPrefix(data[offset:], []byte(header)) {
+			t.Fatalf("xref entry %d points to %q", i, data[offset:offset+min(len(header), len(data)-offset)])
+		}
+		body := data[offset+len(header):]
+		end := bytes.Index(body, []byte("\nendobj\n"))
+		if end < 0 {
+			t.Fatalf("object %d has no endobj", i)
+		}
+		p.objects[i] = body[:end]
+	}
+
+	trailer := entries[size*20:]
+	if !bytes.HasPrefix(trailer, []byte("trailer\n")) {
+		t.Fatalf("no trailer after the cross-reference table: %q", trailer[:min(len(trailer), 20)])
+	}
+	p.trailer = string(trailer[:bytes.Index(trailer, []byte("\nstartxref"))])
+	if want := fmt.Sprintf("/Size %d ", size); !strings.Contains(p.trailer, want) {
+		t.Errorf("trailer %q lacks %s", p.trailer, want)
+	}
+	return p
+}
+
+// object returns the body of object ref.
+func (p *testPDF) object(t *testing.T, ref int) string {
+	t.Helper()
+	body, ok := p.objects[ref]
+	if !ok {
+		t.Fatalf("object %d does not exist", ref)
+	}
+	return string(body)
+}
+
+// dict returns the dictionary of object ref, without a stream.
+func (p *testPDF) dict(t *testing.T, ref int) string {
+	t.Helper()
+	body := p.object(t, ref)
+	if i := strings.Index(body, "\nstream\n"); i >= 0 {
+		return body[:i]
+	}
+	return body
+}
+
+// stream returns the decoded data of stream object ref.
+func (p *testPDF) stream(t *testing.T, ref int) []byte {
+	t.Helper()
+	body := p.object(t, ref)
+	i := strings.Index(body, "\nstream\n")
+	if i < 0 || !strings.HasSuffix(body, "\nendstream") {
+		t.Fatalf("object %d is not a stream", ref)
+	}
+	dict, data := body[:i], []byte(body[i+len("\nstream\n"):len(body)-len("\nendstream")])
+	m := pdfLengthRe.FindStringSubmatch(dict)
+	if m == nil || m[1] != strconv.Itoa(len(data)) {
+		t.Fatalf("object %d: %s for %d bytes of data", ref, dict, len(data))
+	}
+	if !strings.Contains(dict, "/Filter /FlateDecode") {
+		return data
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("object %d: %v", ref, err)
+	}
+	decoded, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("object %d: %v", ref, err)
+	}
+	return decoded
+}
+
+// pdfRef returns the object number of the indirect reference stored under key
+// in dict, e.g. "/Root 1 0 R", or the first one of an array, or 0.
+func pdfRef(dict, key string) int {
+	m := regexp.MustCompile(regexp.QuoteMeta(key) + ` \[?(\d+) 0 R`).FindStringSubmatch(dict)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// pageTexts returns the text shown on each page, one line per text operator.
+func (p *testPDF) pageTexts(t *testing.T) []string {
+	t.Helper()
+	catalog := p.dict(t, pdfRef(p.trailer, "/Root"))
+	if !strings.Contains(catalog, "/Type /Catalog") {
+		t.Fatalf("root %q is no catalog", catalog)
+	}
+	pages := p.dict(t, pdfRef(catalog, "/Pages"))
+	kids := regexp.MustCompile(`(\d+) 0 R`).FindAllStringSubmatch(pages[strings.Index(pages, "/Kids"):], -1)
+	if want := fmt.Sprintf("/Count %d ", len(kids)); !strings.Contains(pages, want) {
+		t.Errorf("pages %q: want %s", pages, want)
+	}
+	var texts []string
+	for _, kid := range kids {
+		ref, _ := strconv.Atoi(kid[1])
+		page := p.dict(t, ref)
+		if !strings.Contains(page, "/Type /Page ") || !strings.Contains(page, "/MediaBox [0 0 595.28 841.89]") {
+			t.Errorf("page %d: %s", ref, page)
+		}
+		var lines []string
+		for _, m := range pdfShowTextRe.FindAllSubmatch(p.stream(t, pdfRef(page, "/Contents")), -1) {
+			lines = append(lines, decodePDFString(m[1]))
+		}
+		texts = append(texts, strings.Join(lines, "\n"))
+	}
+	return texts
+}
+
+// decodePDFString reverses pdfString and the WinAnsi encoding of page text.
+func decodePDFString(s []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			default:
+				c = s[i]
+			}
+		}
+		b.WriteRune(winAnsiRune(c))
+	}
+	return b.String()
+}
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Rechnung", "(Rechnung)"},
+		{"(a) b\\c", `(\(a\) b\\c)`},
+		{"a\r\nb", `(a\r\nb)`},
+	}
+	for _, tt := range tests {
+		got := pdfString([]byte(tt.in))
+		if got != tt.want {
+			t.Errorf("pdfString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+		if back := decodePDFString([]byte(got[1 : len(got)-1])); back != tt.in {
+			t.Errorf("decoded %q, want %q", back, tt.in)
+		}
+	}
+	if got := string(encodeWinAnsi("Straße € – “x” 😀")); got != "Stra\xdfe \x80 \x96 \x93x\x94 ?" {
+		t.Errorf("encodeWinAnsi = %q", got)
+	}
+}
+
+func TestPDFTextString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"A", "<FEFF0041>"},
+		{"Ä€", "<FEFF00C420AC>"},
+		{"😀", "<FEFFD83DDE00>"},
+	}
+	for _, tt := range tests {
+		if got := pdfTextString(tt.in); got != tt.want {
+			t.Errorf("pdfTextString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFNum(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"}, {50, "50"}, {595.28, "595.28"}, {841.890, "841.89"}, {7.5, "7.5"}, {0.004, "0"}, {-0.001, "0"}, {-12.345, "-12.35"},
+	}
+	for _, tt := range tests {
+		if got := pdfNum(tt.in); got != tt.want {
+			t.Errorf("pdfNum(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFDocument(t *testing.T) {
+	d := &pdfDocument{}
+	catalog := d.reserve()
+	plain := d.addStream("/Type /Metadata", []byte("plain data"), false)
+	packed := d.addStream("", []byte(strings.Repeat("compressed ", 100)), true)
+	d.set(catalog, "<< /Type /Catalog >>")
+
+	p := parseTestPDF(t, d.bytes(fmt.Sprintf("/Root %d 0 R", catalog)))
+	if got := p.dict(t, pdfRef(p.trailer, "/Root")); got != "<< /Type /Catalog >>" {
+		t.Errorf("root %q", got)
+	}
+	if got := string(p.stream(t, plain)); got != "plain data" {
+		t.Errorf("plain stream %q", got)
+	}
+	if got := p.dict(t, packed); !strings.Contains(got, "/Filter /FlateDecode") || len(p.objects[packed]) > 200 {
+		t.Errorf("compressed stream %q", got)
+	}
+	if got := string(p.stream(t, packed)); got != strings.Repeat("compressed ", 100) {
+		t.Errorf("compressed stream decodes to %q", got)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hybrid invoices: a PDF/A-3b rendering that carries the machine readable
+// XML as an associated file, so one archived file serves both humans and
+// software (the ZUGFeRD / Factur-X container model).
+
+func init() {
+	registerOutputFormat(outputFormat{
+		Name:        "pdfa",
+		ContentType: "application/pdf",
+		Check: func(inv InvoiceJSON, opts renderOptions) error {
+			if opts.Embed == "cii" && opts.CIIProfile == "xrechnung" {
+				return checkXRechnung(inv)
+			}
+			return nil
+		},
+		Render: RenderInvoicePDFA,
+	})
+}
+
+// pdfAttachment is a file embedded into a PDF/A-3 document.
+type pdfAttachment struct {
+	FileName    string
+	Description string
+	MimeType    string
+	Data        []byte
+	Modified    time.Time
+	// FacturXLevel is the Factur-X conformance level (e.g. "EN 16931") of a
+	// CII attachment; it adds the Factur-X XMP schema. Empty for ebInterface.
+	FacturXLevel string
+}
+
+// RenderInvoicePDFA renders the invoice as PDF/A-3b with the XML of
+// opts.Embed (ebinterface, the default, or cii) attached. The embedded
+// ebInterface document is schema validated like a plain XML response.
+func RenderInvoicePDFA(inv InvoiceJSON, opts renderOptions) ([]byte, error) {
+	att := &pdfAttachment{MimeType: "application/xml", Modified: time.Now().UTC()}
+	switch opts.Embed {
+	case "", "ebinterface":
+		doc, err := TransformToEbInterfaceVersion(inv, opts.EbInterfaceVersion)
+		if err != nil {
+			return nil, err
+		}
+		if err := ValidateEbInterface(doc); err != nil {
+			return nil, err
+		}
+		att.FileName = "ebinterface.xml"
+		att.Description = "ebInterface invoice"
+		att.Data = doc
+	case "cii":
+		profile, err := lookupCIIProfile(opts.CIIProfile)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := TransformToCII(inv, profile.Name)
+		if err != nil {
+			return nil, err
+		}
+		att.FileName = "factur-x.xml" // Name mandated by Factur-X / ZUGFeRD 2.x
+		att.Description = "Factur-X/ZUGFeRD invoice"
+		att.Data = doc
+		att.FacturXLevel = "EN 16931"
+		if profile.Name == "xrechnung" {
+			att.FacturXLevel = "XRECHNUNG"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported embed %q (supported: ebinterface, cii)", opts.Embed)
+	}
+	return renderInvoicePDF(inv, att)
+}
+
+// addPDFA3 writes the PDF/A-3 specific objects (XMP metadata, sRGB output
+// intent, embedded file) and returns the entries to add to the catalog.
+func addPDFA3(d *pdfDocument, meta pdfMetadata, att *pdfAttachment) string {
+	metadata := d.addStream("/Type /Metadata /Subtype /XML", buildXMP(meta, att), false)
+
+	icc := d.addStream("/N 3", srgbICCProfile(), true)
+	intent := d.add(fmt.Sprintf("<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R >>", icc))
+
+	file := d.addStream(fmt.Sprintf("/Type /EmbeddedFile /Subtype /%s /Params << /Size %d /ModDate %s >>",
+		pdfNameEscape(att.MimeType), len(att.Data), pdfDate(att.Modified)), att.Data, true)
+	// Alternative: the XML is an equivalent representation of the visible invoice.
+	spec := d.add(fmt.Sprintf("<< /Type /Filespec /F %s /UF %s /Desc %s /AFRelationship /Alternative /EF << /F %d 0 R /UF %d 0 R >> >>",
+		pdfString([]byte(att.FileName)), pdfTextString(att.FileName), pdfTextString(att.Description), file, file))
+
+	return fmt.Sprintf(" /Metadata %d 0 R /OutputIntents [%d 0 R] /AF [%d 0 R] /Names << /EmbeddedFiles << /Names [%s %d 0 R] >> >> /PageMode /UseAttachments",
+		metadata, intent, spec, pdfString([]byte(att.FileName)), spec)
+}
+
+// pdfNameEscape escapes the characters of s that are not allowed in a PDF name, e.g. "/" in MIME types.
+func pdfNameEscape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c < '!' || c > '~' || strings.IndexByte("#()<>[]{}/%", c) >= 0 {
+			fmt.Fprintf(&b, "#%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// buildXMP returns the XMP packet declaring PDF/A-3b conformance. Title,
+// author, producer and dates mirror the Info dictionary as PDF/A requires.
+func buildXMP(meta pdfMetadata, att *pdfAttachment) []byte {
+	esc := func(s string) string {
+		var b bytes.Buffer
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	date := meta.Created.UTC().Format("2006-01-02T15:04:05+00:00")
+
+	var b strings.Builder
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+<pdfaid:part>3</pdfaid:part>
+<pdfaid:conformance>B</pdfaid:conformance>
+</rdf:Description>
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:format>application/pdf</dc:format>
+`)
+	fmt.Fprintf(&b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", esc(meta.Title))
+	fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", esc(meta.Author))
+	b.WriteString("</rdf:Description>\n")
+	fmt.Fprintf(&b, "<rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n<pdf:Producer>%s</pdf:Producer>\n</rdf:Description>\n", esc(meta.Producer))
+	fmt.Fprintf(&b, "<rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n<xmp:CreateDate>%s</xmp:CreateDate>\n<xmp:ModifyDate>%s</xmp:ModifyDate>\n</rdf:Description>\n", date, date)
+	if att.FacturXLevel != "" {
+		fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:fx="urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#">
+<fx:DocumentType>INVOICE</fx:DocumentType>
+<fx:DocumentFileName>%s</fx:DocumentFileName>
+<fx:Version>1.0</fx:Version>
+<fx:ConformanceLevel>%s</fx:ConformanceLevel>
+</rdf:Description>
+`, esc(att.FileName), esc(att.FacturXLevel))
+		b.WriteString(facturXExtensionSchema)
+	}
+	b.WriteString("</rdf:RDF>\n</x:xmpmeta>\n")
+	// Padding lets tools update the packet in place.
+	b.WriteString(strings.Repeat(strings.Repeat(" ", 99)+"\n", 20))
+	b.WriteString(`<?xpacket end="w"?>`)
+	return []byte(b.String())
+}
+
+// facturXExtensionSchema declares the fx properties; PDF/A only permits custom
+// XMP properties that are described by such an extension schema.
+const facturXExtensionSchema = `<rdf:Description rdf:about="" xmlns:pdfaExtension="http://www.aiim.org/pdfa/ns/extension/" xmlns:pdfaSchema="http://www.aiim.org/pdfa/ns/schema#" xmlns:pdfaProperty="http://www.aiim.org/pdfa/ns/property#">
+<pdfaExtension:schemas>
+<rdf:Bag>
+<rdf:li rdf:parseType="Resource">
+<pdfaSchema:schema>Factur-X PDFA Extension Schema</pdfaSchema:schema>
+<pdfaSchema:namespaceURI>urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#</pdfaSchema:namespaceURI>
+<pdfaSchema:prefix>fx</pdfaSchema:prefix>
+<pdfaSchema:property>
+<rdf:Seq>
+<rdf:li rdf:parseType="Resource">
+<pdfaProperty:name>DocumentFileName</pdfaProperty:name>
+<pdfaProperty:valueType>Text</pdfaProperty:valueType>
+<pdfaProperty:category>external</pdfaProperty:category>
+<pdfaProperty:description>name of the embedded XML invoice file</pdfaProperty:description>
+</rdf:li>
+<rdf:li rdf:parseType="Resource">
+<pdfaProperty:name>DocumentType</pdfaProperty:name>
+<pdfaProperty:valueType>Text</pdfaProperty:valueType>
+<pdfaProperty:category>external</pdfaProperty:category>
+<pdfaProperty:description>INVOICE</pdfaProperty:description>
+</rdf:li>
+<rdf:li rdf:parseType="Resource">
+<pdfaProperty:name>Version</pdfaProperty:name>
+<pdfaProperty:valueType>Text</pdfaProperty:valueType>
+<pdfaProperty:category>external</pdfaProperty:category>
+<pdfaProperty:description>The actual version of the Factur-X XML schema</pdfaProperty:description>
+</rdf:li>
+<rdf:li rdf:parseType="Resource">
+<pdfaProperty:name>ConformanceLevel</pdfaProperty:name>
+<pdfaProperty:valueType>Text</pdfaProperty:valueType>
+<pdfaProperty:category>external</pdfaProperty:category>
+<pdfaProperty:description>The conformance level of the embedded Factur-X data</pdfaProperty:description>
+</rdf:li>
+</rdf:Seq>
+</pdfaSchema:property>
+</rdf:li>
+</rdf:Bag>
+</pdfaExtension:schemas>
+</rdf:Description>
+`
+
+var (
+	srgbICCOnce sync.Once
+	srgbICC     []byte
+)
+
+// srgbICCProfile returns an ICC v2 display profile for sRGB IEC61966-2.1,
+// built from the published primaries (D50 adapted) and tone curve so that no
+// binary profile has to be shipped.
+func srgbICCProfile() []byte {
+	srgbICCOnce.Do(func() { srgbICC = buildSRGBICCProfile() })
+	return srgbICC
+}
+
+func buildSRGBICCProfile() []byte {
+	s15 := func(v float64) []byte {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(int32(math.Round(v*65536))))
+		return b
+	}
+	xyz := func(x, y, z float64) []byte {
+		b := []byte("XYZ \x00\x00\x00\x00")
+		b = append(b, s15(x)...)
+		b = append(b, s15(y)...)
+		return append(b, s15(z)...)
+	}
+	text := func(s string) []byte {
+		b := []byte("text\x00\x00\x00\x00")
+		return append(append(b, s...), 0)
+	}
+	desc := func(s string) []byte {
+		b := []byte("desc\x00\x00\x00\x00")
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)+1))
+		b = append(append(b, s...), 0)
+		b = append(b, make([]byte, 4+4)...)    // Unicode language code and count
+		b = append(b, make([]byte, 2+1+67)...) // ScriptCode code, count and string
+		return b
+	}
+	// The sRGB transfer function sampled at 1024 points.
+	curve := []byte("curv\x00\x00\x00\x00")
+	curve = binary.BigEndian.AppendUint32(curve, 1024)
+	for i := 0; i < 1024; i++ {
+		v := float64(i) / 1023
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curve = binary.BigEndian.AppendUint16(curve, uint16(math.Round(v*65535)))
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc("sRGB IEC61966-2.1")},
+		{"cprt", text("No copyright, use freely")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		{"gTRC", curve},
+		{"bTRC", curve},
+	}
+
+	// Tag data follows the 128 byte header and the tag table; each element is
+	// 4 byte aligned. The three TRC tags share one element.
+	offset := 128 + 4 + 12*len(tags)
+	var table, data []byte
+	table = binary.BigEndian.AppendUint32(table, uint32(len(tags)))
+	var curveOffset int
+	for _, t := range tags {
+		start := offset + len(data)
+		if t.sig == "gTRC" || t.sig == "bTRC" {
+			start = curveOffset
+		} else {
+			if t.sig == "rTRC" {
+				curveOffset = start
+			}
+			data = append(data, t.data...)
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+		table = append(table, t.sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(start))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(t.data)))
+	}
+
+	size := 128 + len(table) + len(data)
+	header := make([]byte, 0, 128)
+	header = binary.BigEndian.AppendUint32(header, uint32(size))
+	header = append(header, 0, 0, 0, 0)                               // Preferred CMM
+	header = append(header, 0x02, 0x10, 0, 0)                         // Version 2.1
+	header = append(header, "mntrRGB XYZ "...)                        // Display class, RGB data, XYZ PCS
+	header = append(header, 0x07, 0xd0, 0, 1, 0, 1, 0, 0, 0, 0, 0, 0) // 2000-01-01
+	header = append(header, "acsp"...)
+	header = append(header, make([]byte, 4+4+4+4+8+4)...) // Platform, flags, manufacturer, model, attributes, intent
+	header = append(header, s15(0.9642)...)               // PCS illuminant D50
+	header = append(header, s15(1.0)...)
+	header = append(header, s15(0.8249)...)
+	header = append(header, make([]byte, 128-len(header))...) // Creator, ID, reserved
+
+	out := append(header, table...)
+	return append(out, data...)
+}
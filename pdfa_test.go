@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// xmpValues returns the text of the first XMP property of each local name,
+// after checking that the packet is well-formed XML. Values inside rdf
+// containers (e.g. dc:title's rdf:Alt) belong to the enclosing property.
+func xmpValues(t *testing.T, packet []byte) map[string]string {
+	t.Helper()
+	const rdf = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	values := map[string]string{}
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	var names []string
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return values
+		}
+		if err != nil {
+			t.Fatalf("XMP: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := tok.Name.Local
+			if tok.Name.Space == rdf && len(names) > 0 {
+				name = names[len(names)-1]
+			}
+			names = append(names, name)
+		case xml.CharData:
+			if s := strings.TrimSpace(string(tok)); s != "" && len(names) > 0 {
+				if name := names[len(names)-1]; values[name] == "" {
+					values[name] = s
+				}
+			}
+		case xml.EndElement:
+			names = names[:len(names)-1]
+		}
+	}
+}
+
+func TestRenderInvoicePDFA(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         renderOptions
+		wantFile     string
+		wantDesc     string
+		wantLevel    string // fx:ConformanceLevel, empty for ebInterface
+		wantDocument func(inv InvoiceJSON) ([]byte, error)
+	}{
+		{"default", renderOptions{}, "ebinterface.xml", "ebInterface invoice", "", func(inv InvoiceJSON) ([]byte, error) {
+			return TransformToEbInterfaceVersion(inv, "")
+		}},
+		{"ebinterface 5.0", renderOptions{Embed: "ebinterface", EbInterfaceVersion: "5.0"}, "ebinterface.xml", "ebInterface invoice", "", func(inv InvoiceJSON) ([]byte, error) {
+			return TransformToEbInterfaceVersion(inv, "5.0")
+		}},
+		{"cii", renderOptions{Embed: "cii"}, "factur-x.xml", "Factur-X/ZUGFeRD invoice", "EN 16931", func(inv InvoiceJSON) ([]byte, error) {
+			return TransformToCII(inv, "en16931")
+		}},
+		{"cii xrechnung", renderOptions{Embed: "cii", CIIProfile: "xrechnung"}, "factur-x.xml", "Factur-X/ZUGFeRD invoice", "XRECHNUNG", func(inv InvoiceJSON) ([]byte, error) {
+			return TransformToCII(inv, "xrechnung")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			data, err := RenderInvoicePDFA(inv, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := parseTestPDF(t, data)
+			catalog := p.dict(t, pdfRef(p.trailer, "/Root"))
+			info := p.dict(t, pdfRef(p.trailer, "/Info"))
+
+			// The visible invoice is unchanged.
+			pages := p.pageTexts(t)
+			if missing := missingPDFLines(pages[0], []string{"Rechnung 2026-001", "Gesamtbetrag EUR", "5.400,00"}); len(missing) > 0 {
+				t.Errorf("missing %q in\n%s", missing, pages[0])
+			}
+
+			// Embedded file: an alternative representation of the invoice,
+			// listed both as associated file and in the EmbeddedFiles name tree.
+			spec := pdfRef(catalog, "/AF")
+			if spec == 0 {
+				t.Fatalf("catalog %s has no /AF", catalog)
+			}
+			if want := fmt.Sprintf("/Names << /EmbeddedFiles << /Names [(%s) %d 0 R] >> >>", tt.wantFile, spec); !strings.Contains(catalog, want) {
+				t.Errorf("catalog %s lacks %s", catalog, want)
+			}
+			if !strings.Contains(catalog, "/PageMode /UseAttachments") {
+				t.Errorf("catalog %s does not open the attachments", catalog)
+			}
+			specDict := p.dict(t, spec)
+			for _, want := range []string{
+				"/Type /Filespec",
+				"/F (" + tt.wantFile + ")",
+				"/UF " + pdfTextString(tt.wantFile),
+				"/Desc " + pdfTextString(tt.wantDesc),
+				"/AFRelationship /Alternative",
+			} {
+				if !strings.Contains(specDict, want) {
+					t.Errorf("file specification %s lacks %s", specDict, want)
+				}
+			}
+			file := pdfRef(specDict, "/EF << /F")
+			if file == 0 || pdfRef(specDict, "/UF") != file {
+				t.Fatalf("file specification %s: /F and /UF must name the same embedded file", specDict)
+			}
+			want, err := tt.wantDocument(inv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.stream(t, file); !bytes.Equal(got, want) {
+				t.Errorf("embedded file differs from the XML output:\n%s", got)
+			}
+			fileDict := p.dict(t, file)
+			for _, want := range []string{
+				"/Type /EmbeddedFile",
+				"/Subtype /application#2Fxml",
+				fmt.Sprintf("/Params << /Size %d /ModDate (D:", len(want)),
+			} {
+				if !strings.Contains(fileDict, want) {
+					t.Errorf("embedded file %s lacks %s", fileDict, want)
+				}
+			}
+
+			// Output intent with an sRGB profile for the colours used on the pages.
+			intent := p.dict(t, pdfRef(catalog, "/OutputIntents"))
+			for _, want := range []string{"/Type /OutputIntent", "/S /GTS_PDFA1", "/OutputConditionIdentifier (sRGB IEC61966-2.1)"} {
+				if !strings.Contains(intent, want) {
+					t.Errorf("output intent %s lacks %s", intent, want)
+				}
+			}
+			icc := pdfRef(intent, "/DestOutputProfile")
+			if d := p.dict(t, icc); !strings.Contains(d, "/N 3") {
+				t.Errorf("output profile %s is not an RGB profile", d)
+			}
+			if got := p.stream(t, icc); !bytes.Equal(got, srgbICCProfile()) {
+				t.Error("output profile is not the sRGB profile")
+			}
+
+			// XMP metadata: uncompressed, PDF/A-3b, and agreeing with the Info dictionary.
+			metadata := pdfRef(catalog, "/Metadata")
+			if d := p.dict(t, metadata); !strings.Contains(d, "/Type /Metadata /Subtype /XML") || strings.Contains(d, "/Filter") {
+				t.Errorf("metadata stream %s", d)
+			}
+			packet := p.stream(t, metadata)
+			if !bytes.HasPrefix(packet, []byte("<?xpacket begin=\"\xef\xbb\xbf\"")) || !bytes.HasSuffix(packet, []byte(`<?xpacket end="w"?>`)) {
+				t.Errorf("metadata is no XMP packet: %q", packet)
+			}
+			xmp := xmpValues(t, packet)
+			created, err := time.Parse(time.RFC3339, xmp["CreateDate"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range map[string]string{
+				"part":        "3",
+				"conformance": "B",
+				"format":      "application/pdf",
+				"ModifyDate":  xmp["CreateDate"],
+			} {
+				if xmp[key] != want {
+					t.Errorf("XMP %s = %q, want %q", key, xmp[key], want)
+				}
+			}
+			for key, want := range map[string]string{
+				"/Title ":        pdfTextString(xmp["title"]),
+				"/Author ":       pdfTextString(xmp["creator"]),
+				"/Producer ":     pdfTextString(xmp["Producer"]),
+				"/CreationDate ": pdfDate(created),
+				"/ModDate ":      pdfDate(created),
+			} {
+				if !strings.Contains(info, key+want+" ") {
+					t.Errorf("info %s: %s differs from the XMP metadata %s", info, key, want)
+				}
+			}
+			if xmp["title"] != "Rechnung 2026-001" || xmp["creator"] != inv.Biller.Name || xmp["Producer"] != generatingSystem {
+				t.Errorf("XMP title %q, creator %q, producer %q", xmp["title"], xmp["creator"], xmp["Producer"])
+			}
+
+			// Factur-X properties, declared by an extension schema, only for CII.
+			if tt.wantLevel == "" {
+				if bytes.Contains(packet, []byte("xmlns:fx=")) {
+					t.Error("ebInterface attachment has Factur-X metadata")
+				}
+				return
+			}
+			for key, want := range map[string]string{
+				"DocumentType":     "INVOICE",
+				"DocumentFileName": tt.wantFile,
+				"Version":          "1.0",
+				"ConformanceLevel": tt.wantLevel,
+				"namespaceURI":     "urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#",
+				"prefix":           "fx",
+			} {
+				if xmp[key] != want {
+					t.Errorf("XMP %s = %q, want %q", key, xmp[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderInvoicePDFAEmbed(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	_, err := RenderInvoicePDFA(inv, renderOptions{Embed: "ubl"})
+	if want := `unsupported embed "ubl" (supported: ebinterface, cii)`; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+	if _, err := RenderInvoicePDFA(inv, renderOptions{Embed: "cii", CIIProfile: "zugferd-basic"}); err == nil {
+		t.Error("unknown CII profile accepted")
+	}
+	if _, err := RenderInvoicePDFA(inv, renderOptions{EbInterfaceVersion: "4.3"}); err == nil {
+		t.Error("unknown ebInterface version accepted")
+	}
+}
+
+func TestPDFNameEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"application/xml", "application#2Fxml"},
+		{"text/plain; charset=utf-8", "text#2Fplain;#20charset=utf-8"},
+		{"a#(b)", "a#23#28b#29"},
+	}
+	for _, tt := range tests {
+		if got := pdfNameEscape(tt.in); got != tt.want {
+			t.Errorf("pdfNameEscape(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSRGBICCProfile(t *testing.T) {
+	icc := srgbICCProfile()
+	if size := binary.BigEndian.Uint32(icc); int(size) != len(icc) {
+		t.Errorf("header size %d, profile has %d bytes", size, len(icc))
+	}
+	if got := string(icc[12:24]); got != "mntrRGB XYZ " {
+		t.Errorf("class and colour spaces %q", got)
+	}
+	if got := string(icc[36:40]); got != "acsp" {
+		t.Errorf("signature %q", got)
+	}
+	count := int(binary.BigEndian.Uint32(icc[128:]))
+	var sigs []string
+	for i := 0; i < count; i++ {
+		entry := icc[132+12*i:]
+		offset, size := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		if offset%4 != 0 || int(offset+size) > len(icc) {
+			t.Errorf("tag %s at %d+%d", entry[:4], offset, size)
+			continue
+		}
+		sigs = append(sigs, string(entry[:4]))
+	}
+	if got := strings.Join(sigs, " "); got != "desc cprt wtpt rXYZ gXYZ bXYZ rTRC gTRC bTRC" {
+		t.Errorf("tags %s", got)
+	}
+	if !bytes.Contains(icc, []byte("sRGB IEC61966-2.1\x00")) {
+		t.Error("profile lacks its description")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported values for InvoiceJSON.Profile.
+const (
+	ProfileB2GFederal = "b2g_federal" // Federal government via e-Rechnung.gv.at
+	ProfileB2GState   = "b2g_state"   // State governments and municipalities
+	ProfileB2B        = "b2b"         // Business customers
+
+	DefaultProfile = ProfileB2GFederal
+)
+
+// invoiceProfile holds the rules that differ between recipients.
+type invoiceProfile struct {
+	// OrderIDRequired makes recipient.order_id mandatory (Auftragsreferenz).
+	OrderIDRequired bool
+	// LineOrderReferences links every line to a position of the recipient's
+	// order, as federal purchase orders require.
+	LineOrderReferences bool
+	// BillerIDRequired makes biller.biller_id mandatory, the supplier number
+	// the recipient assigned to the biller (Lieferantennummer).
+	BillerIDRequired bool
+	// RecipientVATIDOptionalUpToCents is the gross total up to which
+	// recipient.vat_id may be left out; 0 always requires it.
+	RecipientVATIDOptionalUpToCents int64
+}
+
+// smallInvoiceLimitCents is the gross total above which an invoice must
+// state the recipient's UID (§ 11 Abs. 1 Z 2 UStG).
+const smallInvoiceLimitCents = 1000000
+
+var invoiceProfiles = map[string]invoiceProfile{
+	ProfileB2GFederal: {OrderIDRequired: true, LineOrderReferences: true, BillerIDRequired: true},
+	ProfileB2GState:   {OrderIDRequired: true},
+	ProfileB2B:        {RecipientVATIDOptionalUpToCents: smallInvoiceLimitCents},
+}
+
+var supportedProfiles = []string{ProfileB2GFederal, ProfileB2GState, ProfileB2B}
+
+// profileName resolves an empty profile to the default.
+func profileName(profile string) string {
+	if profile == "" {
+		return DefaultProfile
+	}
+	return profile
+}
+
+// profileOf returns the rules of the invoice's profile.
+func profileOf(inv InvoiceJSON) invoiceProfile {
+	return invoiceProfiles[profileName(inv.Profile)]
+}
+
+// validateProfile rejects unknown profiles; empty selects the default.
+func validateProfile(profile string) error {
+	if _, ok := invoiceProfiles[profile]; profile != "" && !ok {
+		return fmt.Errorf("profile %q is not supported (supported: %s)", profile, strings.Join(supportedProfiles, ", "))
+	}
+	return nil
+}
+
+// validateProfileRules checks the fields the invoice's profile makes
+// mandatory. The line items must be valid, as the recipient's VAT ID may
+// depend on the gross total.
+func validateProfileRules(inv InvoiceJSON) error {
+	profile, name := profileOf(inv), profileName(inv.Profile)
+	if profile.OrderIDRequired && inv.Recipient.OrderID == "" {
+		return fmt.Errorf("recipient.order_id is required for profile %s", name)
+	}
+	if profile.BillerIDRequired && inv.Biller.BillerID == "" {
+		return fmt.Errorf("biller.biller_id (supplier number assigned by the recipient) is required for profile %s", name)
+	}
+	if inv.Recipient.VATID != "" {
+		return nil
+	}
+	if profile.RecipientVATIDOptionalUpToCents == 0 {
+		return fmt.Errorf("recipient.vat_id is required for profile %s", name)
+	}
+	if gross := computeTotals(inv).GrossCts; gross > profile.RecipientVATIDOptionalUpToCents || -gross > profile.RecipientVATIDOptionalUpToCents {
+		return fmt.Errorf("recipient.vat_id is required for invoices over %s EUR gross (§ 11 Abs. 1 Z 2 UStG)", formatCentsAsDecimal(profile.RecipientVATIDOptionalUpToCents))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestValidateProfileRules(t *testing.T) {
+	// test_invoice_small.json bills 4,500.00 EUR net, 5,400.00 EUR gross.
+	tests := []struct {
+		name    string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"federal complete", func(inv *InvoiceJSON) {}, ""},
+		{"federal without order id", func(inv *InvoiceJSON) { inv.Recipient.OrderID = "" }, "recipient.order_id is required for profile b2g_federal"},
+		{"federal without biller id", func(inv *InvoiceJSON) { inv.Biller.BillerID = "" }, "biller.biller_id"},
+		{"federal without recipient vat id", func(inv *InvoiceJSON) { inv.Recipient.VATID = "" }, "recipient.vat_id is required for profile b2g_federal"},
+		{"state without biller id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2GState
+			inv.Biller.BillerID = ""
+		}, ""},
+		{"state without order id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2GState
+			inv.Recipient.OrderID = ""
+		}, "recipient.order_id is required for profile b2g_state"},
+		{"state without recipient vat id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2GState
+			inv.Recipient.VATID = ""
+		}, "recipient.vat_id is required for profile b2g_state"},
+		{"b2b without order and biller id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.OrderID = ""
+			inv.Biller.BillerID = ""
+		}, ""},
+		{"b2b small invoice without recipient vat id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.VATID = ""
+		}, ""},
+		{"b2b at the limit without recipient vat id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.VATID = ""
+			inv.Items[0].UnitPriceCents = 833333 // 9,999.996 EUR gross, rounded to 10,000.00
+		}, ""},
+		{"b2b over the limit without recipient vat id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.VATID = ""
+			inv.Items[0].Quantity = DecimalJSON{decimal.NewFromInt(2)}
+		}, "over 10000.00 EUR gross"},
+		{"b2b credit memo over the limit without recipient vat id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.VATID = ""
+			inv.Items[0].Quantity = DecimalJSON{decimal.NewFromInt(2)}
+			inv.DocumentType = DocTypeCreditMemo
+			inv.OriginalInvoice = &DocumentReferenceJSON{InvoiceNumber: "2025-100", InvoiceDate: "2025-12-01"}
+		}, "over 10000.00 EUR gross"},
+		{"b2b reverse charge without recipient vat id", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.VATID = ""
+			inv.Items[0].TaxRate = 0
+			inv.Items[0].TaxCategory = TaxCategoryReverseCharge
+		}, "tax category AE requires recipient.vat_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			tt.edit(&inv)
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestB2BWithoutRecipientVATID(t *testing.T) {
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Profile = ProfileB2B
+	inv.Recipient.VATID = ""
+	doc, err := TransformToEbInterface(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc), "<VATIdentificationNumber>"+ebNoVATID+"</VATIdentificationNumber>") {
+		t.Errorf("recipient VAT ID is not %s:\n%s", ebNoVATID, doc)
+	}
+	parsed := assertRoundTrip(t, inv, DefaultEbInterfaceVersion)
+	if parsed.Invoice.Recipient.VATID != "" || parsed.Invoice.Profile != ProfileB2B {
+		t.Errorf("parsed recipient VAT ID %q, profile %q", parsed.Invoice.Recipient.VATID, parsed.Invoice.Profile)
+	}
+
+	ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(ubl), "<cac:PartyTaxScheme>") != 1 {
+		t.Errorf("want a PartyTaxScheme for the seller only:\n%s", ubl)
+	}
+	if err := outputFormats["ubl"].Check(inv, renderOptions{}); err == nil || !strings.Contains(err.Error(), "electronic address") {
+		t.Errorf("got %v, want an error about the buyer's electronic address", err)
+	}
+	inv.Recipient.Email = "einkauf@example.at"
+	if err := outputFormats["ubl"].Check(inv, renderOptions{}); err != nil {
+		t.Errorf("unexpected error with recipient email: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// schemaFS holds the ebInterface schemas so validation works fully offline.
+//
+//go:embed schemas/*.xsd
+var schemaFS embed.FS
+
+var (
+	schemaCacheMu sync.Mutex
+	schemaCache   = map[string]*xsdSchema{}
+)
+
+// SchemaValidationError lists every schema violation found in a document.
+type SchemaValidationError struct {
+	Namespace  string
+	Violations []Violation
+}
+
+func (e *SchemaValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", v.XPath, v.Message))
+	}
+	return fmt.Sprintf("document is not valid against %s: %s", e.Namespace, strings.Join(msgs, "; "))
+}
+
+// ebInterfaceSchema returns the compiled schema for namespace, loading it on first use.
+func ebInterfaceSchema(namespace string) (*xsdSchema, error) {
+	schemaCacheMu.Lock()
+	defer schemaCacheMu.Unlock()
+	if s, ok := schemaCache[namespace]; ok {
+		return s, nil
+	}
+	v, ok := lookupEbInterfaceNamespace(namespace)
+	if !ok {
+		return nil, fmt.Errorf("unsupported ebInterface namespace %q", namespace)
+	}
+	file := v.SchemaFile
+	s, err := loadXSD(schemaFS, file)
+	if err != nil {
+		return nil, fmt.Errorf("load schema %s: %w", file, err)
+	}
+	schemaCache[namespace] = s
+	return s, nil
+}
+
+// ValidateEbInterface validates an ebInterface XML document against the
+// embedded schema matching its root namespace. It returns a
+// *SchemaValidationError listing all violations if the document is invalid.
+func ValidateEbInterface(doc []byte) error {
+	root, err := parseXMLTree(doc)
+	if err != nil {
+		return fmt.Errorf("parse XML: %w", err)
+	}
+	violations, err := schemaViolations(root)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &SchemaValidationError{Namespace: root.Name.Space, Violations: violations}
+	}
+	return nil
+}
+
+// schemaViolations validates an already parsed document against the schema of its root namespace.
+func schemaViolations(root *xmlNode) ([]Violation, error) {
+	schema, err := ebInterfaceSchema(root.Name.Space)
+	if err != nil {
+		return nil, err
+	}
+	return schema.validate(root), nil
+}
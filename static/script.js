@@ -9,6 +9,7 @@ function copyCurlCommand() {
     "biller": {
       "name": "Musterfirma GmbH",
       "vat_id": "ATU13585627",
+      "biller_id": "123456",
       "address": {
         "street": "Hauptstraße 1",
         "zip": "1010",
@@ -18,8 +19,8 @@ function copyCurlCommand() {
     },
     "recipient": {
       "name": "Kunde AG",
-      "vat_id": "ATU87654321",
-      "order_reference": "1234567890",
+      "vat_id": "ATU87654324",
+      "order_id": "1234567890",
       "address": {
         "street": "Teststraße 5",
         "zip": "4020",
@@ -56,3 +57,29 @@ function copyCurlCommand() {
     });
 }
 
+
+// Labels of the fields that API errors point at.
+const fieldLabels = {
+    'biller.vat_id': 'UID-Nummer des Rechnungsstellers',
+    'recipient.vat_id': 'UID-Nummer des Rechnungsempfängers',
+    'ordering_party.vat_id': 'UID-Nummer des Auftraggebers',
+};
+
+// Turn an API error ({code, message, details}) into a message for the user.
+// Validation details start with the JSON path of the field, e.g.
+// "recipient.vat_id: ...", which is returned so the form can mark it.
+function apiErrorMessage(error) {
+    const field = (error.details || '').split(':')[0];
+    const label = fieldLabels[field] || field;
+    switch (error.code) {
+        case 'VAT_ID_CHECKSUM_INVALID':
+            return {
+                field,
+                message: `Die ${label || 'UID-Nummer'} hat eine ungültige Prüfziffer. Bitte prüfen Sie sie auf Tippfehler.`,
+            };
+        case 'VALIDATION_ERROR':
+            return { field, message: error.details || error.message };
+        default:
+            return { field: '', message: error.message || 'Unbekannter Fehler' };
+    }
+}
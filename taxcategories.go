@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tax category codes (UNCL 5305) accepted for line items.
+const (
+	TaxCategoryStandard       = "S"
+	TaxCategoryZeroRated      = "Z"
+	TaxCategoryExempt         = "E"  // Steuerbefreit, e.g. § 6 UStG 1994
+	TaxCategoryReverseCharge  = "AE" // Übergang der Steuerschuld
+	TaxCategoryIntraCommunity = "K"  // Innergemeinschaftliche Lieferung
+	TaxCategoryExport         = "G"  // Ausfuhrlieferung
+)
+
+var supportedTaxCategories = []string{
+	TaxCategoryStandard, TaxCategoryZeroRated, TaxCategoryReverseCharge,
+	TaxCategoryIntraCommunity, TaxCategoryExport, TaxCategoryExempt,
+}
+
+// defaultTaxExemptionReasons are the legal notes emitted for items that give
+// no tax_exemption_reason. Exempt supplies (E) must state their own legal basis.
+var defaultTaxExemptionReasons = map[string]string{
+	TaxCategoryReverseCharge:  "Übergang der Steuerschuld auf den Leistungsempfänger (Reverse Charge)",
+	TaxCategoryIntraCommunity: "Steuerfreie innergemeinschaftliche Lieferung gemäß Art. 6 Abs. 1 UStG 1994",
+	TaxCategoryExport:         "Steuerfreie Ausfuhrlieferung gemäß § 7 UStG 1994",
+}
+
+// taxExemptionReasonCodes are the VATEX codes (CEF code list) stated next
+// to the reason in UBL and CII. Exempt supplies have no single code.
+var taxExemptionReasonCodes = map[string]string{
+	TaxCategoryReverseCharge:  "VATEX-EU-AE",
+	TaxCategoryIntraCommunity: "VATEX-EU-IC",
+	TaxCategoryExport:         "VATEX-EU-G",
+}
+
+// taxCategory returns the explicit category or the one derived from the rate.
+func taxCategory(category string, rate float64) string {
+	if category != "" {
+		return category
+	}
+	return taxCategoryFromRate(rate)
+}
+
+// isTaxExemptCategory reports whether items of the category are untaxed for a
+// legal reason that the invoice has to state.
+func isTaxExemptCategory(category string) bool {
+	switch category {
+	case TaxCategoryReverseCharge, TaxCategoryIntraCommunity, TaxCategoryExport, TaxCategoryExempt:
+		return true
+	}
+	return false
+}
+
+// taxExemptionReason returns the legal note of a category: the given reason or
+// the default text. Taxed categories have none.
+func taxExemptionReason(category, reason string) string {
+	if !isTaxExemptCategory(category) {
+		return ""
+	}
+	if reason != "" {
+		return reason
+	}
+	return defaultTaxExemptionReasons[category]
+}
+
+// validateTaxCategories checks the tax category of each item against its rate
+// and the exemption reason. Items of one category share a single reason, as
+// the tax summary states it once per category.
+func validateTaxCategories(inv InvoiceJSON) error {
+	reasons := map[string]int{} // category -> first item
+	for i, li := range inv.Items {
+		category := taxCategory(li.TaxCategory, li.TaxRate)
+		switch {
+		case !isSupportedTaxCategory(category):
+			return fmt.Errorf("items[%d].tax_category %q is not supported (supported: %s)", i, li.TaxCategory, strings.Join(supportedTaxCategories, ", "))
+		case category == TaxCategoryStandard && li.TaxRate == 0:
+			return fmt.Errorf("items[%d]: tax category S requires a tax_rate > 0", i)
+		case category != TaxCategoryStandard && li.TaxRate != 0:
+			return fmt.Errorf("items[%d]: tax category %s requires tax_rate 0", i, category)
+		case li.TaxExemptionReason != "" && !isTaxExemptCategory(category):
+			return fmt.Errorf("items[%d].tax_exemption_reason only applies to tax categories AE, K, G and E", i)
+		case category == TaxCategoryExempt && li.TaxExemptionReason == "":
+			return fmt.Errorf("items[%d].tax_exemption_reason is required for tax category E", i)
+		}
+		// Reverse charge and intra-community supplies name the recipient's VAT ID.
+		if (category == TaxCategoryReverseCharge || category == TaxCategoryIntraCommunity) && inv.Recipient.VATID == "" {
+			return fmt.Errorf("items[%d]: tax category %s requires recipient.vat_id", i, category)
+		}
+		if err := validateCrossBorderCategory(inv, category); err != nil {
+			return fmt.Errorf("items[%d]: %w", i, err)
+		}
+		if first, ok := reasons[category]; !ok {
+			reasons[category] = i
+		} else if prev := inv.Items[first]; taxExemptionReason(category, prev.TaxExemptionReason) != taxExemptionReason(category, li.TaxExemptionReason) {
+			return fmt.Errorf("items[%d].tax_exemption_reason differs from items[%d]; items of tax category %s share one reason", i, first, category)
+		}
+	}
+	return nil
+}
+
+// validateCrossBorderCategory checks that the parties of an intra-community
+// supply or an export are located as the category requires. Reverse charge
+// also applies to domestic supplies (§ 19 Abs. 1a UStG 1994) and is not checked.
+func validateCrossBorderCategory(inv InvoiceJSON, category string) error {
+	recipientCountry := addressCountry(inv.Recipient.Address)
+	switch category {
+	case TaxCategoryIntraCommunity:
+		vatCountry := vatIDCountry(inv.Recipient.VATID)
+		if vatCountry == "" || vatCountry == vatIDCountry(inv.Biller.VATID) {
+			return fmt.Errorf("tax category K requires a recipient.vat_id of another EU member state")
+		}
+		if recipientCountry == addressCountry(inv.Biller.Address) {
+			return fmt.Errorf("tax category K requires a recipient address outside the biller's country")
+		}
+	case TaxCategoryExport:
+		if isEUCountry(recipientCountry) {
+			return fmt.Errorf("tax category G requires a recipient address outside the EU")
+		}
+	}
+	return nil
+}
+
+func isSupportedTaxCategory(category string) bool {
+	for _, c := range supportedTaxCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTaxCategories(t *testing.T) {
+	// test_invoice_small.json: Austrian biller and recipient (ATU38516405), one item at 20 %.
+	untaxed := func(category, reason string) func(inv *InvoiceJSON) {
+		return func(inv *InvoiceJSON) {
+			inv.Items[0].TaxRate = 0
+			inv.Items[0].TaxCategory = category
+			inv.Items[0].TaxExemptionReason = reason
+		}
+	}
+	toGermany := func(inv *InvoiceJSON) {
+		inv.Recipient.VATID = "DE136695976"
+		inv.Recipient.Address.Country = "DE"
+	}
+	tests := []struct {
+		name    string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"standard rate", func(inv *InvoiceJSON) { inv.Items[0].TaxCategory = TaxCategoryStandard }, ""},
+		{"zero rate derived", untaxed("", ""), ""},
+		{"zero rated", untaxed(TaxCategoryZeroRated, ""), ""},
+		{"unsupported", func(inv *InvoiceJSON) { inv.Items[0].TaxCategory = "O" }, `items[0].tax_category "O" is not supported`},
+		{"standard without rate", untaxed(TaxCategoryStandard, ""), "items[0]: tax category S requires a tax_rate > 0"},
+		{"reverse charge with rate", func(inv *InvoiceJSON) { inv.Items[0].TaxCategory = TaxCategoryReverseCharge }, "items[0]: tax category AE requires tax_rate 0"},
+		{"zero rated with rate", func(inv *InvoiceJSON) { inv.Items[0].TaxCategory = TaxCategoryZeroRated }, "items[0]: tax category Z requires tax_rate 0"},
+		{"reason for a taxed item", func(inv *InvoiceJSON) { inv.Items[0].TaxExemptionReason = "steuerfrei" }, "items[0].tax_exemption_reason only applies to tax categories AE, K, G and E"},
+		{"reason for a zero rated item", untaxed(TaxCategoryZeroRated, "steuerfrei"), "tax_exemption_reason only applies"},
+
+		{"exempt with reason", untaxed(TaxCategoryExempt, "Steuerfrei gemäß § 6 Abs. 1 Z 19 UStG 1994"), ""},
+		{"exempt without reason", untaxed(TaxCategoryExempt, ""), "items[0].tax_exemption_reason is required for tax category E"},
+
+		{"domestic reverse charge", untaxed(TaxCategoryReverseCharge, ""), ""},
+		{"reverse charge without recipient vat id", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryReverseCharge, "")(inv)
+			inv.Profile = ProfileB2B
+			inv.Recipient.VATID = ""
+		}, "items[0]: tax category AE requires recipient.vat_id"},
+
+		{"intra-community", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryIntraCommunity, "")(inv)
+			toGermany(inv)
+		}, ""},
+		{"intra-community without recipient vat id", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryIntraCommunity, "")(inv)
+			toGermany(inv)
+			inv.Profile = ProfileB2B
+			inv.Recipient.VATID = ""
+		}, "items[0]: tax category K requires recipient.vat_id"},
+		{"intra-community to a domestic vat id", untaxed(TaxCategoryIntraCommunity, ""), "items[0]: tax category K requires a recipient.vat_id of another EU member state"},
+		{"intra-community to a domestic address", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryIntraCommunity, "")(inv)
+			inv.Recipient.VATID = "DE136695976"
+		}, "items[0]: tax category K requires a recipient address outside the biller's country"},
+
+		{"export", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryExport, "")(inv)
+			inv.Recipient.Address.Country = "CH"
+		}, ""},
+		{"export within the EU", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryExport, "")(inv)
+			toGermany(inv)
+		}, "items[0]: tax category G requires a recipient address outside the EU"},
+
+		{"same reason per category", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryReverseCharge, "")(inv)
+			inv.Items = append(inv.Items, LineItemJSON{Description: "Wartung", Quantity: quantity("1"), UnitPriceCents: 100,
+				TaxCategory: TaxCategoryReverseCharge, TaxExemptionReason: defaultTaxExemptionReasons[TaxCategoryReverseCharge]})
+		}, ""},
+		{"differing reasons", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryExempt, "Steuerfrei gemäß § 6 Abs. 1 Z 19 UStG 1994")(inv)
+			inv.Items = append(inv.Items, LineItemJSON{Description: "Miete", Quantity: quantity("1"), UnitPriceCents: 100,
+				TaxCategory: TaxCategoryExempt, TaxExemptionReason: "Steuerfrei gemäß § 6 Abs. 1 Z 16 UStG 1994"})
+		}, "items[1].tax_exemption_reason differs from items[0]; items of tax category E share one reason"},
+		{"differing reasons in other categories", func(inv *InvoiceJSON) {
+			untaxed(TaxCategoryExempt, "Steuerfrei gemäß § 6 Abs. 1 Z 19 UStG 1994")(inv)
+			inv.Items = append(inv.Items, LineItemJSON{Description: "Wartung", Quantity: quantity("1"), UnitPriceCents: 100,
+				TaxCategory: TaxCategoryReverseCharge})
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			tt.edit(&inv)
+			err := validateInvoice(inv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTaxExemptionReason(t *testing.T) {
+	tests := []struct {
+		category, reason, want string
+	}{
+		{TaxCategoryReverseCharge, "", "Übergang der Steuerschuld auf den Leistungsempfänger (Reverse Charge)"},
+		{TaxCategoryIntraCommunity, "", "Steuerfreie innergemeinschaftliche Lieferung gemäß Art. 6 Abs. 1 UStG 1994"},
+		{TaxCategoryExport, "", "Steuerfreie Ausfuhrlieferung gemäß § 7 UStG 1994"},
+		{TaxCategoryExempt, "", ""},
+		{TaxCategoryExport, "Ausfuhr in die Schweiz", "Ausfuhr in die Schweiz"},
+		{TaxCategoryStandard, "steuerfrei", ""},
+		{TaxCategoryZeroRated, "", ""},
+	}
+	for _, tt := range tests {
+		if got := taxExemptionReason(tt.category, tt.reason); got != tt.want {
+			t.Errorf("taxExemptionReason(%q, %q) = %q, want %q", tt.category, tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestTaxExemptionOutput(t *testing.T) {
+	const exemptReason = "Steuerfrei gemäß § 6 Abs. 1 Z 19 UStG 1994"
+	tests := []struct {
+		category string
+		reason   string
+		edit     func(inv *InvoiceJSON)
+		wantText string
+		wantCode string // VATEX code in UBL and CII; none for E
+	}{
+		{TaxCategoryReverseCharge, "", func(inv *InvoiceJSON) {}, defaultTaxExemptionReasons[TaxCategoryReverseCharge], "VATEX-EU-AE"},
+		{TaxCategoryIntraCommunity, "", func(inv *InvoiceJSON) {
+			inv.Recipient.VATID = "DE136695976"
+			inv.Recipient.Address.Country = "DE"
+		}, defaultTaxExemptionReasons[TaxCategoryIntraCommunity], "VATEX-EU-IC"},
+		{TaxCategoryExport, "", func(inv *InvoiceJSON) { inv.Recipient.Address.Country = "CH" }, defaultTaxExemptionReasons[TaxCategoryExport], "VATEX-EU-G"},
+		{TaxCategoryExempt, exemptReason, func(inv *InvoiceJSON) {}, exemptReason, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			inv := readTestInvoice(t, "test_invoice_small.json")
+			inv.Items[0].TaxRate = 0
+			inv.Items[0].TaxCategory = tt.category
+			inv.Items[0].TaxExemptionReason = tt.reason
+			tt.edit(&inv)
+			if err := validateInvoice(inv); err != nil {
+				t.Fatal(err)
+			}
+
+			outputs := map[string][]string{
+				"6.1": {
+					`<TaxPercent TaxCategoryCode="` + tt.category + `">0</TaxPercent>`,
+					"<TaxAmount>0.00</TaxAmount>\n      <Comment>" + tt.wantText + "</Comment>",
+				},
+				"5.0": {
+					`<VATItem>
+        <TaxedAmount>4500.00</TaxedAmount>
+        <TaxExemption TaxExemptionCode="` + tt.category + `">` + tt.wantText + `</TaxExemption>`,
+				},
+			}
+			for version, wants := range outputs {
+				doc, err := TransformToEbInterfaceVersion(inv, version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := ValidateEbInterface(doc); err != nil {
+					t.Errorf("%s schema: %v", version, err)
+				}
+				for _, want := range wants {
+					if !strings.Contains(string(doc), want) {
+						t.Errorf("%s is missing %s", version, want)
+					}
+				}
+			}
+
+			ubl, err := TransformToUBL(inv, peppolBillingCustomizationID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cii, err := TransformToCII(inv, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checks := []struct {
+				format, doc, code, reason string
+			}{
+				{"UBL", string(ubl), "<cbc:TaxExemptionReasonCode>" + tt.wantCode + "</cbc:TaxExemptionReasonCode>", "<cbc:TaxExemptionReason>" + tt.wantText + "</cbc:TaxExemptionReason>"},
+				{"CII", string(cii), "<ram:ExemptionReasonCode>" + tt.wantCode + "</ram:ExemptionReasonCode>", "<ram:ExemptionReason>" + tt.wantText + "</ram:ExemptionReason>"},
+			}
+			for _, c := range checks {
+				if !strings.Contains(c.doc, c.reason) {
+					t.Errorf("%s is missing %s", c.format, c.reason)
+				}
+				hasCode := strings.Contains(c.doc, "ExemptionReasonCode>")
+				switch {
+				case tt.wantCode == "" && hasCode:
+					t.Errorf("%s states a VATEX code for category %s", c.format, tt.category)
+				case tt.wantCode != "" && !strings.Contains(c.doc, c.code):
+					t.Errorf("%s is missing %s", c.format, c.code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// lineTotals holds the computed amounts of a single line item.
+// Quantity and amounts are signed according to the document type.
+type lineTotals struct {
+	Quantity           decimal.Decimal
+	UnitCode           string          // UN/ECE Rec 20
+	UnitPrice          decimal.Decimal // EUR, unsigned, up to 4 decimals
+	BaseCts            int64           // Quantity x unit price rounded to the cent, before reductions and surcharges
+	Adjustments        []adjustmentTotals
+	NetCts             int64
+	TaxCts             int64 // Tax of this line alone; bucket rounding does not sum it
+	TaxRate            float64
+	TaxCategory        string
+	TaxExemptionReason string // Legal note of untaxed categories
+}
+
+// adjustmentTotals is a computed reduction or surcharge on a line or on the
+// whole document. BaseCts and AmountCts are signed according to the document
+// type like all amounts; whether the amount lowers or raises the taxable
+// amount follows from Surcharge.
+type adjustmentTotals struct {
+	Surcharge  bool
+	Percentage float64 // 0 for fixed amounts
+	BaseCts    int64
+	AmountCts  int64
+	Reason     string
+
+	// Document level adjustments apply to one tax bucket.
+	TaxRate            float64
+	TaxCategory        string
+	TaxExemptionReason string
+	TaxCts             int64
+
+	// Advance is set for the deduction of an advance invoice by a final
+	// settlement; its tax is the invoiced one, not recomputed.
+	Advance *AdvanceInvoiceJSON
+}
+
+// effectCts returns the change of the taxable amount caused by the adjustment.
+func (a adjustmentTotals) effectCts() int64 {
+	if a.Surcharge {
+		return a.AmountCts
+	}
+	return -a.AmountCts
+}
+
+// taxBucket aggregates all lines sharing a tax category and rate.
+type taxBucket struct {
+	Rate            float64
+	Category        string
+	ExemptionReason string // Shared by all items of an untaxed category
+	TaxableCts      int64
+	TaxCts          int64
+}
+
+// invoiceTotals is the output-format independent result of the invoice
+// arithmetic. Every renderer (XML versions and formats) builds on it so that
+// all outputs carry identical amounts.
+type invoiceTotals struct {
+	Lines        []lineTotals
+	Adjustments  []adjustmentTotals // Document level reductions and surcharges
+	Buckets      []taxBucket        // Sorted by descending rate, then category
+	LineNetCts   int64              // Sum of line amounts
+	ReductionCts int64              // Sum of document level reductions
+	SurchargeCts int64              // Sum of document level surcharges
+	NetCts       int64              // Taxable total: LineNetCts - ReductionCts + SurchargeCts
+	TaxCts       int64              // Sum of the bucket taxes
+	GrossCts     int64
+	PrepaidCts   int64               // Sum of prepayments
+	PayableCts   int64               // GrossCts - PrepaidCts
+	PaymentTerms *paymentTermsTotals // nil without payment_terms
+	VATRounding  string              // Strategy the taxes were rounded with
+}
+
+// paymentTermsTotals holds the resolved due date and Skonto tiers.
+type paymentTermsTotals struct {
+	DueDate string // ISO-8601
+	Skonto  []skontoTotals
+}
+
+// skontoTotals is a Skonto tier; the discount refers to the payable amount.
+type skontoTotals struct {
+	PaymentDate string // ISO-8601
+	Percentage  float64
+	BaseCts     int64
+	AmountCts   int64
+}
+
+// computeTotals performs the cent based arithmetic for an invoice.
+// With line rounding, tax is rounded per line and per document level
+// adjustment and summed per tax bucket; with bucket rounding it is rounded
+// once on each bucket's taxable amount. Either way the invoice tax is the sum
+// of the bucket taxes.
+func computeTotals(inv InvoiceJSON) invoiceTotals {
+	t := invoiceTotals{VATRounding: vatRounding(inv)}
+
+	// Credit memos and cancellations carry negative quantities and amounts;
+	// unit prices stay positive so that LineItemAmount = Quantity * UnitPrice holds.
+	sign := documentSign(inv)
+
+	buckets := map[taxBucketKey]*taxBucket{}
+	bucket := func(rate float64, category string) *taxBucket {
+		key := taxBucketKey{rate: rate, category: category}
+		b := buckets[key]
+		if b == nil {
+			b = &taxBucket{Rate: rate, Category: category}
+			buckets[key] = b
+		}
+		return b
+	}
+
+	for _, li := range inv.Items {
+		quantity := li.Quantity.Mul(decimal.NewFromInt(sign))
+		unitPrice := li.unitPrice()
+		baseCts := decimalToCents(quantity.Mul(unitPrice))
+		lineNetCts := baseCts
+		var adjustments []adjustmentTotals
+		for _, a := range li.Adjustments {
+			at := computeAdjustment(a, baseCts, sign)
+			adjustments = append(adjustments, at)
+			lineNetCts += at.effectCts()
+		}
+		taxCts := percentOf(lineNetCts, li.TaxRate)
+		category := taxCategory(li.TaxCategory, li.TaxRate)
+		reason := taxExemptionReason(category, li.TaxExemptionReason)
+
+		t.Lines = append(t.Lines, lineTotals{
+			Quantity:           quantity,
+			UnitCode:           unitCode(li.Unit),
+			UnitPrice:          unitPrice,
+			BaseCts:            baseCts,
+			Adjustments:        adjustments,
+			NetCts:             lineNetCts,
+			TaxCts:             taxCts,
+			TaxRate:            li.TaxRate,
+			TaxCategory:        category,
+			TaxExemptionReason: reason,
+		})
+		t.LineNetCts += lineNetCts
+
+		b := bucket(li.TaxRate, category)
+		b.ExemptionReason = reason
+		b.TaxableCts += lineNetCts
+		b.TaxCts += taxCts
+	}
+
+	// Document level adjustments apply to the line amounts of their tax rate;
+	// percentages all refer to that sum, they do not compound.
+	lineBases := map[taxBucketKey]int64{}
+	for key, b := range buckets {
+		lineBases[key] = b.TaxableCts
+	}
+	for _, a := range inv.Adjustments {
+		var rate float64
+		if a.TaxRate != nil {
+			rate = *a.TaxRate
+		}
+		category := taxCategory(a.TaxCategory, rate)
+		b := bucket(rate, category)
+		at := computeAdjustment(a, lineBases[taxBucketKey{rate: rate, category: category}], sign)
+		at.TaxRate = rate
+		at.TaxCategory = category
+		at.TaxExemptionReason = b.ExemptionReason
+		at.TaxCts = percentOf(at.effectCts(), rate)
+		t.Adjustments = append(t.Adjustments, at)
+
+		if at.Surcharge {
+			t.SurchargeCts += at.AmountCts
+		} else {
+			t.ReductionCts += at.AmountCts
+		}
+		b.TaxableCts += at.effectCts()
+		b.TaxCts += at.TaxCts
+	}
+
+	if t.VATRounding == VATRoundingBucket {
+		for _, b := range buckets {
+			b.TaxCts = percentOf(b.TaxableCts, b.Rate)
+		}
+	}
+
+	// Final settlements deduct each advance invoice with the net and tax it stated.
+	for i := range inv.AdvanceInvoices {
+		ai := &inv.AdvanceInvoices[i]
+		for _, tax := range ai.Taxes {
+			category := taxCategory(tax.TaxCategory, tax.TaxRate)
+			b := bucket(tax.TaxRate, category)
+			at := adjustmentTotals{
+				BaseCts:            lineBases[taxBucketKey{rate: tax.TaxRate, category: category}],
+				AmountCts:          sign * tax.NetCents,
+				Reason:             advanceComment(*ai, tax.TaxCents),
+				TaxRate:            tax.TaxRate,
+				TaxCategory:        category,
+				TaxExemptionReason: b.ExemptionReason,
+				TaxCts:             -sign * tax.TaxCents,
+				Advance:            ai,
+			}
+			t.Adjustments = append(t.Adjustments, at)
+			t.ReductionCts += at.AmountCts
+			b.TaxableCts += at.effectCts()
+			b.TaxCts += at.TaxCts
+		}
+	}
+
+	for _, b := range buckets {
+		t.Buckets = append(t.Buckets, *b)
+		t.TaxCts += b.TaxCts
+	}
+	sort.Slice(t.Buckets, func(i, j int) bool {
+		if t.Buckets[i].Rate != t.Buckets[j].Rate {
+			return t.Buckets[i].Rate > t.Buckets[j].Rate
+		}
+		return t.Buckets[i].Category < t.Buckets[j].Category
+	})
+
+	t.NetCts = t.LineNetCts - t.ReductionCts + t.SurchargeCts
+	t.GrossCts = t.NetCts + t.TaxCts
+	for _, pp := range inv.Prepayments {
+		t.PrepaidCts += pp.AmountCents
+	}
+	t.PayableCts = t.GrossCts - t.PrepaidCts
+	t.PaymentTerms = computePaymentTerms(inv, t.PayableCts)
+	return t
+}
+
+// computePaymentTerms resolves deadlines given in days after invoice_date and
+// computes the Skonto amounts from payableCts.
+func computePaymentTerms(inv InvoiceJSON, payableCts int64) *paymentTermsTotals {
+	pt := inv.PaymentTerms
+	if pt == nil {
+		return nil
+	}
+	terms := &paymentTermsTotals{DueDate: paymentDate(inv.InvoiceDate, pt.DueDate, pt.NetDays)}
+	for _, sk := range pt.Skonto {
+		terms.Skonto = append(terms.Skonto, skontoTotals{
+			PaymentDate: paymentDate(inv.InvoiceDate, sk.Date, sk.Days),
+			Percentage:  sk.Percentage,
+			BaseCts:     payableCts,
+			AmountCts:   skontoAmount(payableCts, sk.Percentage),
+		})
+	}
+	return terms
+}
+
+// unitCode resolves a validated unit to its UN/ECE Rec 20 code.
+func unitCode(unit string) string {
+	code, _ := resolveUnitCode(unit)
+	return code
+}
+
+// advanceComment describes the deduction of an advance invoice, e.g.
+// "Anzahlungsrechnung AR-1 vom 2025-12-01, USt 200.00 EUR".
+func advanceComment(ai AdvanceInvoiceJSON, taxCts int64) string {
+	return fmt.Sprintf("Anzahlungsrechnung %s vom %s, USt %s %s",
+		ai.InvoiceNumber, ai.InvoiceDate, formatCentsAsDecimal(taxCts), invoiceCurrency)
+}
+
+// skontoAmount returns the discount of a Skonto tier, rounded to the cent.
+func skontoAmount(payableCts int64, percentage float64) int64 {
+	return percentOf(payableCts, percentage)
+}
+
+// percentOf returns percentage % of cts, rounded half away from zero to the
+// cent. The arithmetic is exact; rates such as 5.5 are taken as written.
+func percentOf(cts int64, percentage float64) int64 {
+	return decimalToCents(decimal.New(cts, -2).Mul(decimal.NewFromFloat(percentage)).Shift(-2))
+}
+
+// decimalToCents rounds an EUR amount half away from zero to whole cents.
+func decimalToCents(eur decimal.Decimal) int64 {
+	return eur.Shift(2).Round(0).IntPart()
+}
+
+// computeAdjustment evaluates a reduction or surcharge against baseCts.
+// Percentages are rounded to the cent; fixed amounts take the document sign.
+func computeAdjustment(a AdjustmentJSON, baseCts, sign int64) adjustmentTotals {
+	at := adjustmentTotals{
+		Surcharge:  a.Type == AdjustmentSurcharge,
+		Percentage: a.Percentage,
+		BaseCts:    baseCts,
+		AmountCts:  sign * a.AmountCents,
+		Reason:     a.Reason,
+	}
+	if a.Percentage != 0 {
+		at.AmountCts = percentOf(baseCts, a.Percentage)
+	}
+	return at
+}
+
+type taxBucketKey struct {
+	rate     float64
+	category string
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// invoiceWithItems returns the small sample invoice with the given items.
+func invoiceWithItems(t *testing.T, items ...LineItemJSON) InvoiceJSON {
+	t.Helper()
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.Items = items
+	return inv
+}
+
+func quantity(s string) DecimalJSON {
+	return DecimalJSON{decimal.RequireFromString(s)}
+}
+
+func unitPrice(s string) *DecimalJSON {
+	return &DecimalJSON{decimal.RequireFromString(s)}
+}
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		cts        int64
+		percentage float64
+		want       int64
+	}{
+		{10000, 20, 2000},
+		{10000, 5.5, 550},
+		{33333, 13, 4333}, // 43.3329
+		{3, 20, 1},        // 0.6 cents
+		{2, 20, 0},        // 0.4 cents
+		{5, 10, 1},        // 0.5 cents, half away from zero
+		{-5, 10, -1},
+		{-3, 20, -1},
+		{12345, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := percentOf(tt.cts, tt.percentage); got != tt.want {
+			t.Errorf("percentOf(%d, %v) = %d, want %d", tt.cts, tt.percentage, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalToCents(t *testing.T) {
+	tests := []struct {
+		eur  string
+		want int64
+	}{
+		{"1.00", 100},
+		{"0.005", 1},
+		{"0.0049", 0},
+		{"-0.005", -1},
+		{"1.2345", 123},
+		{"0.009999", 1},
+	}
+	for _, tt := range tests {
+		if got := decimalToCents(decimal.RequireFromString(tt.eur)); got != tt.want {
+			t.Errorf("decimalToCents(%s) = %d, want %d", tt.eur, got, tt.want)
+		}
+	}
+}
+
+func TestComputeTotalsLineAmounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		item       LineItemJSON
+		wantBase   int64
+		wantTax    int64
+		wantPrice  string
+		wantAmount string
+	}{
+		{"cents", LineItemJSON{Quantity: quantity("3"), UnitPriceCents: 12000, TaxRate: 20}, 36000, 7200, "120", "360.00"},
+		{"decimal quantity", LineItemJSON{Quantity: quantity("7.5"), UnitPriceCents: 9500, TaxRate: 20}, 71250, 14250, "95", "712.50"},
+		{"sub-cent unit price", LineItemJSON{Quantity: quantity("1000"), UnitPrice: unitPrice("0.1234"), TaxRate: 20}, 12340, 2468, "0.1234", "123.40"},
+		{"rounded to the cent", LineItemJSON{Quantity: quantity("0.3333"), UnitPrice: unitPrice("0.03"), TaxRate: 20}, 1, 0, "0.03", "0.01"},
+		{"half cent", LineItemJSON{Quantity: quantity("1.5"), UnitPrice: unitPrice("0.0333"), TaxRate: 10}, 5, 1, "0.0333", "0.05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.item.Description = tt.name
+			totals := computeTotals(invoiceWithItems(t, tt.item))
+			line := totals.Lines[0]
+			if line.BaseCts != tt.wantBase || line.NetCts != tt.wantBase || line.TaxCts != tt.wantTax {
+				t.Errorf("base %d, net %d, tax %d; want %d, %d, %d", line.BaseCts, line.NetCts, line.TaxCts, tt.wantBase, tt.wantBase, tt.wantTax)
+			}
+			if line.UnitPrice.String() != tt.wantPrice {
+				t.Errorf("unit price %s, want %s", line.UnitPrice, tt.wantPrice)
+			}
+			if got := formatCentsAsDecimal(line.NetCts); got != tt.wantAmount {
+				t.Errorf("amount %s, want %s", got, tt.wantAmount)
+			}
+			if totals.GrossCts != tt.wantBase+tt.wantTax {
+				t.Errorf("gross %d, want %d", totals.GrossCts, tt.wantBase+tt.wantTax)
+			}
+		})
+	}
+}
+
+func TestValidateInvoiceQuantityAndPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    LineItemJSON
+		wantErr string
+	}{
+		{"four decimals", LineItemJSON{Quantity: quantity("0.1234"), UnitPrice: unitPrice("1.2345")}, ""},
+		{"zero quantity", LineItemJSON{Quantity: quantity("0"), UnitPriceCents: 100}, "quantity must be > 0"},
+		{"five quantity decimals", LineItemJSON{Quantity: quantity("0.12345"), UnitPriceCents: 100}, "quantity must have at most 4 decimals"},
+		{"five price decimals", LineItemJSON{Quantity: quantity("1"), UnitPrice: unitPrice("0.12345")}, "unit_price must be >= 0 with at most 4 decimals"},
+		{"negative price", LineItemJSON{Quantity: quantity("1"), UnitPrice: unitPrice("-1")}, "unit_price must be >= 0"},
+		{"both prices", LineItemJSON{Quantity: quantity("1"), UnitPriceCents: 100, UnitPrice: unitPrice("1")}, "only one of unit_price and unit_price_cents"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.item.Description = tt.name
+			tt.item.TaxRate = 20
+			err := validateInvoice(invoiceWithItems(t, tt.item))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestComputeTotalsVATRounding(t *testing.T) {
+	item := func(cts int64, rate float64) LineItemJSON {
+		return LineItemJSON{Description: "Kleinteil", Quantity: quantity("1"), UnitPriceCents: cts, TaxRate: rate}
+	}
+	rate20 := 20.0
+	tests := []struct {
+		name        string
+		items       []LineItemJSON
+		adjustments []AdjustmentJSON
+		wantLine    int64
+		wantBucket  int64
+	}{
+		{"single line", []LineItemJSON{item(1000, 20)}, nil, 200, 200},
+		// 3 x 0.6 cents: 3 x 1 per line, 1.8 on the bucket.
+		{"three small lines", []LineItemJSON{item(3, 20), item(3, 20), item(3, 20)}, nil, 3, 2},
+		// 20 %: 3 x 1 or 2 (1.8); 10 %: 3 x 1 (0.5) or 2 (1.5).
+		{"two rates", []LineItemJSON{item(3, 20), item(3, 20), item(3, 20), item(5, 10), item(5, 10), item(5, 10)}, nil, 6, 4},
+		// The reduction's -0.2 cents round to 0 on their own; the bucket has 8 cents.
+		{"document reduction", []LineItemJSON{item(3, 20), item(3, 20), item(3, 20)},
+			[]AdjustmentJSON{{Type: AdjustmentReduction, AmountCents: 1, TaxRate: &rate20}}, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, strategy := range []string{VATRoundingLine, VATRoundingBucket} {
+				inv := invoiceWithItems(t, tt.items...)
+				inv.Adjustments = tt.adjustments
+				inv.VATRounding = strategy
+				totals := computeTotals(inv)
+
+				want := tt.wantLine
+				if strategy == VATRoundingBucket {
+					want = tt.wantBucket
+				}
+				if totals.TaxCts != want || totals.VATRounding != strategy {
+					t.Errorf("%s: tax %d (%s), want %d", strategy, totals.TaxCts, totals.VATRounding, want)
+				}
+				var bucketTax int64
+				for _, b := range totals.Buckets {
+					bucketTax += b.TaxCts
+					if strategy == VATRoundingBucket && b.TaxCts != percentOf(b.TaxableCts, b.Rate) {
+						t.Errorf("%s: bucket %v%% tax %d on %d", strategy, b.Rate, b.TaxCts, b.TaxableCts)
+					}
+				}
+				if bucketTax != totals.TaxCts || totals.GrossCts != totals.NetCts+totals.TaxCts {
+					t.Errorf("%s: buckets %d, tax %d, net %d, gross %d", strategy, bucketTax, totals.TaxCts, totals.NetCts, totals.GrossCts)
+				}
+				// Line taxes are those of the line alone under both strategies.
+				for i, line := range totals.Lines {
+					if line.TaxCts != percentOf(line.NetCts, line.TaxRate) {
+						t.Errorf("%s: line %d tax %d", strategy, i, line.TaxCts)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestValidateVATRounding(t *testing.T) {
+	for _, strategy := range []string{"", VATRoundingLine, VATRoundingBucket} {
+		if err := validateVATRounding(strategy); err != nil {
+			t.Errorf("validateVATRounding(%q) = %v", strategy, err)
+		}
+	}
+	inv := readGoldenInvoice(t)
+	inv.VATRounding = "invoice"
+	if err := validateInvoice(inv); err == nil || !strings.Contains(err.Error(), `vat_rounding "invoice" is not supported`) {
+		t.Errorf("got %v, want an unsupported vat_rounding error", err)
+	}
+	if got := vatRounding(readGoldenInvoice(t)); got != DefaultVATRounding {
+		t.Errorf("default rounding %q, want %q", got, DefaultVATRounding)
+	}
+}
+
+func TestComputeTotalsDocumentSign(t *testing.T) {
+	rate20 := 20.0
+	for _, docType := range []string{DocTypeInvoice, DocTypeCreditMemo, DocTypeCancellation} {
+		t.Run(docType, func(t *testing.T) {
+			inv := invoiceWithItems(t, LineItemJSON{
+				Description:    "Beratung",
+				Quantity:       quantity("2.5"),
+				UnitPriceCents: 10000,
+				TaxRate:        20,
+				Adjustments:    []AdjustmentJSON{{Type: AdjustmentReduction, Percentage: 10}},
+			})
+			inv.Adjustments = []AdjustmentJSON{{Type: AdjustmentSurcharge, AmountCents: 500, TaxRate: &rate20}}
+			inv.DocumentType = docType
+			totals := computeTotals(inv)
+
+			sign := int64(1)
+			if docType != DocTypeInvoice {
+				sign = -1
+			}
+			line := totals.Lines[0]
+			if !line.Quantity.Equal(decimal.RequireFromString("2.5").Mul(decimal.NewFromInt(sign))) || line.UnitPrice.Sign() != 1 {
+				t.Errorf("quantity %s, unit price %s", line.Quantity, line.UnitPrice)
+			}
+			// 250.00 - 25.00 line reduction + 5.00 surcharge = 230.00 net, 46.00 tax.
+			got := []int64{line.BaseCts, line.Adjustments[0].AmountCts, totals.Adjustments[0].AmountCts, totals.NetCts, totals.TaxCts, totals.GrossCts, totals.PayableCts}
+			want := []int64{25000, 2500, 500, 23000, 4600, 27600, 27600}
+			for i := range want {
+				if got[i] != sign*want[i] {
+					t.Errorf("amounts %v, want %v times %d", got, want, sign)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestComputeTotalsFinalSettlement(t *testing.T) {
+	// 4,500.00 EUR net at 20 %; 1,500.00 EUR were invoiced in advance.
+	inv := readTestInvoice(t, "test_invoice_small.json")
+	inv.DocumentType = DocTypeFinalSettlement
+	inv.AdvanceInvoices = []AdvanceInvoiceJSON{{
+		InvoiceNumber: "AR-1",
+		InvoiceDate:   "2025-12-01",
+		Taxes:         []AdvanceTaxJSON{{TaxRate: 20, NetCents: 150000, TaxCents: 30000}},
+	}}
+	inv.Prepayments = []PrepaymentJSON{{AmountCents: 50000}}
+	totals := computeTotals(inv)
+
+	if len(totals.Adjustments) != 1 {
+		t.Fatalf("adjustments %+v", totals.Adjustments)
+	}
+	a := totals.Adjustments[0]
+	if a.Surcharge || a.Advance == nil || a.AmountCts != 150000 || a.TaxCts != -30000 || a.BaseCts != 450000 {
+		t.Errorf("advance deduction %+v", a)
+	}
+	if want := "Anzahlungsrechnung AR-1 vom 2025-12-01, USt 300.00 EUR"; a.Reason != want {
+		t.Errorf("reason %q, want %q", a.Reason, want)
+	}
+	got := []int64{totals.LineNetCts, totals.ReductionCts, totals.NetCts, totals.TaxCts, totals.GrossCts, totals.PrepaidCts, totals.PayableCts}
+	want := []int64{450000, 150000, 300000, 60000, 360000, 50000, 310000}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("line net, reductions, net, tax, gross, prepaid, payable = %v, want %v", got, want)
+		}
+	}
+	if b := totals.Buckets[0]; len(totals.Buckets) != 1 || b.TaxableCts != 300000 || b.TaxCts != 60000 {
+		t.Errorf("buckets %+v", totals.Buckets)
+	}
+}
+
+func TestComputeAdjustment(t *testing.T) {
+	tests := []struct {
+		name          string
+		adjustment    AdjustmentJSON
+		baseCts, sign int64
+		wantCts       int64
+		wantEffectCts int64
+	}{
+		{"percentage", AdjustmentJSON{Type: AdjustmentReduction, Percentage: 10}, 25000, 1, 2500, -2500},
+		{"percentage rounds half up", AdjustmentJSON{Type: AdjustmentReduction, Percentage: 2.5}, 12345, 1, 309, -309}, // 308.625
+		{"percentage rounds down", AdjustmentJSON{Type: AdjustmentSurcharge, Percentage: 10}, 3333, 1, 333, 333},       // 333.3
+		{"credit memo percentage", AdjustmentJSON{Type: AdjustmentReduction, Percentage: 2.5}, -12345, -1, -309, 309},
+		{"amount", AdjustmentJSON{Type: AdjustmentSurcharge, AmountCents: 500}, 25000, 1, 500, 500},
+		{"credit memo amount", AdjustmentJSON{Type: AdjustmentSurcharge, AmountCents: 500}, -25000, -1, -500, -500},
+		{"credit memo reduction", AdjustmentJSON{Type: AdjustmentReduction, AmountCents: 500}, -25000, -1, -500, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := computeAdjustment(tt.adjustment, tt.baseCts, tt.sign)
+			if at.AmountCts != tt.wantCts || at.effectCts() != tt.wantEffectCts || at.BaseCts != tt.baseCts {
+				t.Errorf("amount %d, effect %d, base %d; want %d, %d, %d", at.AmountCts, at.effectCts(), at.BaseCts, tt.wantCts, tt.wantEffectCts, tt.baseCts)
+			}
+			if at.Surcharge != (tt.adjustment.Type == AdjustmentSurcharge) || at.Percentage != tt.adjustment.Percentage {
+				t.Errorf("surcharge %v, percentage %v", at.Surcharge, at.Percentage)
+			}
+		})
+	}
+}
+
+func TestComputeTotalsAdjustmentBuckets(t *testing.T) {
+	rate20, rate10 := 20.0, 10.0
+	inv := invoiceWithItems(t,
+		LineItemJSON{Description: "Beratung", Quantity: quantity("1"), UnitPriceCents: 100000, TaxRate: 20},
+		LineItemJSON{Description: "Schulung", Quantity: quantity("1"), UnitPriceCents: 50000, TaxRate: 20},
+		LineItemJSON{Description: "Buch", Quantity: quantity("1"), UnitPriceCents: 3000, TaxRate: 10},
+	)
+	// Both percentages refer to the 1,500.00 at 20 %; they do not compound.
+	inv.Adjustments = []AdjustmentJSON{
+		{Type: AdjustmentReduction, Percentage: 10, TaxRate: &rate20},
+		{Type: AdjustmentReduction, Percentage: 5, TaxRate: &rate20},
+		{Type: AdjustmentSurcharge, AmountCents: 450, TaxRate: &rate10, Reason: "Versand"},
+	}
+	totals := computeTotals(inv)
+
+	tests := []struct {
+		base, amount, tax int64
+		rate              float64
+	}{
+		{150000, 15000, -3000, 20},
+		{150000, 7500, -1500, 20},
+		{3000, 450, 45, 10},
+	}
+	for i, tt := range tests {
+		a := totals.Adjustments[i]
+		if a.BaseCts != tt.base || a.AmountCts != tt.amount || a.TaxCts != tt.tax || a.TaxRate != tt.rate || a.TaxCategory != TaxCategoryStandard {
+			t.Errorf("adjustment %d: %+v", i, a)
+		}
+	}
+	if got := totals.Adjustments[2].Reason; got != "Versand" {
+		t.Errorf("reason %q", got)
+	}
+
+	// 20 %: 1,500.00 - 225.00 = 1,275.00, tax 255.00; 10 %: 30.00 + 4.50 = 34.50, tax 3.45.
+	want := []taxBucket{
+		{Rate: 20, Category: TaxCategoryStandard, TaxableCts: 127500, TaxCts: 25500},
+		{Rate: 10, Category: TaxCategoryStandard, TaxableCts: 3450, TaxCts: 345},
+	}
+	if len(totals.Buckets) != len(want) {
+		t.Fatalf("buckets %+v", totals.Buckets)
+	}
+	for i, b := range want {
+		if totals.Buckets[i] != b {
+			t.Errorf("bucket %d = %+v, want %+v", i, totals.Buckets[i], b)
+		}
+	}
+	if totals.ReductionCts != 22500 || totals.SurchargeCts != 450 || totals.NetCts != 130950 || totals.TaxCts != 25845 {
+		t.Errorf("reductions %d, surcharges %d, net %d, tax %d", totals.ReductionCts, totals.SurchargeCts, totals.NetCts, totals.TaxCts)
+	}
+}
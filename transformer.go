@@ -3,140 +3,435 @@ package main
 import (
 	"encoding/xml"
 	"fmt"
-	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
+const (
+	generatingSystem = "austrian-invoice-microservice"
+	invoiceCurrency  = "EUR"
+
+	// DefaultEbInterfaceVersion is used when a request does not select a version.
+	DefaultEbInterfaceVersion = "6.1"
+)
+
+// ebInterfaceVersion describes one supported ebInterface release. Each
+// version lives in its own ebinterface_<version>.go file and registers itself
+// from init, so supporting a new release means adding a file.
+type ebInterfaceVersion struct {
+	Version    string
+	Namespace  string
+	SchemaFile string
+	// Build maps the invoice and its computed totals to the version specific document.
+	Build func(inv InvoiceJSON, t invoiceTotals) any
+}
+
+var ebInterfaceVersions = map[string]ebInterfaceVersion{}
+
+func registerEbInterfaceVersion(v ebInterfaceVersion) {
+	ebInterfaceVersions[v.Version] = v
+}
+
+// supportedEbInterfaceVersions lists the registered versions in ascending order.
+func supportedEbInterfaceVersions() []string {
+	versions := make([]string, 0, len(ebInterfaceVersions))
+	for v := range ebInterfaceVersions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// lookupEbInterfaceVersion returns the registered version, defaulting to DefaultEbInterfaceVersion.
+func lookupEbInterfaceVersion(version string) (ebInterfaceVersion, error) {
+	if version == "" {
+		version = DefaultEbInterfaceVersion
+	}
+	v, ok := ebInterfaceVersions[version]
+	if !ok {
+		return ebInterfaceVersion{}, fmt.Errorf("unsupported ebInterface version %q (supported: %s)", version, strings.Join(supportedEbInterfaceVersions(), ", "))
+	}
+	return v, nil
+}
+
+// lookupEbInterfaceNamespace returns the registered version using namespace.
+func lookupEbInterfaceNamespace(namespace string) (ebInterfaceVersion, bool) {
+	for _, v := range ebInterfaceVersions {
+		if v.Namespace == namespace {
+			return v, true
+		}
+	}
+	return ebInterfaceVersion{}, false
+}
+
 // formatCentsAsDecimal converts cents (int64) to a decimal string with 2 decimal places.
 // Example: 12000 -> "120.00"
 func formatCentsAsDecimal(cents int64) string {
-	return fmt.Sprintf("%.2f", float64(cents)/100.0)
+	return decimal.New(cents, -2).StringFixed(2)
+}
+
+// formatQuantity renders a quantity without trailing zeros, e.g. 7.50 -> "7.5".
+func formatQuantity(q decimal.Decimal) string {
+	return q.String()
+}
+
+// formatPrice renders a unit price with 2 to 4 decimals, as many as needed.
+// Example: 0.1234 -> "0.1234", 12 -> "12.00"
+func formatPrice(price decimal.Decimal) string {
+	places := int32(2)
+	for places < maxUnitPriceDecimals && !price.Equal(price.Truncate(places)) {
+		places++
+	}
+	return price.StringFixed(places)
+}
+
+// formatRate renders a tax rate without trailing zeros, e.g. 20 -> "20", 5.5 -> "5.5".
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
 }
 
 // TransformToEbInterface maps the JSON invoice into a minimal ebInterface 6.1 XML document.
 func TransformToEbInterface(inv InvoiceJSON) ([]byte, error) {
-	const currency = "EUR"
-
-	items := make([]EbItem, 0, len(inv.Items))
-	var totalNetCts int64
-	var totalTaxCts int64
-	taxBuckets := map[float64]struct {
-		taxableCts int64
-		taxCts     int64
-	}{}
-
-	for i, li := range inv.Items {
-		lineNetCts := li.UnitPriceCents * li.Quantity
-		taxRate := li.TaxRate
-		taxCts := int64(math.Round(float64(lineNetCts) * taxRate / 100.0))
-
-		totalNetCts += lineNetCts
-		totalTaxCts += taxCts
-
-		b := taxBuckets[taxRate]
-		b.taxableCts += lineNetCts
-		b.taxCts += taxCts
-		taxBuckets[taxRate] = b
-
-		item := EbItem{
-			Description: li.Description,
-			Quantity: EbQuantity{
-				Unit:  "C62", // default to pieces; can be adjusted per item later
-				Value: float64(li.Quantity),
-			},
-			UnitPrice: formatCentsAsDecimal(li.UnitPriceCents),
-			InvoiceRecipientsOrderReference: &EbOrderReferenceItem{
-				OrderID:            inv.Recipient.OrderID,
-				OrderPositionNumber: fmt.Sprintf("%d", i+1), // Position number (1-based)
-			},
-			TaxItem: EbTaxItem{
-				TaxableAmount: formatCentsAsDecimal(lineNetCts), // Net amount for the line (before tax)
-				TaxPercent: EbTaxPercent{
-					TaxCategoryCode: taxCategoryFromRate(taxRate),
-					Value:           taxRate,
-				},
-				// Note: TaxItem in Details does NOT have TaxAmount
-			},
-			LineItemAmount: formatCentsAsDecimal(lineNetCts), // Line item NET amount (before tax) - MUST come after TaxItem
-		}
-		items = append(items, item)
-	}
-
-	summary := make([]EbTaxItemSummary, 0, len(taxBuckets))
-	for rate, b := range taxBuckets {
-		summary = append(summary, EbTaxItemSummary{
-			TaxableAmount: formatCentsAsDecimal(b.taxableCts), // MUST be FIRST
-			TaxPercent: EbTaxPercent{
-				TaxCategoryCode: taxCategoryFromRate(rate),
-				Value:           rate,
-			}, // MUST be SECOND
-			TaxAmount: formatCentsAsDecimal(b.taxCts), // MUST be THIRD
-		})
+	return TransformToEbInterfaceVersion(inv, DefaultEbInterfaceVersion)
+}
+
+// TransformToEbInterfaceVersion maps the JSON invoice into an XML document of the given ebInterface version.
+func TransformToEbInterfaceVersion(inv InvoiceJSON, version string) ([]byte, error) {
+	v, err := lookupEbInterfaceVersion(version)
+	if err != nil {
+		return nil, err
 	}
 
-	totalGrossCts := totalNetCts + totalTaxCts
-
-	doc := EbInterfaceInvoice{
-		GeneratingSystem: "austrian-invoice-microservice",
-		DocumentType:     "Invoice",
-		InvoiceCurrency:  currency,
-		Language:         "de",
-		InvoiceNumber: inv.InvoiceNumber,
-		InvoiceDate:   inv.InvoiceDate,
-		Delivery: &EbDelivery{
-			Date:    inv.InvoiceDate, // Use invoice date as delivery date
-			Address: composeEbAddress(inv.Biller.Name, inv.Biller.Address),
-			Contact: EbContact{
-				Name:  getContactName(inv.Biller.ContactName, "Billing Department"),
-				Email: inv.Biller.Email,
-			},
-		},
-		Biller: EbBiller{
-			VATID:   inv.Biller.VATID,
-			Address: composeEbAddress(inv.Biller.Name, inv.Biller.Address),
-			Contact: EbContact{
-				Name:  getContactName(inv.Biller.ContactName, "Billing Department"),
-				Email: inv.Biller.Email,
-			},
-			InvoiceRecipientsBillerID: inv.Biller.BillerID,
-		},
-		InvoiceRecipient: EbRecipient{
-			VATID: inv.Recipient.VATID,
-			OrderReference: EbOrderReference{
-				OrderID: inv.Recipient.OrderID,
-			},
-			Address: composeEbAddress(inv.Recipient.Name, inv.Recipient.Address),
-			Contact: EbContact{
-				Name:  getContactName(inv.Recipient.ContactName, "Accounting"),
-				Email: inv.Recipient.Email,
-			},
-		},
-		Details: EbDetails{
-			ItemList: EbItemList{
-				Items: items,
-			},
+	doc := v.Build(inv, computeTotals(inv))
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal ebInterface %s: %w", v.Version, err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// -------- Version independent building blocks --------
+
+// buildEbDelivery maps a document or line level delivery; nil if the invoice
+// states none, so no Delivery element is emitted.
+func buildEbDelivery(inv InvoiceJSON, d *DeliveryJSON) *EbDelivery {
+	if d == nil {
+		return nil
+	}
+	delivery := &EbDelivery{Date: d.Date}
+	if d.isPeriod() {
+		delivery.Period = &EbPeriod{FromDate: d.FromDate, ToDate: d.ToDate}
+	}
+	if d.Address != nil {
+		addr := composeEbAddress(deliveryName(inv, *d), *d.Address)
+		delivery.Address = &addr
+	}
+	if d.ContactName != "" {
+		delivery.Contact = &EbContact{Name: d.ContactName, Email: d.Email}
+	}
+	return delivery
+}
+
+func buildEbBiller(inv InvoiceJSON) EbBiller {
+	return EbBiller{
+		VATID:                 inv.Biller.VATID,
+		FurtherIdentification: buildEbFurtherIdentifications(inv.Biller.FurtherIdentifications),
+		Address:               composeEbAddress(inv.Biller.Name, inv.Biller.Address),
+		Contact: EbContact{
+			Name:  getContactName(inv.Biller.ContactName, "Billing Department"),
+			Email: inv.Biller.Email,
 		},
-		Tax: EbTax{
-			TaxItems: summary, // Tax summary items with TaxableAmount, TaxPercent, TaxAmount
+		InvoiceRecipientsBillerID: inv.Biller.BillerID,
+	}
+}
+
+// ebNoVATID is the VATIdentificationNumber ebInterface prescribes for a
+// recipient without a VAT ID.
+const ebNoVATID = "00000000"
+
+func buildEbRecipient(inv InvoiceJSON) EbRecipient {
+	vatID := inv.Recipient.VATID
+	if vatID == "" {
+		vatID = ebNoVATID
+	}
+	r := EbRecipient{
+		VATID:                 vatID,
+		FurtherIdentification: buildEbFurtherIdentifications(inv.Recipient.FurtherIdentifications),
+		Address:               composeEbAddress(inv.Recipient.Name, inv.Recipient.Address),
+		Contact: EbContact{
+			Name:  getContactName(inv.Recipient.ContactName, "Accounting"),
+			Email: inv.Recipient.Email,
 		},
-		TotalGrossAmount: formatCentsAsDecimal(totalGrossCts), // Direct child of Invoice
-		PayableAmount:    formatCentsAsDecimal(totalGrossCts), // Direct child of Invoice
-		PaymentMethod: EbPaymentMethod{
-			UniversalBankTransaction: EbUniversalBankTransaction{
-				BeneficiaryAccount: EbBeneficiaryAccount{
-					BIC:              inv.Payment.BIC,              // MUST be FIRST
-					IBAN:             inv.Payment.IBAN,            // MUST be SECOND
-					BankAccountOwner: inv.Biller.Name,            // MUST be THIRD - Use biller name as account owner
-				},
+	}
+	// B2B invoices may come without an order reference
+	if inv.Recipient.OrderID != "" {
+		r.OrderReference = &EbOrderReference{OrderID: inv.Recipient.OrderID}
+	}
+	return r
+}
+
+// buildEbOrderingParty returns nil unless the invoice names an ordering party.
+func buildEbOrderingParty(inv InvoiceJSON) *EbOrderingParty {
+	op := inv.OrderingParty
+	if op == nil {
+		return nil
+	}
+	party := &EbOrderingParty{
+		VATID:                  op.VATID,
+		Address:                composeEbAddress(op.Name, op.Address),
+		BillersOrderingPartyID: op.CustomerID,
+	}
+	if op.ContactName != "" {
+		party.Contact = &EbContact{Name: op.ContactName, Email: op.Email}
+	}
+	return party
+}
+
+// buildEbFurtherIdentifications lists the further identifications of a party.
+func buildEbFurtherIdentifications(ids []FurtherIdentificationJSON) []EbFurtherIdentification {
+	var out []EbFurtherIdentification
+	for _, id := range ids {
+		out = append(out, EbFurtherIdentification{Type: id.Type, Value: id.Value})
+	}
+	return out
+}
+
+func buildEbPaymentMethod(inv InvoiceJSON) EbPaymentMethod {
+	return EbPaymentMethod{
+		UniversalBankTransaction: EbUniversalBankTransaction{
+			BeneficiaryAccount: EbBeneficiaryAccount{
+				BIC:              inv.Payment.BIC,  // MUST be FIRST
+				IBAN:             inv.Payment.IBAN, // MUST be SECOND
+				BankAccountOwner: inv.Biller.Name,  // MUST be THIRD - Use biller name as account owner
 			},
 		},
-		// Extensions removed - not part of official ebInterface 6.1 structure
 	}
+}
 
-	out, err := xml.MarshalIndent(doc, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("marshal ebInterface: %w", err)
+// buildEbPaymentConditions maps the resolved payment terms (follows PaymentMethod).
+func buildEbPaymentConditions(t invoiceTotals) *EbPaymentConditions {
+	if t.PaymentTerms == nil {
+		return nil
+	}
+	pc := &EbPaymentConditions{DueDate: t.PaymentTerms.DueDate}
+	for _, sk := range t.PaymentTerms.Skonto {
+		pc.Discount = append(pc.Discount, EbDiscount{
+			PaymentDate: sk.PaymentDate,
+			BaseAmount:  formatCentsAsDecimal(sk.BaseCts),
+			Percentage:  formatRate(sk.Percentage),
+			Amount:      formatCentsAsDecimal(sk.AmountCts),
+		})
+	}
+	return pc
+}
+
+// buildEbDocumentReferences returns the reference to the corrected invoice of a
+// credit memo (RelatedDocument) or cancellation (CancelledOriginalDocument).
+func buildEbDocumentReferences(inv InvoiceJSON) (*EbCancelledOriginalDocument, []EbRelatedDocument) {
+	ref := inv.OriginalInvoice
+	if ref == nil {
+		return nil, buildEbAdvanceReferences(inv)
+	}
+	switch documentType(inv) {
+	case DocTypeCancellation:
+		return &EbCancelledOriginalDocument{
+			InvoiceNumber: ref.InvoiceNumber,
+			InvoiceDate:   ref.InvoiceDate,
+			DocumentType:  "Invoice",
+			Comment:       ref.Comment,
+		}, nil
+	case DocTypeCreditMemo:
+		return nil, []EbRelatedDocument{{
+			InvoiceNumber: ref.InvoiceNumber,
+			InvoiceDate:   ref.InvoiceDate,
+			DocumentType:  "Invoice",
+			Comment:       ref.Comment,
+		}}
+	}
+	return nil, nil
+}
+
+// buildEbAdvanceReferences references the advance invoices deducted by a final
+// settlement and those settled by prepayments; for the latter the comment
+// states the amount (see prepaymentComment).
+func buildEbAdvanceReferences(inv InvoiceJSON) []EbRelatedDocument {
+	var refs []EbRelatedDocument
+	for _, ai := range inv.AdvanceInvoices {
+		refs = append(refs, EbRelatedDocument{
+			InvoiceNumber: ai.InvoiceNumber,
+			InvoiceDate:   ai.InvoiceDate,
+			DocumentType:  "InvoiceForAdvancePayment",
+		})
+	}
+	for _, pp := range inv.Prepayments {
+		if pp.InvoiceNumber == "" {
+			continue
+		}
+		refs = append(refs, EbRelatedDocument{
+			InvoiceNumber: pp.InvoiceNumber,
+			InvoiceDate:   pp.InvoiceDate,
+			DocumentType:  "InvoiceForAdvancePayment",
+			Comment:       prepaymentComment(pp.AmountCents),
+		})
+	}
+	return refs
+}
+
+// prepaymentComment describes a prepaid amount, e.g. "Anzahlung 1200.00 EUR".
+func prepaymentComment(cts int64) string {
+	return fmt.Sprintf("Anzahlung %s %s", formatCentsAsDecimal(cts), invoiceCurrency)
+}
+
+func buildEbQuantity(lt lineTotals) EbQuantity {
+	return EbQuantity{
+		Unit:  lt.UnitCode,
+		Value: formatQuantity(lt.Quantity),
+	}
+}
+
+// buildEbLineOrderReference links line i to the recipient's order, or returns nil.
+func buildEbLineOrderReference(inv InvoiceJSON, i int) *EbOrderReferenceItem {
+	orderID, position, ok := lineOrderReference(inv, i)
+	if !ok {
+		return nil
+	}
+	return &EbOrderReferenceItem{
+		OrderID:             orderID,
+		OrderPositionNumber: position,
+	}
+}
+
+// buildEbArticleNumbers lists the article numbers of a line item.
+func buildEbArticleNumbers(li LineItemJSON) []EbArticleNumber {
+	var numbers []EbArticleNumber
+	for _, a := range lineArticleNumbers(li) {
+		numbers = append(numbers, EbArticleNumber{Type: a.Type, Value: a.Value})
+	}
+	return numbers
+}
+
+// buildEbLineAdjustments lists the reductions and surcharges of a line, or returns nil.
+func buildEbLineAdjustments(lt lineTotals) *EbReductionAndSurchargeListLineItemDetails {
+	if len(lt.Adjustments) == 0 {
+		return nil
+	}
+	details := &EbReductionAndSurchargeListLineItemDetails{}
+	for _, at := range lt.Adjustments {
+		details.Entries = append(details.Entries, EbReductionAndSurchargeBase{
+			XMLName:    ebAdjustmentName(at, "ListLineItem"),
+			BaseAmount: formatCentsAsDecimal(at.BaseCts),
+			Percentage: adjustmentPercentage(at),
+			Amount:     formatCentsAsDecimal(at.AmountCts),
+			Comment:    at.Reason,
+		})
+	}
+	return details
+}
+
+// ebAdjustmentName returns the element name of a reduction or surcharge;
+// line level elements carry the suffix ListLineItem.
+func ebAdjustmentName(at adjustmentTotals, suffix string) xml.Name {
+	if at.Surcharge {
+		return xml.Name{Local: "Surcharge" + suffix}
+	}
+	return xml.Name{Local: "Reduction" + suffix}
+}
+
+// adjustmentPercentage renders the percentage of a reduction or surcharge, or "" for fixed amounts.
+func adjustmentPercentage(at adjustmentTotals) string {
+	if at.Percentage == 0 {
+		return ""
+	}
+	return formatRate(at.Percentage)
+}
+
+// adjustmentReason returns the reason of a reduction or surcharge, falling
+// back to a generic label where a format requires one (EN 16931 BR-33, BR-38).
+func adjustmentReason(at adjustmentTotals) string {
+	if at.Reason != "" {
+		return at.Reason
+	}
+	if at.Surcharge {
+		return "Zuschlag"
+	}
+	return "Nachlass"
+}
+
+// paymentTermsNote describes the payment terms as free text (UBL BT-20, CII
+// Description), e.g. "2 % Skonto bei Zahlung bis 15.02.2026". With skontoSyntax
+// the tiers are appended in the XRechnung form "#SKONTO#TAGE=14#PROZENT=2.00#".
+func paymentTermsNote(inv InvoiceJSON, terms *paymentTermsTotals, skontoSyntax bool) string {
+	var lines []string
+	for _, sk := range terms.Skonto {
+		lines = append(lines, fmt.Sprintf("%s %% Skonto bei Zahlung bis %s", formatRateDE(sk.Percentage), formatDateDE(sk.PaymentDate)))
+	}
+	if len(terms.Skonto) > 0 {
+		lines = append(lines, fmt.Sprintf("Zahlbar bis %s ohne Abzug", formatDateDE(terms.DueDate)))
+	} else {
+		lines = append(lines, fmt.Sprintf("Zahlbar bis %s", formatDateDE(terms.DueDate)))
+	}
+	note := strings.Join(lines, "\n")
+	if skontoSyntax {
+		for _, sk := range terms.Skonto {
+			note += fmt.Sprintf("\n#SKONTO#TAGE=%d#PROZENT=%.2f#", daysBetween(inv.InvoiceDate, sk.PaymentDate), sk.Percentage)
+		}
+		if len(terms.Skonto) > 0 {
+			note += "\n"
+		}
+	}
+	return note
+}
+
+// daysBetween returns the number of days from one ISO date to another.
+func daysBetween(from, to string) int {
+	f, errFrom := time.Parse("2006-01-02", from)
+	t, errTo := time.Parse("2006-01-02", to)
+	if errFrom != nil || errTo != nil {
+		return 0
+	}
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// ebDocumentType maps the JSON document type to the ebInterface DocumentType attribute.
+// A cancellation is a credit memo that additionally names the cancelled document.
+func ebDocumentType(inv InvoiceJSON) string {
+	switch documentType(inv) {
+	case DocTypeCreditMemo, DocTypeCancellation:
+		return "CreditMemo"
+	case DocTypeAdvancePayment:
+		return "InvoiceForAdvancePayment"
+	case DocTypeFinalSettlement:
+		return "FinalSettlement"
+	default:
+		return "Invoice"
+	}
+}
+
+// invoiceTypeCode returns the UNTDID 1001 document type code used by UBL and CII.
+func invoiceTypeCode(inv InvoiceJSON) string {
+	switch documentType(inv) {
+	case DocTypeCreditMemo, DocTypeCancellation:
+		return "381" // Credit note; also used for cancellations
+	case DocTypeAdvancePayment:
+		return "386" // Prepayment invoice
+	default:
+		return "380" // Commercial invoice
+	}
+}
+
+// documentSign returns -1 for documents that reverse an earlier invoice and 1 otherwise.
+func documentSign(inv InvoiceJSON) int64 {
+	switch documentType(inv) {
+	case DocTypeCreditMemo, DocTypeCancellation:
+		return -1
+	default:
+		return 1
 	}
-	return append([]byte(xml.Header), out...), nil
 }
 
 // taxCategoryFromRate maps Austrian VAT rates to ebInterface tax category codes.
@@ -160,4 +455,3 @@ func getContactName(provided, defaultName string) string {
 	}
 	return defaultName
 }
-
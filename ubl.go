@@ -0,0 +1,576 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// UBL 2.1 output following EN 16931. The "ubl" format uses the PEPPOL BIS
+// Billing 3.0 customization accepted by e-rechnung.gv.at, "xrechnung" the
+// German XRechnung customization. Both share the mapping below.
+const (
+	ublInvoiceNamespace    = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	ublCreditNoteNamespace = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+	ublCACNamespace        = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	ublCBCNamespace        = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+
+	peppolBillingProfileID       = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+	peppolBillingCustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0"
+	xrechnungCustomizationID     = "urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0"
+)
+
+func init() {
+	registerOutputFormat(outputFormat{
+		Name:        "ubl",
+		ContentType: "application/xml; charset=utf-8",
+		MediaTypes:  []string{"application/ubl+xml"},
+		Check: func(inv InvoiceJSON, _ renderOptions) error {
+			return checkPeppol(inv)
+		},
+		Render: func(inv InvoiceJSON, _ renderOptions) ([]byte, error) {
+			return TransformToUBL(inv, peppolBillingCustomizationID)
+		},
+	})
+	registerOutputFormat(outputFormat{
+		Name:        "xrechnung",
+		ContentType: "application/xml; charset=utf-8",
+		Check: func(inv InvoiceJSON, _ renderOptions) error {
+			return checkXRechnung(inv)
+		},
+		Render: func(inv InvoiceJSON, _ renderOptions) ([]byte, error) {
+			return TransformToUBL(inv, xrechnungCustomizationID)
+		},
+	})
+}
+
+// checkPeppol enforces the buyer reference or order reference PEPPOL BIS
+// requires (PEPPOL-EN16931-R003). Both are taken from the order ID, which
+// B2B invoices may otherwise omit. The buyer's electronic address
+// (PEPPOL-EN16931-R010) is the e-mail or, failing that, the VAT ID.
+func checkPeppol(inv InvoiceJSON) error {
+	if inv.Recipient.OrderID == "" {
+		return fmt.Errorf("recipient.order_id is required for PEPPOL BIS Billing 3.0, which states it as buyer reference")
+	}
+	if inv.Recipient.Email == "" && inv.Recipient.VATID == "" {
+		return fmt.Errorf("recipient.email or recipient.vat_id is required for PEPPOL BIS Billing 3.0 as the buyer's electronic address")
+	}
+	return nil
+}
+
+// checkXRechnung enforces the seller contact (BR-DE-2, BR-DE-6, BR-DE-7),
+// the buyer reference (BR-DE-15) and the buyer's electronic address required
+// by XRechnung.
+func checkXRechnung(inv InvoiceJSON) error {
+	if inv.Biller.Email == "" || inv.Biller.Phone == "" {
+		return fmt.Errorf("biller.email and biller.phone are required for XRechnung")
+	}
+	if inv.Recipient.OrderID == "" {
+		return fmt.Errorf("recipient.order_id is required for XRechnung, which states it as buyer reference (Leitweg-ID)")
+	}
+	if inv.Recipient.Email == "" && inv.Recipient.VATID == "" {
+		return fmt.Errorf("recipient.email or recipient.vat_id is required for XRechnung as the buyer's electronic address")
+	}
+	return nil
+}
+
+// -------- UBL 2.1 XML models (EN 16931 subset) --------
+// Element names carry the cac/cbc prefixes declared on the root element.
+
+// UBLInvoice is the root of both UBL documents. Credit memos and cancellations
+// become a CreditNote with positive amounts; XMLName and the type code field
+// select the variant. Field order defines the element order.
+type UBLInvoice struct {
+	XMLName                 xml.Name              // Invoice or CreditNote, set by TransformToUBL
+	Xmlns                   string                `xml:"xmlns,attr"`
+	XmlnsCAC                string                `xml:"xmlns:cac,attr"`
+	XmlnsCBC                string                `xml:"xmlns:cbc,attr"`
+	CustomizationID         string                `xml:"cbc:CustomizationID"`
+	ProfileID               string                `xml:"cbc:ProfileID"`
+	ID                      string                `xml:"cbc:ID"`
+	IssueDate               string                `xml:"cbc:IssueDate"`
+	DueDate                 string                `xml:"cbc:DueDate,omitempty"` // Invoice only; BT-9
+	InvoiceTypeCode         string                `xml:"cbc:InvoiceTypeCode,omitempty"`
+	CreditNoteTypeCode      string                `xml:"cbc:CreditNoteTypeCode,omitempty"`
+	Note                    []string              `xml:"cbc:Note,omitempty"`
+	DocumentCurrencyCode    string                `xml:"cbc:DocumentCurrencyCode"`
+	BuyerReference          string                `xml:"cbc:BuyerReference,omitempty"`
+	InvoicePeriod           *UBLPeriod            `xml:"cac:InvoicePeriod,omitempty"` // BG-14
+	OrderReference          *UBLOrderReference    `xml:"cac:OrderReference,omitempty"`
+	BillingReference        []UBLBillingReference `xml:"cac:BillingReference,omitempty"`
+	AccountingSupplierParty UBLPartyWrapper       `xml:"cac:AccountingSupplierParty"`
+	AccountingCustomerParty UBLPartyWrapper       `xml:"cac:AccountingCustomerParty"`
+	Delivery                *UBLDelivery          `xml:"cac:Delivery,omitempty"`
+	PaymentMeans            *UBLPaymentMeans      `xml:"cac:PaymentMeans,omitempty"`
+	PaymentTerms            *UBLPaymentTerms      `xml:"cac:PaymentTerms,omitempty"`
+	AllowanceCharge         []UBLAllowanceCharge  `xml:"cac:AllowanceCharge,omitempty"` // Document level reductions and surcharges
+	TaxTotal                UBLTaxTotal           `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal      UBLLegalMonetaryTotal `xml:"cac:LegalMonetaryTotal"`
+	Lines                   []UBLLine             // cac:InvoiceLine or cac:CreditNoteLine
+}
+
+// UBLAmount is a monetary amount with its currency attribute.
+type UBLAmount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+// UBLIdentifier is an identifier with an optional scheme, e.g. an EAS code.
+type UBLIdentifier struct {
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type UBLOrderReference struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type UBLBillingReference struct {
+	InvoiceDocumentReference UBLDocumentReference `xml:"cac:InvoiceDocumentReference"`
+}
+
+type UBLDocumentReference struct {
+	ID        string `xml:"cbc:ID"`
+	IssueDate string `xml:"cbc:IssueDate,omitempty"`
+}
+
+type UBLPartyWrapper struct {
+	Party UBLParty `xml:"cac:Party"`
+}
+
+// UBLParty element order: EndpointID, PartyIdentification, PostalAddress,
+// PartyTaxScheme, PartyLegalEntity, Contact
+type UBLParty struct {
+	EndpointID          *UBLIdentifier          `xml:"cbc:EndpointID,omitempty"`
+	PartyIdentification *UBLPartyIdentification `xml:"cac:PartyIdentification,omitempty"`
+	PostalAddress       UBLAddress              `xml:"cac:PostalAddress"`
+	PartyTaxScheme      *UBLPartyTaxScheme      `xml:"cac:PartyTaxScheme,omitempty"`
+	PartyLegalEntity    UBLPartyLegalEntity     `xml:"cac:PartyLegalEntity"`
+	Contact             *UBLContact             `xml:"cac:Contact,omitempty"`
+}
+
+type UBLPartyIdentification struct {
+	ID UBLIdentifier `xml:"cbc:ID"`
+}
+
+type UBLAddress struct {
+	StreetName string     `xml:"cbc:StreetName"`
+	CityName   string     `xml:"cbc:CityName"`
+	PostalZone string     `xml:"cbc:PostalZone"`
+	Country    UBLCountry `xml:"cac:Country"`
+}
+
+type UBLCountry struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+}
+
+type UBLPartyTaxScheme struct {
+	CompanyID string       `xml:"cbc:CompanyID"`
+	TaxScheme UBLTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type UBLTaxScheme struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type UBLPartyLegalEntity struct {
+	RegistrationName string `xml:"cbc:RegistrationName"`
+}
+
+type UBLContact struct {
+	Name           string `xml:"cbc:Name,omitempty"`
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+// UBLDelivery element order: ActualDeliveryDate, DeliveryLocation, DeliveryParty
+type UBLDelivery struct {
+	ActualDeliveryDate string               `xml:"cbc:ActualDeliveryDate,omitempty"` // BT-72
+	DeliveryLocation   *UBLDeliveryLocation `xml:"cac:DeliveryLocation,omitempty"`
+	DeliveryParty      *UBLDeliveryParty    `xml:"cac:DeliveryParty,omitempty"`
+}
+
+type UBLDeliveryLocation struct {
+	Address UBLAddress `xml:"cac:Address"` // BG-15
+}
+
+type UBLDeliveryParty struct {
+	PartyName UBLPartyName `xml:"cac:PartyName"`
+}
+
+type UBLPartyName struct {
+	Name string `xml:"cbc:Name"` // BT-70
+}
+
+// UBLPeriod is an invoicing period of the document (BG-14) or a line (BG-26).
+type UBLPeriod struct {
+	StartDate string `xml:"cbc:StartDate"`
+	EndDate   string `xml:"cbc:EndDate"`
+}
+
+// UBLPaymentTerms carries the payment terms as text (BT-20).
+type UBLPaymentTerms struct {
+	Note string `xml:"cbc:Note"`
+}
+
+// UBLPaymentMeans describes a SEPA credit transfer (UNCL4461 code 58).
+type UBLPaymentMeans struct {
+	PaymentMeansCode      string                   `xml:"cbc:PaymentMeansCode"`
+	PaymentID             string                   `xml:"cbc:PaymentID,omitempty"`
+	PayeeFinancialAccount UBLPayeeFinancialAccount `xml:"cac:PayeeFinancialAccount"`
+}
+
+type UBLPayeeFinancialAccount struct {
+	ID                         string                 `xml:"cbc:ID"` // IBAN
+	Name                       string                 `xml:"cbc:Name,omitempty"`
+	FinancialInstitutionBranch *UBLFinancialInstitute `xml:"cac:FinancialInstitutionBranch,omitempty"`
+}
+
+type UBLFinancialInstitute struct {
+	ID string `xml:"cbc:ID"` // BIC
+}
+
+type UBLTaxTotal struct {
+	TaxAmount   UBLAmount        `xml:"cbc:TaxAmount"`
+	TaxSubtotal []UBLTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type UBLTaxSubtotal struct {
+	TaxableAmount UBLAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     UBLAmount      `xml:"cbc:TaxAmount"`
+	TaxCategory   UBLTaxCategory `xml:"cac:TaxCategory"`
+}
+
+// UBLTaxCategory is used both in the tax breakdown (TaxCategory) and on lines (ClassifiedTaxCategory).
+// Only the tax breakdown states the exemption reason of untaxed categories.
+type UBLTaxCategory struct {
+	ID                     string       `xml:"cbc:ID"` // UNCL5305, e.g. S
+	Percent                string       `xml:"cbc:Percent"`
+	TaxExemptionReasonCode string       `xml:"cbc:TaxExemptionReasonCode,omitempty"` // VATEX, e.g. VATEX-EU-AE
+	TaxExemptionReason     string       `xml:"cbc:TaxExemptionReason,omitempty"`
+	TaxScheme              UBLTaxScheme `xml:"cac:TaxScheme"`
+}
+
+// UBLAllowanceCharge is a reduction (allowance) or surcharge (charge) on the
+// document or on a line; only document level entries carry a TaxCategory.
+// Element order: ChargeIndicator, AllowanceChargeReason, MultiplierFactorNumeric, Amount, BaseAmount, TaxCategory
+type UBLAllowanceCharge struct {
+	ChargeIndicator         bool            `xml:"cbc:ChargeIndicator"`
+	AllowanceChargeReason   string          `xml:"cbc:AllowanceChargeReason"`
+	MultiplierFactorNumeric string          `xml:"cbc:MultiplierFactorNumeric,omitempty"`
+	Amount                  UBLAmount       `xml:"cbc:Amount"`
+	BaseAmount              UBLAmount       `xml:"cbc:BaseAmount"`
+	TaxCategory             *UBLTaxCategory `xml:"cac:TaxCategory,omitempty"`
+}
+
+// UBLLegalMonetaryTotal element order: LineExtensionAmount, TaxExclusiveAmount,
+// TaxInclusiveAmount, AllowanceTotalAmount, ChargeTotalAmount, PrepaidAmount, PayableAmount
+type UBLLegalMonetaryTotal struct {
+	LineExtensionAmount  UBLAmount  `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount   UBLAmount  `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount   UBLAmount  `xml:"cbc:TaxInclusiveAmount"`
+	AllowanceTotalAmount *UBLAmount `xml:"cbc:AllowanceTotalAmount,omitempty"`
+	ChargeTotalAmount    *UBLAmount `xml:"cbc:ChargeTotalAmount,omitempty"`
+	PrepaidAmount        *UBLAmount `xml:"cbc:PrepaidAmount,omitempty"`
+	PayableAmount        UBLAmount  `xml:"cbc:PayableAmount"`
+}
+
+// UBLLine is an InvoiceLine or CreditNoteLine.
+// Element order: ID, Quantity, LineExtensionAmount, OrderLineReference, AllowanceCharge, Item, Price
+type UBLLine struct {
+	XMLName             xml.Name
+	ID                  string                 `xml:"cbc:ID"`
+	Quantity            UBLQuantity            // cbc:InvoicedQuantity or cbc:CreditedQuantity
+	LineExtensionAmount UBLAmount              `xml:"cbc:LineExtensionAmount"`
+	InvoicePeriod       *UBLPeriod             `xml:"cac:InvoicePeriod,omitempty"`
+	OrderLineReference  *UBLOrderLineReference `xml:"cac:OrderLineReference,omitempty"`
+	AllowanceCharge     []UBLAllowanceCharge   `xml:"cac:AllowanceCharge,omitempty"`
+	Item                UBLItem                `xml:"cac:Item"`
+	Price               UBLPrice               `xml:"cac:Price"`
+}
+
+type UBLQuantity struct {
+	XMLName  xml.Name
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type UBLOrderLineReference struct {
+	LineID string `xml:"cbc:LineID"`
+}
+
+// UBLItem element order: Name, BuyersItemIdentification, SellersItemIdentification,
+// StandardItemIdentification, ClassifiedTaxCategory
+type UBLItem struct {
+	Name                       string                 `xml:"cbc:Name"`
+	BuyersItemIdentification   *UBLItemIdentification `xml:"cac:BuyersItemIdentification,omitempty"`
+	SellersItemIdentification  *UBLItemIdentification `xml:"cac:SellersItemIdentification,omitempty"`
+	StandardItemIdentification *UBLItemIdentification `xml:"cac:StandardItemIdentification,omitempty"` // GTIN, scheme 0160
+	ClassifiedTaxCategory      UBLTaxCategory         `xml:"cac:ClassifiedTaxCategory"`
+}
+
+type UBLItemIdentification struct {
+	ID UBLIdentifier `xml:"cbc:ID"`
+}
+
+type UBLPrice struct {
+	PriceAmount UBLAmount `xml:"cbc:PriceAmount"`
+}
+
+// TransformToUBL maps the JSON invoice into a UBL 2.1 Invoice (or CreditNote)
+// with the given EN 16931 customization. Amounts come from computeTotals, so
+// they match the ebInterface output exactly.
+func TransformToUBL(inv InvoiceJSON, customizationID string) ([]byte, error) {
+	t := computeTotals(inv)
+	delivery := en16931Delivery(inv, t)
+
+	// computeTotals signs amounts for ebInterface; a UBL CreditNote states them positive.
+	sign := documentSign(inv)
+	amount := func(cts int64) UBLAmount {
+		return UBLAmount{CurrencyID: invoiceCurrency, Value: formatCentsAsDecimal(sign * cts)}
+	}
+
+	doc := &UBLInvoice{
+		XMLName:              xml.Name{Local: "Invoice"},
+		Xmlns:                ublInvoiceNamespace,
+		XmlnsCAC:             ublCACNamespace,
+		XmlnsCBC:             ublCBCNamespace,
+		CustomizationID:      customizationID,
+		ProfileID:            peppolBillingProfileID,
+		ID:                   inv.InvoiceNumber,
+		IssueDate:            inv.InvoiceDate,
+		InvoiceTypeCode:      invoiceTypeCode(inv),
+		DocumentCurrencyCode: invoiceCurrency,
+		BuyerReference:       inv.Recipient.OrderID,
+		InvoicePeriod:        buildUBLPeriod(delivery),
+		AccountingSupplierParty: UBLPartyWrapper{Party: buildUBLParty(
+			inv.Biller.Name, inv.Biller.VATID, inv.Biller.Email, inv.Biller.Address,
+			&UBLContact{
+				Name:           getContactName(inv.Biller.ContactName, "Billing Department"),
+				Telephone:      inv.Biller.Phone,
+				ElectronicMail: inv.Biller.Email,
+			})},
+		AccountingCustomerParty: UBLPartyWrapper{Party: buildUBLParty(
+			inv.Recipient.Name, inv.Recipient.VATID, inv.Recipient.Email, inv.Recipient.Address,
+			&UBLContact{
+				Name:           getContactName(inv.Recipient.ContactName, "Accounting"),
+				ElectronicMail: inv.Recipient.Email,
+			})},
+		Delivery: buildUBLDelivery(inv, delivery),
+		PaymentMeans: &UBLPaymentMeans{
+			PaymentMeansCode: "58", // SEPA credit transfer
+			PaymentID:        inv.InvoiceNumber,
+			PayeeFinancialAccount: UBLPayeeFinancialAccount{
+				ID:                         inv.Payment.IBAN,
+				Name:                       inv.Biller.Name,
+				FinancialInstitutionBranch: &UBLFinancialInstitute{ID: inv.Payment.BIC},
+			},
+		},
+		TaxTotal: UBLTaxTotal{TaxAmount: amount(t.TaxCts)},
+		LegalMonetaryTotal: UBLLegalMonetaryTotal{
+			LineExtensionAmount: amount(t.LineNetCts),
+			TaxExclusiveAmount:  amount(t.NetCts),
+			TaxInclusiveAmount:  amount(t.GrossCts),
+			PayableAmount:       amount(t.PayableCts),
+		},
+	}
+	if t.ReductionCts != 0 {
+		total := amount(t.ReductionCts)
+		doc.LegalMonetaryTotal.AllowanceTotalAmount = &total
+	}
+	if t.SurchargeCts != 0 {
+		total := amount(t.SurchargeCts)
+		doc.LegalMonetaryTotal.ChargeTotalAmount = &total
+	}
+	if t.PrepaidCts != 0 {
+		total := amount(t.PrepaidCts)
+		doc.LegalMonetaryTotal.PrepaidAmount = &total
+	}
+	for _, ai := range inv.AdvanceInvoices {
+		doc.BillingReference = append(doc.BillingReference, UBLBillingReference{
+			InvoiceDocumentReference: UBLDocumentReference{ID: ai.InvoiceNumber, IssueDate: ai.InvoiceDate},
+		})
+	}
+	for _, pp := range inv.Prepayments {
+		if pp.InvoiceNumber != "" {
+			doc.BillingReference = append(doc.BillingReference, UBLBillingReference{
+				InvoiceDocumentReference: UBLDocumentReference{ID: pp.InvoiceNumber, IssueDate: pp.InvoiceDate},
+			})
+		}
+	}
+	for _, at := range t.Adjustments {
+		ac := buildUBLAllowanceCharge(at, amount)
+		category := buildUBLTaxCategory(at.TaxCategory, at.TaxRate)
+		ac.TaxCategory = &category
+		doc.AllowanceCharge = append(doc.AllowanceCharge, ac)
+	}
+	if t.PaymentTerms != nil {
+		doc.DueDate = t.PaymentTerms.DueDate
+		doc.PaymentTerms = &UBLPaymentTerms{Note: paymentTermsNote(inv, t.PaymentTerms, customizationID == xrechnungCustomizationID)}
+	}
+	if inv.Biller.BillerID != "" {
+		doc.AccountingSupplierParty.Party.PartyIdentification = &UBLPartyIdentification{ID: UBLIdentifier{Value: inv.Biller.BillerID}}
+	}
+
+	lineName, quantityName := "cac:InvoiceLine", "cbc:InvoicedQuantity"
+	if sign < 0 {
+		doc.XMLName = xml.Name{Local: "CreditNote"}
+		doc.Xmlns = ublCreditNoteNamespace
+		doc.InvoiceTypeCode = ""
+		doc.CreditNoteTypeCode = invoiceTypeCode(inv)
+		doc.DueDate = "" // Not part of a CreditNote; PaymentTerms still states it
+		lineName, quantityName = "cac:CreditNoteLine", "cbc:CreditedQuantity"
+	}
+	if ref := inv.OriginalInvoice; ref != nil {
+		doc.BillingReference = []UBLBillingReference{{
+			InvoiceDocumentReference: UBLDocumentReference{ID: ref.InvoiceNumber, IssueDate: ref.InvoiceDate},
+		}}
+		if ref.Comment != "" {
+			doc.Note = append(doc.Note, ref.Comment)
+		}
+	}
+
+	for _, b := range t.Buckets {
+		category := buildUBLTaxCategory(b.Category, b.Rate)
+		if b.ExemptionReason != "" {
+			category.TaxExemptionReasonCode = taxExemptionReasonCodes[b.Category]
+			category.TaxExemptionReason = b.ExemptionReason
+		}
+		doc.TaxTotal.TaxSubtotal = append(doc.TaxTotal.TaxSubtotal, UBLTaxSubtotal{
+			TaxableAmount: amount(b.TaxableCts),
+			TaxAmount:     amount(b.TaxCts),
+			TaxCategory:   category,
+		})
+	}
+
+	for i, li := range inv.Items {
+		lt := t.Lines[i]
+		var charges []UBLAllowanceCharge
+		for _, at := range lt.Adjustments {
+			charges = append(charges, buildUBLAllowanceCharge(at, amount))
+		}
+		doc.Lines = append(doc.Lines, UBLLine{
+			XMLName: xml.Name{Local: lineName},
+			ID:      fmt.Sprintf("%d", i+1),
+			Quantity: UBLQuantity{
+				XMLName:  xml.Name{Local: quantityName},
+				UnitCode: lt.UnitCode,
+				Value:    formatQuantity(lt.Quantity.Mul(decimal.NewFromInt(sign))),
+			},
+			LineExtensionAmount: amount(lt.NetCts),
+			InvoicePeriod:       buildUBLPeriod(li.Delivery),
+			OrderLineReference:  buildUBLOrderLineReference(inv, i),
+			AllowanceCharge:     charges,
+			Item:                buildUBLItem(li, buildUBLTaxCategory(lt.TaxCategory, lt.TaxRate)),
+			Price:               UBLPrice{PriceAmount: UBLAmount{CurrencyID: invoiceCurrency, Value: formatPrice(lt.UnitPrice)}},
+		})
+	}
+
+	if inv.Recipient.OrderID != "" {
+		doc.OrderReference = &UBLOrderReference{ID: inv.Recipient.OrderID}
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal UBL: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// buildUBLAddress maps a postal address.
+func buildUBLAddress(addr AddressJSON) UBLAddress {
+	return UBLAddress{
+		StreetName: addr.Street,
+		CityName:   addr.City,
+		PostalZone: addr.ZIP,
+		Country:    UBLCountry{IdentificationCode: addressCountry(addr)},
+	}
+}
+
+// buildUBLDelivery maps the delivery date and address; a delivery period is
+// stated as the invoicing period instead (see buildUBLPeriod).
+func buildUBLDelivery(inv InvoiceJSON, d *DeliveryJSON) *UBLDelivery {
+	if d == nil || (d.Date == "" && d.Address == nil) {
+		return nil
+	}
+	delivery := &UBLDelivery{ActualDeliveryDate: d.Date}
+	if d.Address != nil {
+		delivery.DeliveryLocation = &UBLDeliveryLocation{Address: buildUBLAddress(*d.Address)}
+		delivery.DeliveryParty = &UBLDeliveryParty{PartyName: UBLPartyName{Name: deliveryName(inv, *d)}}
+	}
+	return delivery
+}
+
+// buildUBLPeriod returns the delivery period of the document or a line, or nil.
+func buildUBLPeriod(d *DeliveryJSON) *UBLPeriod {
+	if d == nil || !d.isPeriod() {
+		return nil
+	}
+	return &UBLPeriod{StartDate: d.FromDate, EndDate: d.ToDate}
+}
+
+// buildUBLParty maps a biller or recipient. The electronic address (BT-34/BT-49)
+// is the e-mail address if known, otherwise the VAT ID with the EAS code of its
+// country (e.g. 9914 Austrian VAT).
+func buildUBLParty(name, vatID, email string, addr AddressJSON, contact *UBLContact) UBLParty {
+	party := UBLParty{
+		PostalAddress:    buildUBLAddress(addr),
+		PartyLegalEntity: UBLPartyLegalEntity{RegistrationName: name},
+		Contact:          contact,
+	}
+	switch {
+	case email != "":
+		party.EndpointID = &UBLIdentifier{SchemeID: "EM", Value: email}
+	case vatID != "":
+		party.EndpointID = &UBLIdentifier{SchemeID: vatEndpointScheme(vatID, addressCountry(addr)), Value: vatID}
+	}
+	// A recipient without VAT ID (small B2B invoices) has no tax scheme.
+	if vatID != "" {
+		party.PartyTaxScheme = &UBLPartyTaxScheme{CompanyID: vatID, TaxScheme: UBLTaxScheme{ID: "VAT"}}
+	}
+	return party
+}
+
+// buildUBLItem maps the description and article numbers of a line item.
+func buildUBLItem(li LineItemJSON, category UBLTaxCategory) UBLItem {
+	item := UBLItem{Name: li.Description, ClassifiedTaxCategory: category}
+	if li.RecipientArticleNumber != "" {
+		item.BuyersItemIdentification = &UBLItemIdentification{ID: UBLIdentifier{Value: li.RecipientArticleNumber}}
+	}
+	if li.BillerArticleNumber != "" {
+		item.SellersItemIdentification = &UBLItemIdentification{ID: UBLIdentifier{Value: li.BillerArticleNumber}}
+	}
+	if li.GTIN != "" {
+		item.StandardItemIdentification = &UBLItemIdentification{ID: UBLIdentifier{SchemeID: gtinSchemeID, Value: li.GTIN}}
+	}
+	return item
+}
+
+// buildUBLOrderLineReference links line i to the recipient's order, or returns nil.
+func buildUBLOrderLineReference(inv InvoiceJSON, i int) *UBLOrderLineReference {
+	position := lineOrderPosition(inv, i)
+	if position == "" {
+		return nil
+	}
+	return &UBLOrderLineReference{LineID: position}
+}
+
+// buildUBLAllowanceCharge maps a reduction or surcharge; amount states it in document currency.
+func buildUBLAllowanceCharge(at adjustmentTotals, amount func(int64) UBLAmount) UBLAllowanceCharge {
+	return UBLAllowanceCharge{
+		ChargeIndicator:         at.Surcharge,
+		AllowanceChargeReason:   adjustmentReason(at),
+		MultiplierFactorNumeric: adjustmentPercentage(at),
+		Amount:                  amount(at.AmountCts),
+		BaseAmount:              amount(at.BaseCts),
+	}
+}
+
+func buildUBLTaxCategory(category string, rate float64) UBLTaxCategory {
+	return UBLTaxCategory{
+		ID:        category,
+		Percent:   formatRate(rate),
+		TaxScheme: UBLTaxScheme{ID: "VAT"},
+	}
+}
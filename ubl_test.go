@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUBLChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		edit    func(inv *InvoiceJSON)
+		wantErr string
+	}{
+		{"peppol with order id", "ubl", func(inv *InvoiceJSON) {}, ""},
+		{"peppol b2b without order id", "ubl", func(inv *InvoiceJSON) {
+			inv.Profile = ProfileB2B
+			inv.Recipient.OrderID = ""
+		}, "PEPPOL"},
+		{"xrechnung without phone", "xrechnung", func(inv *InvoiceJSON) {}, "biller.phone"},
+		{"xrechnung complete", "xrechnung", func(inv *InvoiceJSON) { inv.Biller.Phone = "+43 1 234" }, ""},
+		{"xrechnung without order id", "xrechnung", func(inv *InvoiceJSON) {
+			inv.Biller.Phone = "+43 1 234"
+			inv.Recipient.OrderID = ""
+		}, "Leitweg-ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := readGoldenInvoice(t)
+			tt.edit(&inv)
+			err := outputFormats[tt.format].Check(inv, renderOptions{})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUBLBuyerReference(t *testing.T) {
+	doc, err := TransformToUBL(readGoldenInvoice(t), peppolBillingCustomizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<cbc:BuyerReference>1234567890</cbc:BuyerReference>", "<cac:OrderReference>"} {
+		if !strings.Contains(string(doc), want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
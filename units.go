@@ -0,0 +1,84 @@
+package main
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// DefaultUnitCode is used for line items without a unit ("one").
+const DefaultUnitCode = "C62"
+
+// rec20List is the bundled EN 16931 unit code list: UN/ECE Recommendation 20
+// and the X-prefixed Recommendation 21 package codes.
+//
+//go:embed codelists/unece-rec20.txt
+var rec20List string
+
+// rec20Units maps each bundled unit code to its name.
+var rec20Units = parseCodeList(rec20List)
+
+// unitAliases maps common spellings (lower case) to their Rec 20 code.
+var unitAliases = map[string]string{
+	"h": "HUR", "hr": "HUR", "hrs": "HUR", "hour": "HUR", "hours": "HUR", "std": "HUR", "stunde": "HUR", "stunden": "HUR",
+	"s": "SEC", "sec": "SEC", "second": "SEC", "seconds": "SEC",
+	"minute": "MIN", "minutes": "MIN", "minuten": "MIN",
+	"d": "DAY", "day": "DAY", "days": "DAY", "tag": "DAY", "tage": "DAY",
+	"wk": "WEE", "week": "WEE", "weeks": "WEE", "woche": "WEE", "wochen": "WEE",
+	"mo": "MON", "month": "MON", "months": "MON", "monat": "MON", "monate": "MON",
+	"y": "ANN", "yr": "ANN", "year": "ANN", "years": "ANN", "jahr": "ANN", "jahre": "ANN",
+	"mg": "MGM", "g": "GRM", "kg": "KGM", "t": "TNE",
+	"mm": "MMT", "cm": "CMT", "m": "MTR", "km": "KMT",
+	"m2": "MTK", "m²": "MTK", "sqm": "MTK", "m3": "MTQ", "m³": "MTQ",
+	"ml": "MLT", "l": "LTR", "liter": "LTR", "litre": "LTR",
+	"wh": "WHR", "kwh": "KWH", "mwh": "MWH",
+	"pc": "H87", "pcs": "H87", "piece": "H87", "pieces": "H87", "stk": "H87", "stück": "H87",
+	"licence": "C62", "license": "C62", "lizenz": "C62",
+	"pair": "PR", "paar": "PR",
+	"lump sum": "LS", "pauschal": "LS", "pauschale": "LS",
+	"%": "P1", "percent": "P1",
+}
+
+// unitLabelsDE are the German unit symbols printed on PDF invoices; other
+// codes are printed as they are.
+var unitLabelsDE = map[string]string{
+	"C62": "", "H87": "Stk.", "EA": "Stk.", "PR": "Paar", "SET": "Set", "LS": "pauschal", "P1": "%",
+	"SEC": "s", "MIN": "Min.", "HUR": "Std.", "DAY": "Tage", "WEE": "Wo.", "MON": "Mon.", "ANN": "Jahre",
+	"GRM": "g", "KGM": "kg", "TNE": "t", "MTR": "m", "KMT": "km", "MTK": "m²", "MTQ": "m³",
+	"LTR": "l", "KWH": "kWh",
+}
+
+// parseCodeList reads "CODE<TAB>name" lines of a bundled code list, skipping comments.
+func parseCodeList(list string) map[string]string {
+	codes := map[string]string{}
+	for _, line := range strings.Split(list, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, name, _ := strings.Cut(line, "\t")
+		codes[code] = name
+	}
+	return codes
+}
+
+// resolveUnitCode returns the Rec 20 code for a unit code or alias; an empty
+// unit means DefaultUnitCode.
+func resolveUnitCode(unit string) (string, bool) {
+	unit = strings.TrimSpace(unit)
+	if unit == "" {
+		return DefaultUnitCode, true
+	}
+	if code, ok := unitAliases[strings.ToLower(unit)]; ok {
+		return code, true
+	}
+	code := strings.ToUpper(unit)
+	_, ok := rec20Units[code]
+	return code, ok
+}
+
+// unitLabelDE returns the unit printed next to a quantity.
+func unitLabelDE(code string) string {
+	if label, ok := unitLabelsDE[code]; ok {
+		return label
+	}
+	return code
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestResolveUnitCode(t *testing.T) {
+	tests := []struct {
+		unit   string
+		want   string
+		wantOK bool
+	}{
+		{"", DefaultUnitCode, true},
+		{"  ", DefaultUnitCode, true},
+		{"h", "HUR", true},
+		{"Stunden", "HUR", true},
+		{"m²", "MTK", true},
+		{"pauschal", "LS", true},
+		{"kgm", "KGM", true},
+		{"C62", "C62", true},
+		{"HAR", "HAR", true},
+		{"E49", "E49", true}, // working day
+		{"KWN", "KWN", true}, // level 3 code
+		{"XPP", "XPP", true}, // Rec 21 package code
+		{"xbx", "XBX", true}, // upper-cased before lookup
+		{"ZZ", "ZZ", true},
+		{"XYZZY", "XYZZY", false},
+		{"pieces of eight", "PIECES OF EIGHT", false},
+	}
+	for _, tt := range tests {
+		got, ok := resolveUnitCode(tt.unit)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("resolveUnitCode(%q) = %q, %v; want %q, %v", tt.unit, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRec20ListAliasesAndLabels(t *testing.T) {
+	for alias, code := range unitAliases {
+		if _, ok := rec20Units[code]; !ok {
+			t.Errorf("alias %q maps to %s, which is not in the bundled list", alias, code)
+		}
+	}
+	for code := range unitLabelsDE {
+		if _, ok := rec20Units[code]; !ok {
+			t.Errorf("label for %s, which is not in the bundled list", code)
+		}
+	}
+	for code, name := range rec20Units {
+		if name == "" {
+			t.Errorf("%s has no name", code)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vatIDFormat describes the VAT ID of one EU member state: the part after the
+// country prefix and, where the member state publishes one, its check digit
+// algorithm.
+type vatIDFormat struct {
+	Country string         // ISO 3166-1 code; the prefix differs for Greece (EL) and Northern Ireland (XI)
+	Pattern *regexp.Regexp // Number after the prefix
+	Format  string         // Shown in validation errors
+	Check   func(number string) bool
+}
+
+// vatIDFormats maps the VAT ID prefix to the member state's format (VIES).
+var vatIDFormats = map[string]vatIDFormat{
+	"AT": {"AT", regexp.MustCompile(`^U\d{8}$`), "ATU followed by 8 digits (e.g., ATU13585627)", checkVATIDAT},
+	"BE": {"BE", regexp.MustCompile(`^[01]\d{9}$`), "BE followed by 10 digits starting with 0 or 1", checkVATIDBE},
+	"BG": {"BG", regexp.MustCompile(`^\d{9,10}$`), "BG followed by 9 or 10 digits", checkVATIDBG},
+	"CY": {"CY", regexp.MustCompile(`^\d{8}[A-Z]$`), "CY followed by 8 digits and a letter", checkVATIDCY},
+	"CZ": {"CZ", regexp.MustCompile(`^\d{8,10}$`), "CZ followed by 8 to 10 digits", checkVATIDCZ},
+	"DE": {"DE", regexp.MustCompile(`^\d{9}$`), "DE followed by 9 digits", checkISO7064Mod1110},
+	"DK": {"DK", regexp.MustCompile(`^\d{8}$`), "DK followed by 8 digits", checkVATIDDK},
+	"EE": {"EE", regexp.MustCompile(`^\d{9}$`), "EE followed by 9 digits", checkVATIDEE},
+	"EL": {"GR", regexp.MustCompile(`^\d{9}$`), "EL followed by 9 digits", checkVATIDEL},
+	"ES": {"ES", regexp.MustCompile(`^[A-Z0-9]\d{7}[A-Z0-9]$`), "ES followed by 9 characters", checkVATIDES},
+	"FI": {"FI", regexp.MustCompile(`^\d{8}$`), "FI followed by 8 digits", checkVATIDFI},
+	"FR": {"FR", regexp.MustCompile(`^[0-9A-HJ-NP-Z]{2}\d{9}$`), "FR followed by a 2 character key and 9 digits", checkVATIDFR},
+	"HR": {"HR", regexp.MustCompile(`^\d{11}$`), "HR followed by 11 digits", checkISO7064Mod1110},
+	"HU": {"HU", regexp.MustCompile(`^\d{8}$`), "HU followed by 8 digits", checkVATIDHU},
+	"IE": {"IE", regexp.MustCompile(`^(\d{7}[A-W][A-IW]?|\d[A-Z+*]\d{5}[A-W])$`), "IE followed by 8 or 9 characters", checkVATIDIE},
+	"IT": {"IT", regexp.MustCompile(`^\d{11}$`), "IT followed by 11 digits", checkLuhn},
+	"LT": {"LT", regexp.MustCompile(`^(\d{9}|\d{12})$`), "LT followed by 9 or 12 digits", checkVATIDLT},
+	"LU": {"LU", regexp.MustCompile(`^\d{8}$`), "LU followed by 8 digits", checkVATIDLU},
+	"LV": {"LV", regexp.MustCompile(`^\d{11}$`), "LV followed by 11 digits", checkVATIDLV},
+	"MT": {"MT", regexp.MustCompile(`^\d{8}$`), "MT followed by 8 digits", checkVATIDMT},
+	"NL": {"NL", regexp.MustCompile(`^\d{9}B\d{2}$`), "NL followed by 9 digits, B and 2 digits", checkVATIDNL},
+	"PL": {"PL", regexp.MustCompile(`^\d{10}$`), "PL followed by 10 digits", checkVATIDPL},
+	"PT": {"PT", regexp.MustCompile(`^\d{9}$`), "PT followed by 9 digits", checkVATIDPT},
+	"RO": {"RO", regexp.MustCompile(`^[1-9]\d{1,9}$`), "RO followed by 2 to 10 digits", checkVATIDRO},
+	"SE": {"SE", regexp.MustCompile(`^\d{10}01$`), "SE followed by 10 digits and 01", checkVATIDSE},
+	"SI": {"SI", regexp.MustCompile(`^[1-9]\d{7}$`), "SI followed by 8 digits", checkVATIDSI},
+	"SK": {"SK", regexp.MustCompile(`^[1-9]\d{9}$`), "SK followed by 10 digits", checkVATIDSK},
+	"XI": {"GB", regexp.MustCompile(`^(\d{9}|\d{12}|GD\d{3}|HA\d{3})$`), "XI followed by 9 or 12 digits", checkVATIDXI},
+}
+
+// vatIDPrefix returns the EU VAT ID prefix of vatID, or "" if it has none.
+func vatIDPrefix(vatID string) string {
+	if len(vatID) < 2 {
+		return ""
+	}
+	if _, ok := vatIDFormats[vatID[:2]]; !ok {
+		return ""
+	}
+	return vatID[:2]
+}
+
+// validateVATID checks an EU VAT ID against the format and check digit of
+// its member state.
+func validateVATID(vatID string) error {
+	prefix := vatIDPrefix(vatID)
+	if prefix == "" {
+		return fmt.Errorf("vat_id must start with the prefix of an EU member state (e.g., ATU13585627, DE136695976)")
+	}
+	f := vatIDFormats[prefix]
+	number := vatID[2:]
+	if !f.Pattern.MatchString(number) {
+		return fmt.Errorf("vat_id must be in format %s", f.Format)
+	}
+	if f.Check != nil && !f.Check(number) {
+		return &errVATIDChecksum{vatID: vatID}
+	}
+	return nil
+}
+
+// errVATIDChecksum is returned by validateVATID for a well-formed VAT ID
+// whose check digit does not match, which usually means a typo.
+type errVATIDChecksum struct {
+	vatID string
+}
+
+func (e *errVATIDChecksum) Error() string {
+	return fmt.Sprintf("vat_id %s has an invalid check digit, please check it for typos", e.vatID)
+}
+
+// validatePartyVATID checks the VAT ID of a party in the given country.
+// Parties outside the EU may state their local tax number instead.
+func validatePartyVATID(vatID, country string) error {
+	if vatIDPrefix(vatID) == "" && !isEUCountry(country) {
+		return nil
+	}
+	return validateVATID(vatID)
+}
+
+// vatIDCountry returns the ISO country code of an EU VAT ID, or "" for
+// other tax numbers.
+func vatIDCountry(vatID string) string {
+	prefix := vatIDPrefix(vatID)
+	if prefix == "" {
+		return ""
+	}
+	return vatIDFormats[prefix].Country
+}
+
+// vatEndpointSchemes are the EAS codes (CEF code list) of VAT ID based
+// electronic addresses, by VAT ID prefix or, for other tax numbers, by country.
+var vatEndpointSchemes = map[string]string{
+	"AT": "9914", "BE": "9925", "BG": "9926", "CY": "9928", "CZ": "9929", "DE": "9930",
+	"EE": "9931", "EL": "9933", "ES": "9920", "FR": "9957", "HR": "9934", "HU": "9910",
+	"IE": "9935", "IT": "9906", "LT": "9937", "LU": "9938", "LV": "9939", "MT": "9943",
+	"NL": "9944", "PL": "9945", "PT": "9946", "RO": "9947", "SE": "9955", "SI": "9949",
+	"SK": "9950", "XI": "9932", "DK": "0184", "FI": "0213",
+	"GB": "9932", "CH": "9927", "LI": "9936", "TR": "9952", "SM": "9951", "VA": "9953", "MC": "9940", "AD": "9922",
+}
+
+// vatEndpointScheme returns the EAS code for a party's VAT ID. Unknown tax
+// numbers keep the Austrian scheme.
+func vatEndpointScheme(vatID, country string) string {
+	if prefix := vatIDPrefix(vatID); prefix != "" {
+		return vatEndpointSchemes[prefix]
+	}
+	if scheme, ok := vatEndpointSchemes[country]; ok {
+		return scheme
+	}
+	return vatEndpointSchemes[DefaultCountryCode]
+}
+
+// -------- check digit algorithms --------
+
+// digits converts a string of ASCII digits; the patterns guarantee the input.
+func digits(s string) []int {
+	d := make([]int, len(s))
+	for i := range s {
+		d[i] = int(s[i] - '0')
+	}
+	return d
+}
+
+// weightedSum multiplies the leading digits with the weights and adds them up.
+func weightedSum(d []int, weights ...int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += d[i] * w
+	}
+	return sum
+}
+
+// checkISO7064Mod1110 verifies the last digit per ISO 7064 MOD 11,10 (DE, HR).
+func checkISO7064Mod1110(number string) bool {
+	d := digits(number)
+	product := 10
+	for _, v := range d[:len(d)-1] {
+		sum := (v + product) % 10
+		if sum == 0 {
+			sum = 10
+		}
+		product = (2 * sum) % 11
+	}
+	check := 11 - product
+	if check == 10 {
+		check = 0
+	}
+	return check == d[len(d)-1]
+}
+
+// checkLuhn verifies the last digit per the Luhn algorithm (IT, SE).
+func checkLuhn(number string) bool {
+	d := digits(number)
+	sum := 0
+	for i := len(d) - 1; i >= 0; i-- {
+		v := d[i]
+		if (len(d)-1-i)%2 == 1 {
+			v *= 2
+			if v > 9 {
+				v -= 9
+			}
+		}
+		sum += v
+	}
+	return sum%10 == 0
+}
+
+// checkVATIDAT verifies the check digit of an Austrian UID (U followed by 8
+// digits) as published by the Federal Ministry of Finance.
+func checkVATIDAT(number string) bool {
+	d := digits(number[1:])
+	sum := d[0] + d[2] + d[4] + d[6]
+	for _, v := range []int{d[1], d[3], d[5]} {
+		sum += v/5 + v*2%10
+	}
+	return (10-(sum+4)%10)%10 == d[7]
+}
+
+func checkVATIDBE(number string) bool {
+	base, _ := strconv.Atoi(number[:8])
+	check, _ := strconv.Atoi(number[8:])
+	return 97-base%97 == check
+}
+
+// checkVATIDBG verifies 9 digit company numbers and 10 digit numbers, which
+// are either a personal number (EGN), a foreigner's number (PNF) or another
+// registration number, each with its own weights.
+func checkVATIDBG(number string) bool {
+	d := digits(number)
+	if len(d) == 9 {
+		check := weightedSum(d, 1, 2, 3, 4, 5, 6, 7, 8) % 11
+		if check == 10 {
+			check = weightedSum(d, 3, 4, 5, 6, 7, 8, 9, 10) % 11
+		}
+		return check%10 == d[8]
+	}
+	if weightedSum(d, 2, 4, 8, 5, 10, 9, 7, 3, 6)%11%10 == d[9] {
+		return true // EGN
+	}
+	if weightedSum(d, 21, 19, 17, 13, 11, 9, 7, 3, 1)%10 == d[9] {
+		return true // PNF
+	}
+	check := 11 - weightedSum(d, 4, 3, 2, 7, 6, 5, 4, 3, 2)%11
+	return check != 10 && check%11 == d[9]
+}
+
+// checkVATIDCY verifies the check letter: digits in even positions are
+// translated, those in odd positions added as they are.
+func checkVATIDCY(number string) bool {
+	translation := []int{1, 0, 5, 7, 9, 13, 15, 17, 19, 21}
+	d := digits(number[:8])
+	sum := 0
+	for i, v := range d {
+		if i%2 == 0 {
+			sum += translation[v]
+		} else {
+			sum += v
+		}
+	}
+	return rune('A'+sum%26) == rune(number[8])
+}
+
+// checkVATIDCZ verifies 8 digit company numbers and 10 digit birth numbers.
+// 9 digit birth numbers were issued before 1954 without a check digit.
+func checkVATIDCZ(number string) bool {
+	d := digits(number)
+	switch len(d) {
+	case 8:
+		if d[0] == 9 {
+			return false
+		}
+		check := (11 - weightedSum(d, 8, 7, 6, 5, 4, 3, 2)%11) % 11
+		if check == 0 {
+			check = 1
+		}
+		return check%10 == d[7]
+	case 10:
+		n, _ := strconv.ParseInt(number, 10, 64)
+		if n%11 == 0 {
+			return true
+		}
+		// Until 1985 a remainder of 10 was written as check digit 0.
+		return (n/10)%11 == 10 && d[9] == 0
+	}
+	return true
+}
+
+// checkVATIDES verifies the control character of the Spanish NIF. Its first
+// character tells the kind: a digit for Spanish citizens (DNI), X, Y or Z for
+// foreigners (NIE), K, L or M for special cases, and a letter for legal
+// entities (CIF), whose control is a Luhn digit or the letter standing for it.
+func checkVATIDES(number string) bool {
+	const dniLetters = "TRWAGMYFPDXBNJZSQVHLCKE"
+	first, body, control := number[0], number[1:8], number[8]
+	switch {
+	case first >= '0' && first <= '9':
+		n, _ := strconv.Atoi(number[:8])
+		return dniLetters[n%23] == control
+	case first == 'X' || first == 'Y' || first == 'Z':
+		n, _ := strconv.Atoi(string('0'+first-'X') + body)
+		return dniLetters[n%23] == control
+	case first == 'K' || first == 'L' || first == 'M':
+		n, _ := strconv.Atoi(body)
+		return dniLetters[n%23] == control
+	case strings.IndexByte("ABCDEFGHJNPQRSUVW", first) >= 0:
+		for c := 0; c <= 9; c++ {
+			if checkLuhn(body + strconv.Itoa(c)) {
+				return control == byte('0'+c) || control == "JABCDEFGHI"[c]
+			}
+		}
+	}
+	return false
+}
+
+// checkVATIDIE verifies the check letter of current numbers (7 digits, the
+// check letter and an optional second letter) and of the old format, whose
+// second character is a letter, + or *.
+func checkVATIDIE(number string) bool {
+	const alphabet = "WABCDEFGHIJKLMNOPQRSTUV"
+	if number[1] < '0' || number[1] > '9' {
+		number = "0" + number[2:7] + number[:1] + number[7:]
+	}
+	sum := weightedSum(digits(number[:7]), 8, 7, 6, 5, 4, 3, 2)
+	if len(number) == 9 {
+		sum += 9 * strings.IndexByte(alphabet, number[8])
+	}
+	return alphabet[sum%23] == number[7]
+}
+
+// checkVATIDLT verifies the last digit with weights 1 to 9 repeating, falling
+// back to weights starting at 3 when the first pass gives 10.
+func checkVATIDLT(number string) bool {
+	d := digits(number)
+	n := len(d) - 1
+	sum, alt := 0, 0
+	for i, v := range d[:n] {
+		sum += (1 + i%9) * v
+		alt += (1 + (i+2)%9) * v
+	}
+	check := sum % 11
+	if check == 10 {
+		check = alt % 11
+	}
+	return check%10 == d[n]
+}
+
+// checkVATIDLV verifies company numbers, which start with a digit above 3,
+// and personal codes. Personal codes starting with 32 carry no check digit.
+func checkVATIDLV(number string) bool {
+	d := digits(number)
+	if d[0] > 3 {
+		return weightedSum(d, 9, 1, 4, 8, 3, 10, 2, 5, 7, 6, 1)%11 == 3
+	}
+	if strings.HasPrefix(number, "32") {
+		return true
+	}
+	return (1+weightedSum(d, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9))%11%10 == d[10]
+}
+
+// checkVATIDXI verifies UK VAT numbers as issued in Northern Ireland: the
+// first 9 digits weighted 8 to 2, 10 and 1 must be 0 (old numbers) or 42
+// (numbers since 2010) modulo 97. Government departments (GD) are numbered
+// below 500, health authorities (HA) from 500.
+func checkVATIDXI(number string) bool {
+	switch number[:2] {
+	case "GD":
+		n, _ := strconv.Atoi(number[2:])
+		return n < 500
+	case "HA":
+		n, _ := strconv.Atoi(number[2:])
+		return n >= 500
+	}
+	r := weightedSum(digits(number[:9]), 8, 7, 6, 5, 4, 3, 2, 10, 1) % 97
+	return r == 0 || r == 42
+}
+
+func checkVATIDDK(number string) bool {
+	return weightedSum(digits(number), 2, 7, 6, 5, 4, 3, 2, 1)%11 == 0
+}
+
+func checkVATIDEE(number string) bool {
+	d := digits(number)
+	return (10-weightedSum(d, 3, 7, 1, 3, 7, 1, 3, 7)%10)%10 == d[8]
+}
+
+func checkVATIDEL(number string) bool {
+	d := digits(number)
+	return weightedSum(d, 256, 128, 64, 32, 16, 8, 4, 2)%11%10 == d[8]
+}
+
+func checkVATIDFI(number string) bool {
+	d := digits(number)
+	r := weightedSum(d, 7, 9, 10, 5, 8, 4, 2) % 11
+	switch r {
+	case 0:
+		return d[7] == 0
+	case 1:
+		return false
+	}
+	return 11-r == d[7]
+}
+
+// checkVATIDFR verifies numeric keys against the SIREN; alphanumeric keys
+// have no published algorithm.
+func checkVATIDFR(number string) bool {
+	key, err := strconv.Atoi(number[:2])
+	if err != nil {
+		return true
+	}
+	siren, _ := strconv.Atoi(number[2:])
+	return key == (12+3*(siren%97))%97
+}
+
+func checkVATIDHU(number string) bool {
+	d := digits(number)
+	return (10-weightedSum(d, 9, 7, 3, 1, 9, 7, 3)%10)%10 == d[7]
+}
+
+func checkVATIDLU(number string) bool {
+	base, _ := strconv.Atoi(number[:6])
+	check, _ := strconv.Atoi(number[6:])
+	return base%89 == check
+}
+
+func checkVATIDMT(number string) bool {
+	d := digits(number)
+	check, _ := strconv.Atoi(number[6:])
+	return (weightedSum(d, 3, 4, 6, 7, 8, 9)+check)%37 == 0
+}
+
+// checkVATIDNL accepts the former BSN based mod 11 numbers and the current
+// mod 97 numbers, which are checked including the NL prefix.
+func checkVATIDNL(number string) bool {
+	d := digits(number[:9])
+	if (weightedSum(d, 9, 8, 7, 6, 5, 4, 3, 2)-d[8])%11 == 0 {
+		return true
+	}
+	return mod97("NL"+number) == 1
+}
+
+func checkVATIDPL(number string) bool {
+	d := digits(number)
+	r := weightedSum(d, 6, 5, 7, 2, 3, 4, 5, 6, 7) % 11
+	return r != 10 && r == d[9]
+}
+
+func checkVATIDPT(number string) bool {
+	d := digits(number)
+	check := 11 - weightedSum(d, 9, 8, 7, 6, 5, 4, 3, 2)%11
+	if check >= 10 {
+		check = 0
+	}
+	return check == d[8]
+}
+
+func checkVATIDRO(number string) bool {
+	d := digits(strings.Repeat("0", 10-len(number)) + number)
+	return weightedSum(d, 7, 5, 3, 2, 1, 7, 5, 3, 2)*10%11%10 == d[9]
+}
+
+func checkVATIDSE(number string) bool {
+	return checkLuhn(number[:10])
+}
+
+func checkVATIDSI(number string) bool {
+	d := digits(number)
+	r := weightedSum(d, 8, 7, 6, 5, 4, 3, 2) % 11
+	if r == 0 {
+		return false
+	}
+	check := 11 - r
+	if check == 10 {
+		check = 0
+	}
+	return check == d[7]
+}
+
+func checkVATIDSK(number string) bool {
+	n, _ := strconv.ParseInt(number, 10, 64)
+	return n%11 == 0
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateVATIDAustria(t *testing.T) {
+	tests := []struct {
+		vatID        string
+		wantChecksum bool
+		wantErr      bool
+	}{
+		{"ATU13585627", false, false},
+		{"ATU38516405", false, false},
+		{"ATU87654324", false, false},
+		{"ATU13585628", true, true},
+		{"ATU87654321", true, true},
+		{"ATU12345678", true, true},
+		{"ATU1358562", false, true},
+		{"AT13585627", false, true},
+		{"atu13585627", false, true},
+	}
+	for _, tt := range tests {
+		err := validateVATID(tt.vatID)
+		var checksum *errVATIDChecksum
+		if (err != nil) != tt.wantErr || errors.As(err, &checksum) != tt.wantChecksum {
+			t.Errorf("validateVATID(%q) = %v, want error %v, checksum error %v", tt.vatID, err, tt.wantErr, tt.wantChecksum)
+		}
+	}
+}
+
+// The API reports VAT_ID_CHECKSUM_INVALID with details starting with the
+// field's JSON path, which static/script.js uses to point at the field.
+func TestValidateInvoiceVATIDChecksumField(t *testing.T) {
+	inv := readGoldenInvoice(t)
+	inv.Recipient.VATID = "ATU87654321"
+	err := validateInvoice(inv)
+	var checksum *errVATIDChecksum
+	if !errors.As(err, &checksum) {
+		t.Fatalf("got %v, want a checksum error", err)
+	}
+	if !strings.HasPrefix(err.Error(), "recipient.vat_id: ") {
+		t.Errorf("error %q does not start with the field", err)
+	}
+}
+
+func TestValidateVATIDMemberStates(t *testing.T) {
+	// Each valid number is followed by the same number with a wrong check digit.
+	tests := []struct {
+		valid, invalid string
+	}{
+		{"BE0428759497", "BE0428759498"},
+		{"DE136695976", "DE136695977"},
+		{"DK13585628", "DK13585627"},
+		{"EE100931558", "EE100931559"},
+		{"EL094259216", "EL094259217"},
+		{"FI20774740", "FI20774741"},
+		{"FR40303265045", "FR41303265045"},
+		{"HR33392005961", "HR33392005962"},
+		{"HU12892312", "HU12892313"},
+		{"IT00743110157", "IT00743110158"},
+		{"LU15027442", "LU15027443"},
+		{"MT11679112", "MT11679113"},
+		{"NL004495445B01", "NL004495446B01"},
+		{"PL8567346215", "PL8567346216"},
+		{"PT501964843", "PT501964844"},
+		{"RO18547290", "RO18547291"},
+		{"SE123456789701", "SE123456789801"},
+		{"SI50223054", "SI50223055"},
+		{"SK2022749619", "SK2022749610"},
+		{"BG175074752", "BG175074753"},   // company
+		{"BG7523169263", "BG7523169264"}, // personal number
+		{"CY10259033P", "CY10259033Q"},
+		{"CZ25123891", "CZ25123892"},     // company
+		{"CZ7103192745", "CZ7103192746"}, // birth number
+		{"ESA13585625", "ESA13585626"},   // legal entity, Luhn digit
+		{"ESB58378431", "ESB58378432"},
+		{"ES54362315K", "ES54362315L"}, // DNI
+		{"ESX2482300W", "ESX2482300X"}, // NIE
+		{"IE6433435F", "IE6433435E"},
+		{"IE3628739UA", "IE3628739UB"}, // second letter
+		{"IE8D79739I", "IE8D79739J"},   // old format
+		{"LT119511515", "LT119511516"},
+		{"LT100001919017", "LT100001919018"},
+		{"LV40003521600", "LV40003521601"}, // company
+		{"LV16117519997", "LV16117519998"}, // personal code
+		{"XI980780684", "XI980780685"},
+		{"XIGD001", "XIGD500"}, // government department below 500
+		{"XIHA500", "XIHA499"}, // health authority from 500
+	}
+	for _, tt := range tests {
+		if err := validateVATID(tt.valid); err != nil {
+			t.Errorf("validateVATID(%q) = %v", tt.valid, err)
+		}
+		var checksum *errVATIDChecksum
+		if err := validateVATID(tt.invalid); !errors.As(err, &checksum) {
+			t.Errorf("validateVATID(%q) = %v, want a checksum error", tt.invalid, err)
+		}
+	}
+}
+
+func TestValidateVATIDFormats(t *testing.T) {
+	tests := []struct {
+		vatID   string
+		wantErr string
+	}{
+		{"NL000099998B57", ""}, // mod 97 number
+		{"FRK7399859412", ""},  // alphanumeric key, not checked
+		{"ESJ1234567", "ES followed by 9 characters"},
+		{"XI12345678", "XI followed by 9 or 12 digits"},
+		{"CZ640903926", ""},       // birth number before 1954, no check digit
+		{"LV32123456789", ""},     // personal code without check digit
+		{"GR094259216", "prefix"}, // Greece uses EL
+		{"DE12345678", "DE followed by 9 digits"},
+		{"NL004495445C01", "NL followed by 9 digits, B and 2 digits"},
+		{"SE123456789702", "SE followed by 10 digits and 01"},
+		{"", "prefix"},
+	}
+	for _, tt := range tests {
+		err := validateVATID(tt.vatID)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validateVATID(%q) = %v", tt.vatID, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validateVATID(%q) = %v, want an error containing %q", tt.vatID, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidatePartyVATID(t *testing.T) {
+	tests := []struct {
+		vatID, country string
+		wantErr        bool
+	}{
+		{"ATU13585627", "AT", false},
+		{"DE136695976", "AT", false},
+		{"CHE-123.456.788 MWST", "CH", false}, // local tax number outside the EU
+		{"123456789", "DE", true},
+		{"ATU87654321", "CH", true}, // EU VAT IDs are checked wherever the party is
+	}
+	for _, tt := range tests {
+		if err := validatePartyVATID(tt.vatID, tt.country); (err != nil) != tt.wantErr {
+			t.Errorf("validatePartyVATID(%q, %q) = %v, want error %v", tt.vatID, tt.country, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVATIDCountryAndEndpointScheme(t *testing.T) {
+	tests := []struct {
+		vatID, country          string
+		wantCountry, wantScheme string
+	}{
+		{"ATU13585627", "AT", "AT", "9914"},
+		{"EL094259216", "GR", "GR", "9933"},
+		{"XI123456789", "GB", "GB", "9932"},
+		{"DK13585628", "DK", "DK", "0184"},
+		{"CHE-123.456.788 MWST", "CH", "", "9927"},
+		{"12-3456789", "US", "", "9914"},
+	}
+	for _, tt := range tests {
+		if got := vatIDCountry(tt.vatID); got != tt.wantCountry {
+			t.Errorf("vatIDCountry(%q) = %q, want %q", tt.vatID, got, tt.wantCountry)
+		}
+		if got := vatEndpointScheme(tt.vatID, tt.country); got != tt.wantScheme {
+			t.Errorf("vatEndpointScheme(%q, %q) = %q, want %q", tt.vatID, tt.country, got, tt.wantScheme)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Business rule identifiers reported by checkEbInterfaceRules.
+const (
+	RuleVATID          = "VAT_ID"
+	RuleVATIDChecksum  = "VAT_ID_CHECKSUM"
+	RuleOrderReference = "B2G_ORDER_REFERENCE"
+	RulePayment        = "PAYMENT_ACCOUNT"
+	RuleDocumentRef    = "DOCUMENT_REFERENCE"
+	RuleLineAmount     = "LINE_AMOUNT"
+	RuleTaxSummary     = "TAX_SUMMARY"
+	RuleTotals         = "TOTALS"
+)
+
+// xmlCursor is an element of a parsed document together with its XPath.
+type xmlCursor struct {
+	node  *xmlNode
+	xpath string
+}
+
+// child returns the first child element with the given local name.
+func (c xmlCursor) child(local string) (xmlCursor, bool) {
+	all := c.all(local)
+	if len(all) == 0 {
+		return xmlCursor{}, false
+	}
+	return all[0], true
+}
+
+// all returns every child element with the given local name.
+func (c xmlCursor) all(local string) []xmlCursor {
+	if c.node == nil {
+		return nil
+	}
+	paths := childXPaths(c.node, c.xpath)
+	var out []xmlCursor
+	for i, n := range c.node.Children {
+		if n.Name.Local == local {
+			out = append(out, xmlCursor{node: n, xpath: paths[i]})
+		}
+	}
+	return out
+}
+
+// children returns every child element in document order.
+func (c xmlCursor) children() []xmlCursor {
+	if c.node == nil {
+		return nil
+	}
+	paths := childXPaths(c.node, c.xpath)
+	out := make([]xmlCursor, len(c.node.Children))
+	for i, n := range c.node.Children {
+		out[i] = xmlCursor{node: n, xpath: paths[i]}
+	}
+	return out
+}
+
+// path follows a chain of child names, e.g. c.path("Tax", "TaxItem").
+func (c xmlCursor) path(locals ...string) (xmlCursor, bool) {
+	cur := c
+	for _, l := range locals {
+		next, ok := cur.child(l)
+		if !ok {
+			return xmlCursor{}, false
+		}
+		cur = next
+	}
+	return cur, true
+}
+
+// text returns the trimmed character data of the element.
+func (c xmlCursor) text() string {
+	if c.node == nil {
+		return ""
+	}
+	return strings.TrimSpace(c.node.Text)
+}
+
+// parseDecimalRat parses an xs:decimal literal exactly.
+func parseDecimalRat(s string) (*big.Rat, bool) {
+	if !xsdDecimalRegex.MatchString(s) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// ratToCents rounds an amount in euros to whole cents, half away from zero.
+func ratToCents(r *big.Rat) int64 {
+	cents := new(big.Rat).Mul(r, big.NewRat(100, 1))
+	num, den := cents.Num(), cents.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	}
+	return q.Int64()
+}
+
+// amountCents reads a decimal amount element as cents.
+func amountCents(c xmlCursor) (int64, bool) {
+	r, ok := parseDecimalRat(c.text())
+	if !ok {
+		return 0, false
+	}
+	return ratToCents(r), true
+}
+
+// ruleChecker collects business rule violations.
+type ruleChecker struct {
+	violations []Violation
+}
+
+func (rc *ruleChecker) report(rule, xpath, format string, args ...any) {
+	rc.violations = append(rc.violations, Violation{Rule: rule, XPath: xpath, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkEbInterfaceRules applies the business rules validateInvoice enforces
+// for JSON input to a parsed ebInterface document: VAT ID and bank account
+// formats, the order reference of the profile, document references of credit
+// memos and the consistency of line, tax and document totals. Missing elements
+// are left to schema validation.
+func checkEbInterfaceRules(root *xmlNode, profile string) []Violation {
+	rc := &ruleChecker{}
+	inv := xmlCursor{node: root, xpath: "/" + root.Name.Local}
+
+	rc.checkParties(inv, profile)
+	rc.checkDocumentReference(inv)
+	rc.checkPayment(inv)
+	rc.checkAmounts(inv)
+	return rc.violations
+}
+
+func (rc *ruleChecker) checkParties(inv xmlCursor, profile string) {
+	for _, party := range []string{"Biller", "InvoiceRecipient", "OrderingParty"} {
+		if vat, ok := inv.path(party, "VATIdentificationNumber"); ok && !(party == "InvoiceRecipient" && vat.text() == ebNoVATID) {
+			country := DefaultCountryCode
+			if c, ok := inv.path(party, "Address", "Country"); ok {
+				country, _ = c.node.attr("CountryCode")
+			}
+			if err := validatePartyVATID(vat.text(), country); err != nil {
+				rule := RuleVATID
+				var checksum *errVATIDChecksum
+				if errors.As(err, &checksum) {
+					rule = RuleVATIDChecksum
+				}
+				rc.report(rule, vat.xpath, "%s", err)
+			}
+		}
+	}
+	recipient, ok := inv.child("InvoiceRecipient")
+	if !ok || !invoiceProfiles[profileName(profile)].OrderIDRequired {
+		return
+	}
+	if id, ok := recipient.path("OrderReference", "OrderID"); !ok || id.text() == "" {
+		rc.report(RuleOrderReference, recipient.xpath, "OrderReference/OrderID is required for profile %s", profileName(profile))
+	}
+}
+
+func (rc *ruleChecker) checkDocumentReference(inv xmlCursor) {
+	docType, _ := inv.node.attr("DocumentType")
+	if docType != "CreditMemo" {
+		return
+	}
+	_, related := inv.child("RelatedDocument")
+	_, cancelled := inv.child("CancelledOriginalDocument")
+	if !related && !cancelled {
+		rc.report(RuleDocumentRef, inv.xpath, "a CreditMemo must reference the original invoice via RelatedDocument or CancelledOriginalDocument")
+	}
+}
+
+func (rc *ruleChecker) checkPayment(inv xmlCursor) {
+	ubt, ok := inv.path("PaymentMethod", "UniversalBankTransaction")
+	if !ok {
+		return
+	}
+	for _, acct := range ubt.all("BeneficiaryAccount") {
+		if iban, ok := acct.child("IBAN"); ok {
+			if err := validateIBAN(iban.text()); err != nil {
+				rc.report(RulePayment, iban.xpath, "%s", err)
+			}
+		}
+		if bic, ok := acct.child("BIC"); ok {
+			if err := validateBIC(bic.text()); err != nil {
+				rc.report(RulePayment, bic.xpath, "%s", err)
+			} else if iban, ok := acct.child("IBAN"); ok {
+				if err := validateBICCountry(bic.text(), iban.text()); err != nil {
+					rc.report(RulePayment, bic.xpath, "%s", err)
+				}
+			}
+		}
+	}
+}
+
+// taxKey identifies a tax bucket by category and rate.
+type taxKey struct {
+	category string
+	rate     string // normalized rate, e.g. "20"
+}
+
+// taxFields names the elements of a tax entry, which differ between
+// ebInterface 6.x (TaxItem) and 5.0 (VATItem).
+type taxFields struct {
+	rate, taxable, amount string
+}
+
+var (
+	taxItemFields = taxFields{rate: "TaxPercent", taxable: "TaxableAmount", amount: "TaxAmount"}
+	vatItemFields = taxFields{rate: "VATRate", taxable: "TaxedAmount", amount: "Amount"}
+)
+
+// readTaxKey reads the rate element rateName (TaxPercent or VATRate) below c.
+// A 5.0 TaxExemption in place of the VATRate stands for rate 0 of the
+// category given as TaxExemptionCode.
+func readTaxKey(c xmlCursor, rateName string) (taxKey, *big.Rat, bool) {
+	pct, ok := c.child(rateName)
+	if !ok {
+		if ex, ok := c.child("TaxExemption"); ok && rateName == vatItemFields.rate {
+			category, _ := ex.node.attr("TaxExemptionCode")
+			return taxKey{category: category, rate: "0.00"}, new(big.Rat), true
+		}
+		return taxKey{}, nil, false
+	}
+	rate, ok := parseDecimalRat(pct.text())
+	if !ok {
+		return taxKey{}, nil, false
+	}
+	category, _ := pct.node.attr("TaxCategoryCode")
+	return taxKey{category: category, rate: rate.FloatString(2)}, rate, true
+}
+
+type taxTally struct {
+	taxableCts int64
+	lines      int
+}
+
+func (rc *ruleChecker) checkAmounts(inv xmlCursor) {
+	tallies := map[taxKey]*taxTally{}
+	tally := func(k taxKey) *taxTally {
+		if tallies[k] == nil {
+			tallies[k] = &taxTally{}
+		}
+		return tallies[k]
+	}
+
+	details, _ := inv.child("Details")
+	for _, list := range details.all("ItemList") {
+		for _, line := range list.all("ListLineItem") {
+			rc.checkLine(line)
+			// 6.x lines carry a TaxItem, 5.0 lines a VATRate applying to LineItemAmount.
+			var key taxKey
+			var taxable xmlCursor
+			okKey, okAmt := false, false
+			if taxItem, ok := line.child("TaxItem"); ok {
+				key, _, okKey = readTaxKey(taxItem, "TaxPercent")
+				taxable, okAmt = taxItem.child("TaxableAmount")
+			} else {
+				key, _, okKey = readTaxKey(line, "VATRate")
+				taxable, okAmt = line.child("LineItemAmount")
+			}
+			if !okKey || !okAmt {
+				continue
+			}
+			if cts, ok := amountCents(taxable); ok {
+				t := tally(key)
+				t.taxableCts += cts
+				t.lines++
+			}
+		}
+	}
+
+	if rs, ok := inv.child("ReductionAndSurchargeDetails"); ok {
+		for _, kind := range []string{"Reduction", "Surcharge"} {
+			for _, entry := range rs.all(kind) {
+				var key taxKey
+				var taxable xmlCursor
+				okKey, okAmt := false, false
+				if taxItem, ok := entry.child("TaxItem"); ok {
+					key, _, okKey = readTaxKey(taxItem, "TaxPercent")
+					taxable, okAmt = taxItem.child("TaxableAmount")
+				} else {
+					key, _, okKey = readTaxKey(entry, "VATRate")
+					taxable, okAmt = entry.child("Amount")
+				}
+				if !okKey || !okAmt {
+					continue
+				}
+				if cts, ok := amountCents(taxable); ok {
+					if kind == "Reduction" {
+						cts = -cts
+					}
+					t := tally(key)
+					t.taxableCts += cts
+					t.lines++
+				}
+			}
+		}
+	}
+
+	var sumTaxableCts, sumTaxCts int64
+	summarized := map[taxKey]bool{}
+	tax, _ := inv.child("Tax")
+	type summaryItem struct {
+		cursor xmlCursor
+		fields taxFields
+	}
+	var items []summaryItem
+	for _, item := range tax.all("TaxItem") {
+		items = append(items, summaryItem{item, taxItemFields})
+	}
+	if vat, ok := tax.child("VAT"); ok {
+		for _, item := range vat.all("VATItem") {
+			items = append(items, summaryItem{item, vatItemFields})
+		}
+	}
+	for _, si := range items {
+		item, fields := si.cursor, si.fields
+		key, rate, ok := readTaxKey(item, fields.rate)
+		if !ok {
+			continue
+		}
+		summarized[key] = true
+		taxable, _ := item.child(fields.taxable)
+		taxableCts, okTaxable := amountCents(taxable)
+		if !okTaxable {
+			continue
+		}
+		sumTaxableCts += taxableCts
+		if t := tallies[key]; t != nil && t.taxableCts != taxableCts {
+			rc.report(RuleTaxSummary, taxable.xpath, "%s %s does not match the sum of line amounts %s for %s%% (%s)",
+				fields.taxable, formatCentsAsDecimal(taxableCts), formatCentsAsDecimal(t.taxableCts), key.rate, key.category)
+		} else if t == nil {
+			rc.report(RuleTaxSummary, item.xpath, "no line item uses tax rate %s%% (%s)", key.rate, key.category)
+		}
+		taxAmount, ok := item.child(fields.amount)
+		if !ok {
+			rc.report(RuleTaxSummary, item.xpath, "%s is required in the tax summary", fields.amount)
+			continue
+		}
+		taxCts, ok := amountCents(taxAmount)
+		if !ok {
+			continue
+		}
+		sumTaxCts += taxCts
+		// Lines may be rounded individually, so allow one cent per contributing line.
+		expected := ratToCents(new(big.Rat).Quo(new(big.Rat).Mul(big.NewRat(taxableCts, 100), rate), big.NewRat(100, 1)))
+		tolerance := int64(1)
+		if t := tallies[key]; t != nil && t.lines > 1 {
+			tolerance = int64(t.lines)
+		}
+		if diff := taxCts - expected; diff > tolerance || diff < -tolerance {
+			rc.report(RuleTaxSummary, taxAmount.xpath, "%s %s does not match %s%% of %s (expected %s)",
+				fields.amount, formatCentsAsDecimal(taxCts), key.rate, formatCentsAsDecimal(taxableCts), formatCentsAsDecimal(expected))
+		}
+	}
+	keys := make([]taxKey, 0, len(tallies))
+	for k := range tallies {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].rate+keys[i].category < keys[j].rate+keys[j].category })
+	for _, k := range keys {
+		if tax.node != nil && !summarized[k] {
+			rc.report(RuleTaxSummary, tax.xpath, "tax summary is missing an entry for %s%% (%s)", k.rate, k.category)
+		}
+	}
+	for _, other := range tax.all("OtherTax") {
+		if amt, ok := other.child("Amount"); ok {
+			if cts, ok := amountCents(amt); ok {
+				sumTaxCts += cts
+			}
+		}
+	}
+
+	gross, okGross := inv.child("TotalGrossAmount")
+	grossCts, okGrossAmt := amountCents(gross)
+	if okGross && okGrossAmt && tax.node != nil {
+		if expected := sumTaxableCts + sumTaxCts; grossCts != expected {
+			rc.report(RuleTotals, gross.xpath, "TotalGrossAmount %s does not equal taxable amounts plus taxes %s",
+				formatCentsAsDecimal(grossCts), formatCentsAsDecimal(expected))
+		}
+	}
+	payable, okPayable := inv.child("PayableAmount")
+	payableCts, okPayableAmt := amountCents(payable)
+	if okGross && okGrossAmt && okPayable && okPayableAmt {
+		expected := grossCts
+		if prepaid, ok := inv.child("PrepaidAmount"); ok {
+			if cts, ok := amountCents(prepaid); ok {
+				expected -= cts
+			}
+		}
+		if rounding, ok := inv.child("RoundingAmount"); ok {
+			if cts, ok := amountCents(rounding); ok {
+				expected += cts
+			}
+		}
+		if payableCts != expected {
+			rc.report(RuleTotals, payable.xpath, "PayableAmount %s does not equal TotalGrossAmount minus PrepaidAmount plus RoundingAmount %s",
+				formatCentsAsDecimal(payableCts), formatCentsAsDecimal(expected))
+		}
+	}
+}
+
+// checkLine verifies Quantity x UnitPrice against LineItemAmount and the line's taxable amount.
+func (rc *ruleChecker) checkLine(line xmlCursor) {
+	amount, ok := line.child("LineItemAmount")
+	if !ok {
+		return
+	}
+	amountCts, ok := amountCents(amount)
+	if !ok {
+		return
+	}
+	if taxable, ok := line.path("TaxItem", "TaxableAmount"); ok {
+		if cts, ok := amountCents(taxable); ok && cts != amountCts {
+			rc.report(RuleLineAmount, taxable.xpath, "TaxableAmount %s does not equal LineItemAmount %s",
+				formatCentsAsDecimal(cts), formatCentsAsDecimal(amountCts))
+		}
+	}
+	if _, adjusted := line.child("ReductionAndSurchargeListLineItemDetails"); adjusted {
+		return
+	}
+	qty, okQty := line.child("Quantity")
+	price, okPrice := line.child("UnitPrice")
+	if !okQty || !okPrice {
+		return
+	}
+	q, okQ := parseDecimalRat(qty.text())
+	p, okP := parseDecimalRat(price.text())
+	if !okQ || !okP {
+		return
+	}
+	net := new(big.Rat).Mul(q, p)
+	if base, ok := price.node.attr("BaseQuantity"); ok {
+		if b, ok := parseDecimalRat(base); ok && b.Sign() != 0 {
+			net.Quo(net, b)
+		}
+	}
+	if expected := ratToCents(net); expected != amountCts {
+		rc.report(RuleLineAmount, amount.xpath, "LineItemAmount %s does not equal Quantity x UnitPrice %s",
+			formatCentsAsDecimal(amountCts), formatCentsAsDecimal(expected))
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func renderTestDocument(t *testing.T, version string) string {
+	t.Helper()
+	doc, err := TransformToEbInterfaceVersion(readTestInvoice(t, "test_invoice_small.json"), version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(doc)
+}
+
+func TestCheckEbInterfaceRules(t *testing.T) {
+	// test_invoice_small.json: one line of 4500.00 at 20 %, 900.00 tax, 5400.00 gross.
+	tests := []struct {
+		name      string
+		version   string
+		profile   string
+		old, new  string
+		wantRule  string
+		wantXPath string
+	}{
+		{name: "valid 6.1", version: "6.1"},
+		{name: "valid 5.0", version: "5.0"},
+		{
+			name: "vat id format", version: "6.1",
+			old: "<VATIdentificationNumber>ATU13585627", new: "<VATIdentificationNumber>ATX13585627",
+			wantRule: RuleVATID, wantXPath: "/Invoice/Biller/VATIdentificationNumber",
+		},
+		{
+			name: "vat id checksum", version: "6.1",
+			old: "<VATIdentificationNumber>ATU38516405", new: "<VATIdentificationNumber>ATU38516406",
+			wantRule: RuleVATIDChecksum, wantXPath: "/Invoice/InvoiceRecipient/VATIdentificationNumber",
+		},
+		{
+			name: "federal order reference", version: "6.1",
+			old: "<OrderReference>\n      <OrderID>1234567890</OrderID>\n    </OrderReference>", new: "",
+			wantRule: RuleOrderReference, wantXPath: "/Invoice/InvoiceRecipient",
+		},
+		{
+			name: "b2b without order reference", version: "6.1", profile: ProfileB2B,
+			old: "<OrderReference>\n      <OrderID>1234567890</OrderID>\n    </OrderReference>", new: "",
+		},
+		{
+			name: "iban", version: "6.1",
+			old: "<IBAN>AT611904300234573201", new: "<IBAN>AT611904300234573202",
+			wantRule: RulePayment, wantXPath: "/Invoice/PaymentMethod/UniversalBankTransaction/BeneficiaryAccount/IBAN",
+		},
+		{
+			name: "bic country", version: "6.1",
+			old: "<BIC>BKAUATWW", new: "<BIC>COBADEFF",
+			wantRule: RulePayment, wantXPath: "/Invoice/PaymentMethod/UniversalBankTransaction/BeneficiaryAccount/BIC",
+		},
+		{
+			name: "credit memo without reference", version: "6.1",
+			old: `DocumentType="Invoice"`, new: `DocumentType="CreditMemo"`,
+			wantRule: RuleDocumentRef, wantXPath: "/Invoice",
+		},
+		{
+			name: "line amount", version: "6.1",
+			old: "<UnitPrice>4500.00", new: "<UnitPrice>4000.00",
+			wantRule: RuleLineAmount, wantXPath: "/Invoice/Details/ItemList/ListLineItem/LineItemAmount",
+		},
+		{
+			name: "line taxable amount", version: "6.1",
+			old: "<TaxItem>\n          <TaxableAmount>4500.00", new: "<TaxItem>\n          <TaxableAmount>4000.00",
+			wantRule: RuleLineAmount, wantXPath: "/Invoice/Details/ItemList/ListLineItem/TaxItem/TaxableAmount",
+		},
+		{
+			name: "tax amount", version: "6.1",
+			old: "<TaxAmount>900.00", new: "<TaxAmount>901.00",
+			wantRule: RuleTaxSummary, wantXPath: "/Invoice/Tax/TaxItem/TaxAmount",
+		},
+		{
+			name: "tax summary taxable amount", version: "6.1",
+			old: "<TaxItem>\n      <TaxableAmount>4500.00", new: "<TaxItem>\n      <TaxableAmount>4000.00",
+			wantRule: RuleTaxSummary, wantXPath: "/Invoice/Tax/TaxItem/TaxableAmount",
+		},
+		{
+			name: "tax summary rate without lines", version: "6.1",
+			old: `<TaxAmount>900.00`, new: `<TaxAmount>900.00</TaxAmount></TaxItem><TaxItem><TaxableAmount>0.00</TaxableAmount><TaxPercent TaxCategoryCode="AA">10</TaxPercent><TaxAmount>0.00`,
+			wantRule: RuleTaxSummary, wantXPath: "/Invoice/Tax/TaxItem[2]",
+		},
+		{
+			name: "5.0 vat amount", version: "5.0",
+			old: "<Amount>900.00", new: "<Amount>901.00",
+			wantRule: RuleTaxSummary, wantXPath: "/Invoice/Tax/VAT/VATItem/Amount",
+		},
+		{
+			name: "gross amount", version: "6.1",
+			old: "<TotalGrossAmount>5400.00", new: "<TotalGrossAmount>5500.00",
+			wantRule: RuleTotals, wantXPath: "/Invoice/TotalGrossAmount",
+		},
+		{
+			name: "payable amount", version: "6.1",
+			old: "<PayableAmount>5400.00", new: "<PayableAmount>5300.00",
+			wantRule: RuleTotals, wantXPath: "/Invoice/PayableAmount",
+		},
+		{
+			name: "prepaid amount", version: "6.1",
+			old: "<PayableAmount>5400.00", new: "<PrepaidAmount>400.00</PrepaidAmount><PayableAmount>5000.00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := renderTestDocument(t, tt.version)
+			mutated := strings.Replace(doc, tt.old, tt.new, 1)
+			if tt.old != "" && mutated == doc {
+				t.Fatalf("mutation %q not found", tt.old)
+			}
+			root, err := parseXMLTree([]byte(mutated))
+			if err != nil {
+				t.Fatal(err)
+			}
+			violations := checkEbInterfaceRules(root, tt.profile)
+			if tt.wantRule == "" {
+				if len(violations) > 0 {
+					t.Errorf("unexpected violations: %v", violations)
+				}
+				return
+			}
+			for _, v := range violations {
+				if v.Rule == tt.wantRule && v.XPath == tt.wantXPath {
+					return
+				}
+			}
+			t.Errorf("no %s violation at %s: %v", tt.wantRule, tt.wantXPath, violations)
+		})
+	}
+}
+
+func TestValidateXMLHandler(t *testing.T) {
+	doc := renderTestDocument(t, "6.1")
+	multipartBody := func(data string) (*bytes.Buffer, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, err := mw.CreateFormFile("file", "invoice.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(data))
+		mw.Close()
+		return &buf, mw.FormDataContentType()
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		body       string
+		multipart  bool
+		wantStatus int
+		wantCode   string // error code of a non-200 response
+		wantValid  bool
+		wantRules  []string
+		wantXPaths []string
+	}{
+		{name: "valid document", method: http.MethodPost, body: doc, wantStatus: http.StatusOK, wantValid: true},
+		{name: "valid multipart upload", method: http.MethodPost, body: doc, multipart: true, wantStatus: http.StatusOK, wantValid: true},
+		{
+			name: "schema and rule violations", method: http.MethodPost,
+			body:       strings.Replace(strings.Replace(doc, "<PayableAmount>5400.00", "<PayableAmount>5300.00", 1), `Language="de"`, `Language="de" Foo="bar"`, 1),
+			wantStatus: http.StatusOK,
+			wantRules:  []string{RuleSchema, RuleTotals},
+			wantXPaths: []string{"/Invoice/@Foo", "/Invoice/PayableAmount"},
+		},
+		{
+			name: "profile from query", method: http.MethodPost, query: "?profile=b2b",
+			body:       strings.Replace(doc, "<OrderReference>\n      <OrderID>1234567890</OrderID>\n    </OrderReference>", "", 1),
+			wantStatus: http.StatusOK, wantValid: true,
+		},
+		{name: "unknown profile", method: http.MethodPost, query: "?profile=retail", body: doc, wantStatus: http.StatusBadRequest, wantCode: ErrCodeValidationError},
+		{name: "malformed xml", method: http.MethodPost, body: "<Invoice>", wantStatus: http.StatusBadRequest, wantCode: ErrCodeInvalidXML},
+		{name: "unsupported namespace", method: http.MethodPost, body: `<Invoice xmlns="urn:other"/>`, wantStatus: http.StatusBadRequest, wantCode: ErrCodeInvalidXML},
+		{name: "multipart without file", method: http.MethodPost, body: "", multipart: true, wantStatus: http.StatusBadRequest, wantCode: ErrCodeInvalidXML},
+		{name: "get", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed, wantCode: ErrCodeInternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			switch {
+			case tt.multipart && tt.body != "":
+				body, contentType := multipartBody(tt.body)
+				req = httptest.NewRequest(tt.method, "/validate-xml"+tt.query, body)
+				req.Header.Set("Content-Type", contentType)
+			case tt.multipart:
+				req = httptest.NewRequest(tt.method, "/validate-xml"+tt.query, strings.NewReader(""))
+				req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+			default:
+				req = httptest.NewRequest(tt.method, "/validate-xml"+tt.query, strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/xml")
+			}
+			rec := httptest.NewRecorder()
+			validateXMLHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type %q", ct)
+			}
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Code != tt.wantCode || resp.Error.Message == "" {
+					t.Errorf("error %+v, want code %s", resp.Error, tt.wantCode)
+				}
+				return
+			}
+
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"valid", "namespace", "violations"} {
+				if _, ok := raw[key]; !ok {
+					t.Errorf("response has no %q field: %s", key, rec.Body)
+				}
+			}
+			var result XMLValidationResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != tt.wantValid || result.Namespace != ebInterface61Namespace {
+				t.Errorf("valid %v, namespace %q", result.Valid, result.Namespace)
+			}
+			if tt.wantValid && string(raw["violations"]) != "[]" {
+				t.Errorf("violations = %s, want []", raw["violations"])
+			}
+			for i, rule := range tt.wantRules {
+				found := false
+				for _, v := range result.Violations {
+					if v.Rule == rule && v.XPath == tt.wantXPaths[i] && v.Message != "" {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("no %s violation at %s: %v", rule, tt.wantXPaths[i], result.Violations)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,1332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Minimal XML Schema (XSD 1.0) engine used to validate ebInterface documents
+// offline. It supports the constructs found in the embedded schemas:
+// global/local elements, named and anonymous types, sequence/choice/all,
+// xs:any wildcards, attributes, simple/complex content derivation, group
+// references and the common restriction facets. Identity constraints and
+// substitution groups are not evaluated.
+
+const (
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// -------- Generic XML tree --------
+
+// xmlNode is a generic element tree used both for schema documents and for
+// the instance documents being validated.
+type xmlNode struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Children []*xmlNode
+	Text     string
+	ns       map[string]string // in-scope prefix -> namespace, used to resolve QName attribute values
+}
+
+// parseXMLTree reads a whole document into an xmlNode tree.
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	return buildXMLTree(data, false)
+}
+
+// parseSchemaTree reads a schema document into an xmlNode tree. Unlike
+// instance documents, schemas may declare internal entities in their DOCTYPE
+// (the W3C xmldsig schema does), which are honoured here.
+func parseSchemaTree(data []byte) (*xmlNode, error) {
+	return buildXMLTree(data, true)
+}
+
+// xmlEntityDecl matches a general internal entity declaration in a DOCTYPE.
+var xmlEntityDecl = regexp.MustCompile(`<!ENTITY\s+([A-Za-z_][\w.-]*)\s+(?:'([^']*)'|"([^"]*)")\s*>`)
+
+func buildXMLTree(data []byte, entities bool) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{Name: t.Name, Attrs: t.Attr}
+			var parentNS map[string]string
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+				parentNS = parent.ns
+			} else if root == nil {
+				root = n
+			} else {
+				return nil, fmt.Errorf("multiple root elements")
+			}
+			n.ns = parentNS
+			copied := false
+			for _, a := range t.Attr {
+				prefix, ok := "", false
+				if a.Name.Space == "xmlns" {
+					prefix, ok = a.Name.Local, true
+				} else if a.Name.Space == "" && a.Name.Local == "xmlns" {
+					ok = true
+				}
+				if !ok {
+					continue
+				}
+				if !copied {
+					n.ns = make(map[string]string, len(parentNS)+1)
+					for k, v := range parentNS {
+						n.ns[k] = v
+					}
+					copied = true
+				}
+				n.ns[prefix] = a.Value
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		case xml.Directive:
+			if !entities || !bytes.HasPrefix(t, []byte("DOCTYPE")) {
+				continue
+			}
+			for _, m := range xmlEntityDecl.FindAllSubmatch(t, -1) {
+				if dec.Entity == nil {
+					dec.Entity = map[string]string{}
+				}
+				dec.Entity[string(m[1])] = string(m[2]) + string(m[3])
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+	return root, nil
+}
+
+// attr returns the value of the unqualified attribute name.
+func (n *xmlNode) attr(name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// resolveQName resolves a prefixed name (e.g. "xs:string") using the namespaces in scope of n.
+func (n *xmlNode) resolveQName(q string) xml.Name {
+	prefix, local := "", q
+	if i := strings.IndexByte(q, ':'); i >= 0 {
+		prefix, local = q[:i], q[i+1:]
+	}
+	return xml.Name{Space: n.ns[prefix], Local: local}
+}
+
+// -------- Compiled schema model --------
+
+type xsdParticleKind int
+
+const (
+	particleElement xsdParticleKind = iota
+	particleSequence
+	particleChoice
+	particleAll
+	particleAny
+)
+
+// xsdParticle is a node of a content model with its occurrence constraints.
+type xsdParticle struct {
+	kind     xsdParticleKind
+	min, max int // max < 0 means unbounded
+	elem     *xsdElement
+	items    []*xsdParticle
+	anyNS    string // namespace constraint of xs:any (##any, ##other, ##targetNamespace or a URI list)
+	targetNS string
+	skip     bool // processContents="skip"
+}
+
+type xsdElement struct {
+	name xml.Name
+	doc  *xsdDoc
+	typ  *xsdType
+}
+
+type xsdAttribute struct {
+	name     string
+	typ      *xsdSimpleType
+	required bool
+	fixed    string
+}
+
+// xsdType is a compiled complex type. Simple types used as element types are
+// wrapped with only the simple field set.
+type xsdType struct {
+	simple     *xsdSimpleType
+	attrs      map[string]*xsdAttribute
+	anyAttr    bool
+	content    *xsdParticle
+	mixed      bool
+	anyContent bool
+	decls      map[xml.Name]*xsdElement
+}
+
+// xsdSimpleType is one derivation step of a simple type. Facets of a step are
+// checked after the value has been accepted by the base type.
+type xsdSimpleType struct {
+	builtin        string
+	base           *xsdSimpleType
+	enums          []string
+	patterns       []xsdPattern
+	length         int
+	minLength      int
+	maxLength      int
+	minInclusive   string
+	maxInclusive   string
+	minExclusive   string
+	maxExclusive   string
+	totalDigits    int
+	fractionDigits int
+	list           *xsdSimpleType
+	union          []*xsdSimpleType
+}
+
+// xsdDoc holds the per-file settings of a schema document.
+type xsdDoc struct {
+	targetNS  string
+	qualified bool
+}
+
+type xsdDef struct {
+	node *xmlNode
+	doc  *xsdDoc
+}
+
+// xsdSchema is a set of schema documents compiled on demand.
+type xsdSchema struct {
+	elementDefs   map[xml.Name]xsdDef
+	typeDefs      map[xml.Name]xsdDef
+	groupDefs     map[xml.Name]xsdDef
+	attrGroupDefs map[xml.Name]xsdDef
+	attrDefs      map[xml.Name]xsdDef
+
+	elements    map[xml.Name]*xsdElement
+	types       map[xml.Name]*xsdType
+	simpleTypes map[xml.Name]*xsdSimpleType
+}
+
+// xsdCatalog maps well-known remote schemaLocations to the file name of a
+// local copy, so the official schemas can be embedded unchanged.
+var xsdCatalog = map[string]string{
+	"http://www.w3.org/TR/2002/REC-xmldsig-core-20020212/xmldsig-core-schema.xsd": "xmldsig-core-schema.xsd",
+	"http://www.w3.org/TR/xmldsig-core/xmldsig-core-schema.xsd":                   "xmldsig-core-schema.xsd",
+}
+
+// loadXSD parses the schema file at name in fsys, following include and import
+// directives whose schemaLocation is available in the same file system, either
+// relative to the including file or through xsdCatalog.
+func loadXSD(fsys fs.FS, name string) (*xsdSchema, error) {
+	s := &xsdSchema{
+		elementDefs:   map[xml.Name]xsdDef{},
+		typeDefs:      map[xml.Name]xsdDef{},
+		groupDefs:     map[xml.Name]xsdDef{},
+		attrGroupDefs: map[xml.Name]xsdDef{},
+		attrDefs:      map[xml.Name]xsdDef{},
+		elements:      map[xml.Name]*xsdElement{},
+		types:         map[xml.Name]*xsdType{},
+		simpleTypes:   map[xml.Name]*xsdSimpleType{},
+	}
+	if err := s.addFile(fsys, name, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	// Compile everything up front so that schema errors surface at load time.
+	for q := range s.elementDefs {
+		if _, err := s.element(q); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *xsdSchema) addFile(fsys fs.FS, name string, seen map[string]bool) error {
+	if seen[name] {
+		return nil
+	}
+	seen[name] = true
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("read schema %s: %w", name, err)
+	}
+	root, err := parseSchemaTree(data)
+	if err != nil {
+		return fmt.Errorf("parse schema %s: %w", name, err)
+	}
+	if root.Name.Space != xsdNamespace || root.Name.Local != "schema" {
+		return fmt.Errorf("%s is not an XML schema", name)
+	}
+	doc := &xsdDoc{}
+	doc.targetNS, _ = root.attr("targetNamespace")
+	if v, _ := root.attr("elementFormDefault"); v == "qualified" {
+		doc.qualified = true
+	}
+	for _, c := range root.Children {
+		if c.Name.Space != xsdNamespace {
+			continue
+		}
+		q := xml.Name{Space: doc.targetNS}
+		q.Local, _ = c.attr("name")
+		switch c.Name.Local {
+		case "include", "import", "redefine":
+			loc, ok := c.attr("schemaLocation")
+			if !ok {
+				continue
+			}
+			if strings.Contains(loc, "://") {
+				if loc, ok = xsdCatalog[loc]; !ok {
+					continue // imports without a local copy are treated laxly
+				}
+			}
+			p := path.Join(path.Dir(name), loc)
+			if _, err := fs.Stat(fsys, p); err != nil {
+				continue
+			}
+			if err := s.addFile(fsys, p, seen); err != nil {
+				return err
+			}
+		case "element":
+			s.elementDefs[q] = xsdDef{c, doc}
+		case "complexType", "simpleType":
+			s.typeDefs[q] = xsdDef{c, doc}
+		case "group":
+			s.groupDefs[q] = xsdDef{c, doc}
+		case "attributeGroup":
+			s.attrGroupDefs[q] = xsdDef{c, doc}
+		case "attribute":
+			s.attrDefs[q] = xsdDef{c, doc}
+		}
+	}
+	return nil
+}
+
+// element returns the compiled global element declaration q.
+func (s *xsdSchema) element(q xml.Name) (*xsdElement, error) {
+	if e, ok := s.elements[q]; ok {
+		return e, nil
+	}
+	def, ok := s.elementDefs[q]
+	if !ok {
+		return nil, nil
+	}
+	e := &xsdElement{name: q, doc: def.doc}
+	s.elements[q] = e
+	if err := s.resolveElementType(e, def.node); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// compileAll compiles every global element and type, so errors in parts of a
+// schema that no document has used yet surface immediately.
+func (s *xsdSchema) compileAll() error {
+	for q := range s.elementDefs {
+		if _, err := s.element(q); err != nil {
+			return fmt.Errorf("element %s: %w", q.Local, err)
+		}
+	}
+	for q := range s.typeDefs {
+		if _, err := s.typeByName(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *xsdSchema) resolveElementType(e *xsdElement, n *xmlNode) error {
+	if t, ok := n.attr("type"); ok {
+		typ, err := s.typeByName(n.resolveQName(t))
+		if err != nil {
+			return fmt.Errorf("element %s: %w", e.name.Local, err)
+		}
+		e.typ = typ
+		return nil
+	}
+	for _, c := range xsdChildren(n) {
+		switch c.Name.Local {
+		case "complexType":
+			t, err := s.compileComplexType(c, e.doc, &xsdType{})
+			if err != nil {
+				return fmt.Errorf("element %s: %w", e.name.Local, err)
+			}
+			e.typ = t
+			return nil
+		case "simpleType":
+			st, err := s.compileSimpleType(c)
+			if err != nil {
+				return fmt.Errorf("element %s: %w", e.name.Local, err)
+			}
+			e.typ = &xsdType{simple: st}
+			return nil
+		}
+	}
+	e.typ = &xsdType{anyContent: true}
+	return nil
+}
+
+// typeByName returns a named complex or simple type as an element type.
+func (s *xsdSchema) typeByName(q xml.Name) (*xsdType, error) {
+	if q.Space == xsdNamespace {
+		if q.Local == "anyType" {
+			return &xsdType{anyContent: true}, nil
+		}
+		st, err := s.simpleTypeByName(q)
+		if err != nil {
+			return nil, err
+		}
+		return &xsdType{simple: st}, nil
+	}
+	if t, ok := s.types[q]; ok {
+		return t, nil
+	}
+	def, ok := s.typeDefs[q]
+	if !ok {
+		return &xsdType{anyContent: true}, nil // type from a namespace without local schema
+	}
+	if def.node.Name.Local == "simpleType" {
+		st, err := s.simpleTypeByName(q)
+		if err != nil {
+			return nil, err
+		}
+		t := &xsdType{simple: st}
+		s.types[q] = t
+		return t, nil
+	}
+	t := &xsdType{}
+	s.types[q] = t // registered before compiling to support recursive types
+	if _, err := s.compileComplexType(def.node, def.doc, t); err != nil {
+		return nil, fmt.Errorf("type %s: %w", q.Local, err)
+	}
+	return t, nil
+}
+
+func (s *xsdSchema) simpleTypeByName(q xml.Name) (*xsdSimpleType, error) {
+	if q.Space == xsdNamespace {
+		if q.Local == "anySimpleType" {
+			return builtinType("string"), nil
+		}
+		return builtinType(q.Local), nil
+	}
+	if st, ok := s.simpleTypes[q]; ok {
+		return st, nil
+	}
+	def, ok := s.typeDefs[q]
+	if !ok {
+		return builtinType("string"), nil
+	}
+	if def.node.Name.Local != "simpleType" {
+		// Complex type with simple content used as a base.
+		t, err := s.typeByName(q)
+		if err != nil {
+			return nil, err
+		}
+		if t.simple == nil {
+			return nil, fmt.Errorf("type %s has no simple content", q.Local)
+		}
+		return t.simple, nil
+	}
+	st, err := s.compileSimpleType(def.node)
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %w", q.Local, err)
+	}
+	s.simpleTypes[q] = st
+	return st, nil
+}
+
+// builtinType returns a simple type without any facets.
+func builtinType(name string) *xsdSimpleType {
+	return &xsdSimpleType{builtin: name, length: -1, minLength: -1, maxLength: -1, totalDigits: -1, fractionDigits: -1}
+}
+
+func (s *xsdSchema) compileSimpleType(n *xmlNode) (*xsdSimpleType, error) {
+	for _, c := range xsdChildren(n) {
+		switch c.Name.Local {
+		case "restriction":
+			return s.compileRestriction(c)
+		case "list":
+			st := builtinType("list")
+			var err error
+			if it, ok := c.attr("itemType"); ok {
+				st.list, err = s.simpleTypeByName(c.resolveQName(it))
+			} else if inner := firstXSDChild(c, "simpleType"); inner != nil {
+				st.list, err = s.compileSimpleType(inner)
+			}
+			return st, err
+		case "union":
+			st := builtinType("union")
+			if members, ok := c.attr("memberTypes"); ok {
+				for _, m := range strings.Fields(members) {
+					mt, err := s.simpleTypeByName(c.resolveQName(m))
+					if err != nil {
+						return nil, err
+					}
+					st.union = append(st.union, mt)
+				}
+			}
+			for _, inner := range xsdChildren(c) {
+				if inner.Name.Local != "simpleType" {
+					continue
+				}
+				mt, err := s.compileSimpleType(inner)
+				if err != nil {
+					return nil, err
+				}
+				st.union = append(st.union, mt)
+			}
+			return st, nil
+		}
+	}
+	return builtinType("string"), nil
+}
+
+// compileRestriction compiles the facets of a simple type restriction.
+func (s *xsdSchema) compileRestriction(n *xmlNode) (*xsdSimpleType, error) {
+	st := builtinType("")
+	var err error
+	if b, ok := n.attr("base"); ok {
+		st.base, err = s.simpleTypeByName(n.resolveQName(b))
+	} else if inner := firstXSDChild(n, "simpleType"); inner != nil {
+		st.base, err = s.compileSimpleType(inner)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range xsdChildren(n) {
+		v, _ := f.attr("value")
+		switch f.Name.Local {
+		case "enumeration":
+			st.enums = append(st.enums, v)
+		case "pattern":
+			pat, err := compileXSDPattern(v)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %q: %w", v, err)
+			}
+			st.patterns = append(st.patterns, pat)
+		case "length":
+			st.length, _ = strconv.Atoi(v)
+		case "minLength":
+			st.minLength, _ = strconv.Atoi(v)
+		case "maxLength":
+			st.maxLength, _ = strconv.Atoi(v)
+		case "minInclusive":
+			st.minInclusive = v
+		case "maxInclusive":
+			st.maxInclusive = v
+		case "minExclusive":
+			st.minExclusive = v
+		case "maxExclusive":
+			st.maxExclusive = v
+		case "totalDigits":
+			st.totalDigits, _ = strconv.Atoi(v)
+		case "fractionDigits":
+			st.fractionDigits, _ = strconv.Atoi(v)
+		}
+	}
+	return st, nil
+}
+
+// compileComplexType fills t from a complexType definition.
+func (s *xsdSchema) compileComplexType(n *xmlNode, doc *xsdDoc, t *xsdType) (*xsdType, error) {
+	if v, _ := n.attr("mixed"); v == "true" {
+		t.mixed = true
+	}
+	t.attrs = map[string]*xsdAttribute{}
+	for _, c := range xsdChildren(n) {
+		switch c.Name.Local {
+		case "simpleContent":
+			for _, d := range xsdChildren(c) {
+				if d.Name.Local != "extension" && d.Name.Local != "restriction" {
+					continue
+				}
+				b, _ := d.attr("base")
+				base, err := s.simpleTypeByName(d.resolveQName(b))
+				if err != nil {
+					return nil, err
+				}
+				baseType, err := s.typeByName(d.resolveQName(b))
+				if err != nil {
+					return nil, err
+				}
+				for k, a := range baseType.attrs {
+					t.attrs[k] = a
+				}
+				t.simple = base
+				if d.Name.Local == "restriction" {
+					r, err := s.compileRestriction(d)
+					if err != nil {
+						return nil, err
+					}
+					r.base = base
+					t.simple = r
+				}
+				if err := s.compileAttributes(d, doc, t); err != nil {
+					return nil, err
+				}
+			}
+		case "complexContent":
+			for _, d := range xsdChildren(c) {
+				if d.Name.Local != "extension" && d.Name.Local != "restriction" {
+					continue
+				}
+				b, _ := d.attr("base")
+				baseType, err := s.typeByName(d.resolveQName(b))
+				if err != nil {
+					return nil, err
+				}
+				for k, a := range baseType.attrs {
+					t.attrs[k] = a
+				}
+				t.anyAttr = baseType.anyAttr
+				own, err := s.compileModel(d, doc)
+				if err != nil {
+					return nil, err
+				}
+				if d.Name.Local == "extension" && baseType.content != nil {
+					if own != nil {
+						own = &xsdParticle{kind: particleSequence, min: 1, max: 1, items: []*xsdParticle{baseType.content, own}}
+					} else {
+						own = baseType.content
+					}
+				}
+				t.content = own
+				if err := s.compileAttributes(d, doc, t); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if t.simple == nil && t.content == nil {
+		model, err := s.compileModel(n, doc)
+		if err != nil {
+			return nil, err
+		}
+		t.content = model
+	}
+	if err := s.compileAttributes(n, doc, t); err != nil {
+		return nil, err
+	}
+	t.decls = map[xml.Name]*xsdElement{}
+	collectDecls(t.content, t.decls)
+	return t, nil
+}
+
+// compileModel compiles the first model group (sequence, choice, all or group
+// reference) found below n.
+func (s *xsdSchema) compileModel(n *xmlNode, doc *xsdDoc) (*xsdParticle, error) {
+	for _, c := range xsdChildren(n) {
+		switch c.Name.Local {
+		case "sequence", "choice", "all", "group":
+			return s.compileParticle(c, doc)
+		}
+	}
+	return nil, nil
+}
+
+func (s *xsdSchema) compileParticle(n *xmlNode, doc *xsdDoc) (*xsdParticle, error) {
+	p := &xsdParticle{min: 1, max: 1}
+	if v, ok := n.attr("minOccurs"); ok {
+		p.min, _ = strconv.Atoi(v)
+	}
+	if v, ok := n.attr("maxOccurs"); ok {
+		if v == "unbounded" {
+			p.max = -1
+		} else {
+			p.max, _ = strconv.Atoi(v)
+		}
+	}
+	switch n.Name.Local {
+	case "element":
+		p.kind = particleElement
+		if ref, ok := n.attr("ref"); ok {
+			q := n.resolveQName(ref)
+			e, err := s.element(q)
+			if err != nil {
+				return nil, err
+			}
+			if e == nil {
+				e = &xsdElement{name: q, typ: &xsdType{anyContent: true}}
+			}
+			p.elem = e
+			return p, nil
+		}
+		name, _ := n.attr("name")
+		q := xml.Name{Local: name}
+		form, _ := n.attr("form")
+		if form == "qualified" || (form == "" && doc.qualified) {
+			q.Space = doc.targetNS
+		}
+		e := &xsdElement{name: q, doc: doc}
+		if err := s.resolveElementType(e, n); err != nil {
+			return nil, err
+		}
+		p.elem = e
+	case "any":
+		p.kind = particleAny
+		p.anyNS, _ = n.attr("namespace")
+		if p.anyNS == "" {
+			p.anyNS = "##any"
+		}
+		p.targetNS = doc.targetNS
+		if v, _ := n.attr("processContents"); v == "skip" {
+			p.skip = true
+		}
+	case "group":
+		ref, _ := n.attr("ref")
+		def, ok := s.groupDefs[n.resolveQName(ref)]
+		if !ok {
+			return nil, fmt.Errorf("unknown group %s", ref)
+		}
+		inner, err := s.compileModel(def.node, def.doc)
+		if err != nil {
+			return nil, err
+		}
+		if inner == nil {
+			return nil, nil
+		}
+		return &xsdParticle{kind: particleSequence, min: p.min, max: p.max, items: []*xsdParticle{inner}}, nil
+	case "sequence", "choice", "all":
+		p.kind = map[string]xsdParticleKind{"sequence": particleSequence, "choice": particleChoice, "all": particleAll}[n.Name.Local]
+		for _, c := range xsdChildren(n) {
+			switch c.Name.Local {
+			case "element", "any", "group", "sequence", "choice":
+				item, err := s.compileParticle(c, doc)
+				if err != nil {
+					return nil, err
+				}
+				if item != nil {
+					p.items = append(p.items, item)
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+func (s *xsdSchema) compileAttributes(n *xmlNode, doc *xsdDoc, t *xsdType) error {
+	for _, c := range xsdChildren(n) {
+		switch c.Name.Local {
+		case "attribute":
+			a, err := s.compileAttribute(c)
+			if err != nil {
+				return err
+			}
+			if a != nil {
+				t.attrs[a.name] = a
+			}
+		case "attributeGroup":
+			ref, _ := c.attr("ref")
+			def, ok := s.attrGroupDefs[c.resolveQName(ref)]
+			if !ok {
+				return fmt.Errorf("unknown attributeGroup %s", ref)
+			}
+			if err := s.compileAttributes(def.node, def.doc, t); err != nil {
+				return err
+			}
+		case "anyAttribute":
+			t.anyAttr = true
+		}
+	}
+	return nil
+}
+
+func (s *xsdSchema) compileAttribute(n *xmlNode) (*xsdAttribute, error) {
+	if use, _ := n.attr("use"); use == "prohibited" {
+		return nil, nil
+	}
+	src := n
+	if ref, ok := n.attr("ref"); ok {
+		def, ok := s.attrDefs[n.resolveQName(ref)]
+		if !ok {
+			return nil, nil // e.g. xml:lang, accepted as any attribute below
+		}
+		src = def.node
+	}
+	a := &xsdAttribute{typ: builtinType("string")}
+	a.name, _ = src.attr("name")
+	a.fixed, _ = src.attr("fixed")
+	if use, _ := n.attr("use"); use == "required" {
+		a.required = true
+	}
+	var err error
+	if t, ok := src.attr("type"); ok {
+		a.typ, err = s.simpleTypeByName(src.resolveQName(t))
+	} else if inner := firstXSDChild(src, "simpleType"); inner != nil {
+		a.typ, err = s.compileSimpleType(inner)
+	}
+	return a, err
+}
+
+func collectDecls(p *xsdParticle, into map[xml.Name]*xsdElement) {
+	if p == nil {
+		return
+	}
+	if p.kind == particleElement {
+		if _, ok := into[p.elem.name]; !ok {
+			into[p.elem.name] = p.elem
+		}
+	}
+	for _, c := range p.items {
+		collectDecls(c, into)
+	}
+}
+
+func xsdChildren(n *xmlNode) []*xmlNode {
+	out := make([]*xmlNode, 0, len(n.Children))
+	for _, c := range n.Children {
+		if c.Name.Space == xsdNamespace && c.Name.Local != "annotation" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func firstXSDChild(n *xmlNode, local string) *xmlNode {
+	for _, c := range xsdChildren(n) {
+		if c.Name.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// -------- Instance validation --------
+
+// Violation is a single validation finding located by an XPath expression.
+type Violation struct {
+	Rule    string `json:"rule,omitempty"`
+	XPath   string `json:"xpath"`
+	Message string `json:"message"`
+}
+
+// RuleSchema marks violations reported by schema validation.
+const RuleSchema = "SCHEMA"
+
+type xsdValidator struct {
+	schema     *xsdSchema
+	violations []Violation
+}
+
+func (v *xsdValidator) report(xpath, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Rule: RuleSchema, XPath: xpath, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate checks the document rooted at root and returns every violation found.
+func (s *xsdSchema) validate(root *xmlNode) []Violation {
+	v := &xsdValidator{schema: s}
+	xpath := "/" + root.Name.Local
+	decl, _ := s.element(root.Name)
+	if decl == nil {
+		v.report(xpath, "no declaration for root element {%s}%s", root.Name.Space, root.Name.Local)
+		return v.violations
+	}
+	v.validateElement(root, decl.typ, xpath)
+	return v.violations
+}
+
+func (v *xsdValidator) validateElement(n *xmlNode, t *xsdType, xpath string) {
+	v.validateAttributes(n, t, xpath)
+	if t.anyContent {
+		return
+	}
+	if t.simple != nil {
+		if len(n.Children) > 0 {
+			v.report(xpath, "element %s must not contain child elements", n.Name.Local)
+			return
+		}
+		if msg := t.simple.check(n.Text); msg != "" {
+			v.report(xpath, "invalid value %q: %s", n.Text, msg)
+		}
+		return
+	}
+	if !t.mixed && strings.TrimSpace(n.Text) != "" {
+		v.report(xpath, "element %s must not contain text", n.Name.Local)
+	}
+	paths := childXPaths(n, xpath)
+	if t.content == nil {
+		for i := range n.Children {
+			v.report(paths[i], "element %s is not allowed in %s", n.Children[i].Name.Local, n.Name.Local)
+		}
+		return
+	}
+
+	m := &xsdMatcher{kids: n.Children, failPos: -1}
+	ends := m.match(t.content, 0)
+	complete, furthest := false, 0
+	for _, e := range ends {
+		if e == len(n.Children) {
+			complete = true
+		}
+		if e > furthest {
+			furthest = e
+		}
+	}
+	if !complete {
+		pos, expected := furthest, []string(nil)
+		if m.failPos >= furthest {
+			pos, expected = m.failPos, m.expected
+		}
+		switch {
+		case pos >= len(n.Children):
+			v.report(xpath, "element %s is incomplete, expected %s", n.Name.Local, strings.Join(expected, " or "))
+		case len(expected) > 0:
+			v.report(paths[pos], "unexpected element %s, expected %s", n.Children[pos].Name.Local, strings.Join(expected, " or "))
+		default:
+			v.report(paths[pos], "element %s is not allowed here", n.Children[pos].Name.Local)
+		}
+	}
+
+	for i, c := range n.Children {
+		if decl, ok := t.decls[c.Name]; ok {
+			v.validateElement(c, decl.typ, paths[i])
+			continue
+		}
+		// Children matched by a wildcard are validated laxly against global declarations.
+		if decl, _ := v.schema.element(c.Name); decl != nil {
+			v.validateElement(c, decl.typ, paths[i])
+		}
+	}
+}
+
+func (v *xsdValidator) validateAttributes(n *xmlNode, t *xsdType, xpath string) {
+	seen := map[string]bool{}
+	for _, a := range n.Attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") || a.Name.Space == xsiNamespace {
+			continue
+		}
+		if a.Name.Space != "" {
+			continue // qualified attributes (xml:lang, foreign namespaces) are not constrained
+		}
+		seen[a.Name.Local] = true
+		decl, ok := t.attrs[a.Name.Local]
+		if !ok {
+			if !t.anyAttr && !t.anyContent {
+				v.report(xpath+"/@"+a.Name.Local, "attribute %s is not allowed on %s", a.Name.Local, n.Name.Local)
+			}
+			continue
+		}
+		if msg := decl.typ.check(a.Value); msg != "" {
+			v.report(xpath+"/@"+a.Name.Local, "invalid value %q: %s", a.Value, msg)
+		} else if decl.fixed != "" && a.Value != decl.fixed {
+			v.report(xpath+"/@"+a.Name.Local, "value must be %q", decl.fixed)
+		}
+	}
+	names := make([]string, 0, len(t.attrs))
+	for name := range t.attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if t.attrs[name].required && !seen[name] {
+			v.report(xpath, "missing required attribute %s", name)
+		}
+	}
+}
+
+// childXPaths returns the XPath of each child, indexing names that occur more than once.
+func childXPaths(n *xmlNode, parent string) []string {
+	counts := map[xml.Name]int{}
+	for _, c := range n.Children {
+		counts[c.Name]++
+	}
+	seen := map[xml.Name]int{}
+	paths := make([]string, len(n.Children))
+	for i, c := range n.Children {
+		seen[c.Name]++
+		paths[i] = parent + "/" + c.Name.Local
+		if counts[c.Name] > 1 {
+			paths[i] += fmt.Sprintf("[%d]", seen[c.Name])
+		}
+	}
+	return paths
+}
+
+// xsdMatcher matches a child element list against a content model. It
+// explores all alternatives and remembers the furthest position at which an
+// element was expected but not found, for error reporting. Results are
+// memoized per particle and start position, so nested repetitions are
+// matched in polynomial time.
+type xsdMatcher struct {
+	kids     []*xmlNode
+	failPos  int
+	expected []string
+	memo     map[xsdMatchKey][]int
+}
+
+type xsdMatchKey struct {
+	p   *xsdParticle
+	pos int
+}
+
+func (m *xsdMatcher) fail(pos int, what string) {
+	if pos > m.failPos {
+		m.failPos, m.expected = pos, nil
+	}
+	if pos == m.failPos {
+		for _, e := range m.expected {
+			if e == what {
+				return
+			}
+		}
+		m.expected = append(m.expected, what)
+	}
+}
+
+// match returns the sorted set of positions at which p, including its
+// occurrence constraints, can end when started at pos.
+func (m *xsdMatcher) match(p *xsdParticle, pos int) []int {
+	key := xsdMatchKey{p, pos}
+	if ends, ok := m.memo[key]; ok {
+		return ends
+	}
+	ends := m.matchOccurrences(p, pos)
+	if m.memo == nil {
+		m.memo = map[xsdMatchKey][]int{}
+	}
+	m.memo[key] = ends
+	return ends
+}
+
+func (m *xsdMatcher) matchOccurrences(p *xsdParticle, pos int) []int {
+	ends := map[int]bool{}
+	if p.min == 0 {
+		ends[pos] = true
+	}
+	frontier := []int{pos}
+	// Once the minimum is reached, an unbounded particle continuing from a
+	// position it already continued from cannot end anywhere new.
+	continued := map[int]bool{}
+	for k := 1; len(frontier) > 0 && (p.max < 0 || k <= p.max); k++ {
+		next := map[int]bool{}
+		for _, q := range frontier {
+			if p.max < 0 && k > p.min {
+				if continued[q] {
+					continue
+				}
+				continued[q] = true
+			}
+			for _, e := range m.matchOnce(p, q) {
+				if e == q {
+					// An empty match can fill any remaining required occurrences.
+					ends[q] = true
+					continue
+				}
+				if k >= p.min {
+					ends[e] = true
+				}
+				next[e] = true
+			}
+		}
+		frontier = sortedPositions(next)
+	}
+	return sortedPositions(ends)
+}
+
+func (m *xsdMatcher) matchOnce(p *xsdParticle, pos int) []int {
+	switch p.kind {
+	case particleElement:
+		if pos < len(m.kids) && m.kids[pos].Name == p.elem.name {
+			return []int{pos + 1}
+		}
+		m.fail(pos, p.elem.name.Local)
+	case particleAny:
+		if pos < len(m.kids) && wildcardAllows(p, m.kids[pos].Name.Space) {
+			return []int{pos + 1}
+		}
+		m.fail(pos, "any element")
+	case particleSequence:
+		cur := []int{pos}
+		for _, item := range p.items {
+			next := map[int]bool{}
+			for _, q := range cur {
+				for _, e := range m.match(item, q) {
+					next[e] = true
+				}
+			}
+			if len(next) == 0 {
+				return nil
+			}
+			cur = sortedPositions(next)
+		}
+		return cur
+	case particleChoice:
+		next := map[int]bool{}
+		for _, item := range p.items {
+			for _, e := range m.match(item, pos) {
+				next[e] = true
+			}
+		}
+		return sortedPositions(next)
+	case particleAll:
+		used := make([]bool, len(p.items))
+		q := pos
+	outer:
+		for q < len(m.kids) {
+			for i, item := range p.items {
+				if !used[i] && item.kind == particleElement && m.kids[q].Name == item.elem.name {
+					used[i] = true
+					q++
+					continue outer
+				}
+			}
+			break
+		}
+		for i, item := range p.items {
+			if !used[i] && item.min > 0 {
+				m.fail(q, item.elem.name.Local)
+				return nil
+			}
+		}
+		return []int{q}
+	}
+	return nil
+}
+
+func wildcardAllows(p *xsdParticle, ns string) bool {
+	switch p.anyNS {
+	case "##any":
+		return true
+	case "##other":
+		return ns != p.targetNS && ns != ""
+	}
+	for _, allowed := range strings.Fields(p.anyNS) {
+		if allowed == ns || (allowed == "##targetNamespace" && ns == p.targetNS) || (allowed == "##local" && ns == "") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedPositions(set map[int]bool) []int {
+	out := make([]int, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// -------- Simple type checks --------
+
+var (
+	xsdDecimalRegex = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	xsdIntegerRegex = regexp.MustCompile(`^[+-]?\d+$`)
+	xsdTZSuffix     = regexp.MustCompile(`(Z|[+-]\d{2}:\d{2})$`)
+)
+
+// check returns an empty string if value is valid for st, otherwise a description of the problem.
+func (st *xsdSimpleType) check(value string) string {
+	if st.root() != "string" {
+		value = strings.Join(strings.Fields(value), " ")
+	}
+	switch {
+	case st.base != nil:
+		if msg := st.base.check(value); msg != "" {
+			return msg
+		}
+	case st.builtin == "list":
+		for _, item := range strings.Fields(value) {
+			if msg := st.list.check(item); msg != "" {
+				return msg
+			}
+		}
+		return ""
+	case st.builtin == "union":
+		for _, m := range st.union {
+			if m.check(value) == "" {
+				return ""
+			}
+		}
+		return "value matches none of the union member types"
+	default:
+		if msg := checkBuiltin(st.builtin, value); msg != "" {
+			return msg
+		}
+	}
+	return st.checkFacets(value)
+}
+
+// root returns the builtin type the derivation chain of st starts from.
+func (st *xsdSimpleType) root() string {
+	for t := st; t != nil; t = t.base {
+		if t.base == nil {
+			return t.builtin
+		}
+	}
+	return ""
+}
+
+func (st *xsdSimpleType) checkFacets(value string) string {
+	if len(st.enums) > 0 {
+		ok := false
+		for _, e := range st.enums {
+			if e == value {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Sprintf("must be one of %s", strings.Join(st.enums, ", "))
+		}
+	}
+	if len(st.patterns) > 0 {
+		ok := false
+		for _, pat := range st.patterns {
+			if pat.re.MatchString(value) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Sprintf("does not match pattern %s", st.patterns[0].source)
+		}
+	}
+	n := utf8.RuneCountInString(value)
+	if st.length >= 0 && n != st.length {
+		return fmt.Sprintf("length must be %d", st.length)
+	}
+	if st.minLength >= 0 && n < st.minLength {
+		return fmt.Sprintf("length must be at least %d", st.minLength)
+	}
+	if st.maxLength >= 0 && n > st.maxLength {
+		return fmt.Sprintf("length must be at most %d", st.maxLength)
+	}
+	if st.minInclusive != "" && compareXSDValues(value, st.minInclusive) < 0 {
+		return fmt.Sprintf("must be >= %s", st.minInclusive)
+	}
+	if st.maxInclusive != "" && compareXSDValues(value, st.maxInclusive) > 0 {
+		return fmt.Sprintf("must be <= %s", st.maxInclusive)
+	}
+	if st.minExclusive != "" && compareXSDValues(value, st.minExclusive) <= 0 {
+		return fmt.Sprintf("must be > %s", st.minExclusive)
+	}
+	if st.maxExclusive != "" && compareXSDValues(value, st.maxExclusive) >= 0 {
+		return fmt.Sprintf("must be < %s", st.maxExclusive)
+	}
+	if st.totalDigits >= 0 || st.fractionDigits >= 0 {
+		total, fraction := decimalDigits(value)
+		if st.totalDigits >= 0 && total > st.totalDigits {
+			return fmt.Sprintf("must have at most %d digits", st.totalDigits)
+		}
+		if st.fractionDigits >= 0 && fraction > st.fractionDigits {
+			return fmt.Sprintf("must have at most %d fraction digits", st.fractionDigits)
+		}
+	}
+	return ""
+}
+
+// compareXSDValues compares numbers numerically and everything else (dates) lexically.
+func compareXSDValues(a, b string) int {
+	ra, okA := new(big.Rat).SetString(a)
+	rb, okB := new(big.Rat).SetString(b)
+	if okA && okB {
+		return ra.Cmp(rb)
+	}
+	return strings.Compare(a, b)
+}
+
+// decimalDigits counts the significant total and fraction digits of a decimal literal.
+func decimalDigits(value string) (total, fraction int) {
+	value = strings.TrimLeft(value, "+-")
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	intPart = strings.TrimLeft(intPart, "0")
+	fracPart = strings.TrimRight(fracPart, "0")
+	return len(intPart) + len(fracPart), len(fracPart)
+}
+
+func checkBuiltin(builtin, value string) string {
+	switch builtin {
+	case "decimal":
+		if !xsdDecimalRegex.MatchString(value) {
+			return "not a valid decimal"
+		}
+	case "integer", "long", "int", "short", "byte", "positiveInteger", "nonNegativeInteger", "negativeInteger", "nonPositiveInteger",
+		"unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte":
+		if !xsdIntegerRegex.MatchString(value) {
+			return "not a valid integer"
+		}
+		i, _ := new(big.Int).SetString(strings.TrimPrefix(value, "+"), 10)
+		switch builtin {
+		case "positiveInteger":
+			if i.Sign() <= 0 {
+				return "must be a positive integer"
+			}
+		case "nonNegativeInteger", "unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte":
+			if i.Sign() < 0 {
+				return "must be a non-negative integer"
+			}
+		case "negativeInteger":
+			if i.Sign() >= 0 {
+				return "must be a negative integer"
+			}
+		case "nonPositiveInteger":
+			if i.Sign() > 0 {
+				return "must be a non-positive integer"
+			}
+		case "long":
+			if !i.IsInt64() {
+				return "out of range for long"
+			}
+		case "int":
+			if !i.IsInt64() || i.Int64() < -1<<31 || i.Int64() > 1<<31-1 {
+				return "out of range for int"
+			}
+		}
+	case "double", "float":
+		if value != "INF" && value != "-INF" && value != "NaN" {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return "not a valid " + builtin
+			}
+		}
+	case "boolean":
+		switch value {
+		case "true", "false", "1", "0":
+		default:
+			return "must be true or false"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", xsdTZSuffix.ReplaceAllString(value, "")); err != nil {
+			return "not a valid date (YYYY-MM-DD)"
+		}
+	case "dateTime":
+		v := xsdTZSuffix.ReplaceAllString(value, "")
+		if _, err := time.Parse("2006-01-02T15:04:05", strings.SplitN(v, ".", 2)[0]); err != nil {
+			return "not a valid dateTime"
+		}
+	case "base64Binary":
+		if _, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), "")); err != nil {
+			return "not valid base64"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testSignature = `<dsig:Signature xmlns:dsig="http://www.w3.org/2000/09/xmldsig#">
+    <dsig:SignedInfo>
+      <dsig:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/>
+      <dsig:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/>
+      <dsig:Reference URI="">
+        <dsig:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>
+        <dsig:DigestValue>q83vEjRWeJA=</dsig:DigestValue>
+      </dsig:Reference>
+    </dsig:SignedInfo>
+    <dsig:SignatureValue>q83vEjRWeJA=</dsig:SignatureValue>
+  </dsig:Signature>
+</Invoice>`
+
+func readExampleEbi61(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile("tests/example-ebi61.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestValidateEbInterfaceAcceptsExample(t *testing.T) {
+	doc := readExampleEbi61(t)
+	if err := ValidateEbInterface([]byte(doc)); err != nil {
+		t.Fatalf("example: %v", err)
+	}
+	signed := strings.Replace(doc, "</Invoice>", testSignature, 1)
+	if err := ValidateEbInterface([]byte(signed)); err != nil {
+		t.Fatalf("signed example: %v", err)
+	}
+}
+
+func TestValidateEbInterfaceRejectsInvalidDocuments(t *testing.T) {
+	doc := readExampleEbi61(t)
+	tests := []struct {
+		name      string
+		old, new  string
+		wantXPath string
+	}{
+		{
+			name:      "wrong element order",
+			old:       "<InvoiceNumber>ERB_EBI61_001</InvoiceNumber>\n  <InvoiceDate>2023-01-01</InvoiceDate>",
+			new:       "<InvoiceDate>2023-01-01</InvoiceDate>\n  <InvoiceNumber>ERB_EBI61_001</InvoiceNumber>",
+			wantXPath: "/Invoice/InvoiceDate",
+		},
+		{
+			name:      "missing required element",
+			old:       "<PayableAmount>1361.50</PayableAmount>",
+			new:       "",
+			wantXPath: "/Invoice/PaymentMethod",
+		},
+		{
+			name:      "missing required child",
+			old:       "<VATIdentificationNumber>ATU13585627</VATIdentificationNumber>\n    <FurtherIdentification",
+			new:       "<FurtherIdentification",
+			wantXPath: "/Invoice/Biller/FurtherIdentification[1]",
+		},
+		{
+			name:      "unknown element",
+			old:       "<TotalGrossAmount>",
+			new:       "<Total>1</Total><TotalGrossAmount>",
+			wantXPath: "/Invoice/Total",
+		},
+		{
+			name:      "too many occurrences",
+			old:       "<InvoiceDate>2023-01-01</InvoiceDate>",
+			new:       "<InvoiceDate>2023-01-01</InvoiceDate><InvoiceDate>2023-01-02</InvoiceDate>",
+			wantXPath: "/Invoice/InvoiceDate[2]",
+		},
+		{
+			name:      "bad document type enumeration",
+			old:       `DocumentType="Invoice"`,
+			new:       `DocumentType="Bill"`,
+			wantXPath: "/Invoice/@DocumentType",
+		},
+		{
+			name:      "bad tax category enumeration",
+			old:       `<TaxPercent TaxCategoryCode="S">20.00</TaxPercent>`,
+			new:       `<TaxPercent TaxCategoryCode="X">20.00</TaxPercent>`,
+			wantXPath: "/Invoice/Tax/TaxItem[1]/TaxPercent/@TaxCategoryCode",
+		},
+		{
+			name:      "bad currency",
+			old:       `InvoiceCurrency="EUR"`,
+			new:       `InvoiceCurrency="EURO"`,
+			wantXPath: "/Invoice/@InvoiceCurrency",
+		},
+		{
+			name:      "missing required attribute",
+			old:       `Language="de"`,
+			new:       ``,
+			wantXPath: "/Invoice",
+		},
+		{
+			name:      "undeclared attribute",
+			old:       `Language="de"`,
+			new:       `Language="de" Foo="bar"`,
+			wantXPath: "/Invoice/@Foo",
+		},
+		{
+			name:      "fraction digits facet",
+			old:       "<TotalGrossAmount>1361.50</TotalGrossAmount>",
+			new:       "<TotalGrossAmount>1361.505</TotalGrossAmount>",
+			wantXPath: "/Invoice/TotalGrossAmount",
+		},
+		{
+			name:      "not a decimal",
+			old:       "<TotalGrossAmount>1361.50</TotalGrossAmount>",
+			new:       "<TotalGrossAmount>1.361,50</TotalGrossAmount>",
+			wantXPath: "/Invoice/TotalGrossAmount",
+		},
+		{
+			name:      "not a date",
+			old:       "<InvoiceDate>2023-01-01</InvoiceDate>",
+			new:       "<InvoiceDate>01.01.2023</InvoiceDate>",
+			wantXPath: "/Invoice/InvoiceDate",
+		},
+		{
+			name:      "empty non-empty string",
+			old:       "<InvoiceNumber>ERB_EBI61_001</InvoiceNumber>",
+			new:       "<InvoiceNumber></InvoiceNumber>",
+			wantXPath: "/Invoice/InvoiceNumber",
+		},
+		{
+			name:      "text in element-only content",
+			old:       "<Tax>",
+			new:       "<Tax>text",
+			wantXPath: "/Invoice/Tax",
+		},
+		{
+			name:      "element after Extension",
+			old:       "</Invoice>",
+			new:       "<Extension/><Comment>late</Comment></Invoice>",
+			wantXPath: "/Invoice/Comment[2]",
+		},
+		{
+			name:      "unqualified element in Extension",
+			old:       "</Invoice>",
+			new:       "<Extension><Foo/></Extension></Invoice>",
+			wantXPath: "/Invoice/Extension/Foo",
+		},
+		{
+			name:      "signature missing SignatureValue",
+			old:       "</Invoice>",
+			new:       strings.Replace(testSignature, "<dsig:SignatureValue>q83vEjRWeJA=</dsig:SignatureValue>", "", 1),
+			wantXPath: "/Invoice/Signature",
+		},
+		{
+			name:      "signature with invalid base64",
+			old:       "</Invoice>",
+			new:       strings.Replace(testSignature, "<dsig:DigestValue>q83vEjRWeJA=", "<dsig:DigestValue>not base64!", 1),
+			wantXPath: "/Invoice/Signature/SignedInfo/Reference/DigestValue",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutated := strings.Replace(doc, tt.old, tt.new, 1)
+			if mutated == doc {
+				t.Fatalf("mutation %q not found in example", tt.old)
+			}
+			err := ValidateEbInterface([]byte(mutated))
+			var sve *SchemaValidationError
+			if !errors.As(err, &sve) {
+				t.Fatalf("got %v, want a *SchemaValidationError", err)
+			}
+			for _, v := range sve.Violations {
+				if v.XPath == tt.wantXPath && v.Rule == RuleSchema {
+					return
+				}
+			}
+			t.Errorf("no violation at %s: %v", tt.wantXPath, sve.Violations)
+		})
+	}
+}
+
+const testFacetSchema = `<?xml version="1.0"?>
+<!DOCTYPE xs:schema [
+  <!ENTITY ns 'urn:test'>
+]>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns="&ns;" targetNamespace="&ns;" elementFormDefault="qualified">
+  <xs:simpleType name="CodeType">
+    <xs:restriction base="xs:string">
+      <xs:pattern value="[A-Z]{2}\d{2}"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:element name="Root">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="Code" type="CodeType"/>
+        <xs:element name="Name" minOccurs="0">
+          <xs:simpleType>
+            <xs:restriction base="xs:string">
+              <xs:minLength value="2"/>
+              <xs:maxLength value="5"/>
+            </xs:restriction>
+          </xs:simpleType>
+        </xs:element>
+        <xs:element name="Fixed" minOccurs="0">
+          <xs:simpleType>
+            <xs:restriction base="xs:string">
+              <xs:length value="3"/>
+            </xs:restriction>
+          </xs:simpleType>
+        </xs:element>
+        <xs:element name="Percent" minOccurs="0">
+          <xs:simpleType>
+            <xs:restriction base="xs:decimal">
+              <xs:minInclusive value="0"/>
+              <xs:maxInclusive value="100"/>
+              <xs:totalDigits value="5"/>
+            </xs:restriction>
+          </xs:simpleType>
+        </xs:element>
+        <xs:element name="Count" type="xs:positiveInteger" minOccurs="0"/>
+        <xs:element name="Flag" type="xs:boolean" minOccurs="0"/>
+        <xs:choice minOccurs="0">
+          <xs:element name="A" type="xs:string"/>
+          <xs:element name="B" type="xs:string"/>
+        </xs:choice>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+func TestXSDFacets(t *testing.T) {
+	schema, err := loadXSD(fstest.MapFS{"test.xsd": {Data: []byte(testFacetSchema)}}, "test.xsd")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	tests := []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{"minimal", `<Code>AB12</Code>`, true},
+		{"all facets satisfied", `<Code>AB12</Code><Name>abc</Name><Fixed>xyz</Fixed><Percent>99.5</Percent><Count>1</Count><Flag>true</Flag><B>b</B>`, true},
+		{"pattern", `<Code>ab12</Code>`, false},
+		{"pattern is anchored", `<Code>AB123</Code>`, false},
+		{"minLength", `<Code>AB12</Code><Name>a</Name>`, false},
+		{"maxLength", `<Code>AB12</Code><Name>abcdef</Name>`, false},
+		{"length", `<Code>AB12</Code><Fixed>xy</Fixed>`, false},
+		{"minInclusive", `<Code>AB12</Code><Percent>-1</Percent>`, false},
+		{"maxInclusive", `<Code>AB12</Code><Percent>100.01</Percent>`, false},
+		{"totalDigits", `<Code>AB12</Code><Percent>1.23456</Percent>`, false},
+		{"positiveInteger", `<Code>AB12</Code><Count>0</Count>`, false},
+		{"boolean", `<Code>AB12</Code><Flag>yes</Flag>`, false},
+		{"choice allows one branch", `<Code>AB12</Code><A>a</A><B>b</B>`, false},
+		{"missing required", `<Name>abc</Name>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseXMLTree([]byte(`<Root xmlns="urn:test">` + tt.body + `</Root>`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			violations := schema.validate(root)
+			if tt.valid && len(violations) > 0 {
+				t.Errorf("unexpected violations: %v", violations)
+			}
+			if !tt.valid && len(violations) == 0 {
+				t.Error("expected a violation")
+			}
+		})
+	}
+}
+
+func TestLoadXSDResolvesCatalogImports(t *testing.T) {
+	const importing = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" targetNamespace="urn:test" elementFormDefault="qualified">
+  <xs:import namespace="http://www.w3.org/2000/09/xmldsig#" schemaLocation="http://www.w3.org/TR/2002/REC-xmldsig-core-20020212/xmldsig-core-schema.xsd"/>
+  <xs:element name="Root"><xs:complexType><xs:sequence><xs:element ref="ds:Signature"/></xs:sequence></xs:complexType></xs:element>
+</xs:schema>`
+	dsig, err := schemaFS.ReadFile("schemas/xmldsig-core-schema.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"schemas/test.xsd":                {Data: []byte(importing)},
+		"schemas/xmldsig-core-schema.xsd": {Data: dsig},
+	}
+	schema, err := loadXSD(fsys, "schemas/test.xsd")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	root, err := parseXMLTree([]byte(`<Root xmlns="urn:test"><Signature xmlns="http://www.w3.org/2000/09/xmldsig#"/></Root>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.validate(root)) == 0 {
+		t.Error("an empty Signature was accepted, so the xmldsig schema was not imported")
+	}
+}
+
+func TestParseXMLTreeIgnoresEntityDeclarations(t *testing.T) {
+	// Only schema documents may declare entities; instance documents are untrusted.
+	doc := `<!DOCTYPE Root [<!ENTITY x 'expanded'>]><Root>&x;</Root>`
+	if _, err := parseXMLTree([]byte(doc)); err == nil {
+		t.Error("instance document entity was expanded")
+	}
+	root, err := parseSchemaTree([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Text != "expanded" {
+		t.Errorf("got %q", root.Text)
+	}
+}
+
+func TestXSDMatcherNestedRepetition(t *testing.T) {
+	// Every nesting level can split the run of A elements in many ways. The
+	// matcher has to share the results per particle and position to finish.
+	model := `<xs:element name="A" maxOccurs="unbounded"/>`
+	for i := 0; i < 6; i++ {
+		model = `<xs:sequence minOccurs="0" maxOccurs="unbounded">` + model + `</xs:sequence>`
+	}
+	schema, err := loadXSD(fstest.MapFS{"test.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:test" elementFormDefault="qualified">
+  <xs:element name="Root"><xs:complexType>` + model + `</xs:complexType></xs:element>
+</xs:schema>`)}}, "test.xsd")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	body := strings.Repeat("<A/>", 60)
+	for _, tt := range []struct {
+		doc       string
+		wantXPath string
+	}{
+		{body, ""},
+		{body + "<B/>", "/Root/B"},
+	} {
+		root, err := parseXMLTree([]byte(`<Root xmlns="urn:test">` + tt.doc + `</Root>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		violations := schema.validate(root)
+		switch {
+		case tt.wantXPath == "" && len(violations) > 0:
+			t.Errorf("unexpected violations: %v", violations)
+		case tt.wantXPath != "" && (len(violations) != 1 || violations[0].XPath != tt.wantXPath):
+			t.Errorf("got %v, want one violation at %s", violations, tt.wantXPath)
+		}
+	}
+}
+
+func TestCompileXSDPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{`[A-Z]{2}\d{2}`, "AB12", true},
+		{`[A-Z]{2}\d{2}`, "AB123", false},
+		{`\d+`, "٤٥", true},   // \d covers all Unicode decimal digits
+		{`$\d^`, "$5^", true}, // ^ and $ are ordinary characters
+		{`a|b(c|d)?`, "bd", true},
+		{`a|b(c|d)?`, "ad", false},
+		{`[+\-]?1`, "-1", true},
+		{`[-a]+`, "a-a", true},
+		{`[a-]+`, "a-a", true},
+		{`[^-a]`, "-", false},
+		{`[a-z-[aeiou]]+`, "bcd", true},
+		{`[a-z-[aeiou]]+`, "bad", false},
+		{`[\i-[:]][\c-[:]]*`, "ns1", true},
+		{`[\i-[:]][\c-[:]]*`, "ns:a", false},
+		{`[\i-[:]][\c-[:]]*`, "1ns", false},
+		{`\i\c*`, "Größe", true},
+		{`\p{Lu}\p{Ll}+`, "Éva", true},
+		{`\p{Lu}\p{Ll}+`, "éva", false},
+		{`\P{N}+`, "abc", true},
+		{`\p{IsBasicLatin}+`, "abc", true},
+		{`\p{IsBasicLatin}+`, "äbc", false},
+		{`[\p{IsBasicLatin}\p{IsLatin-1Supplement}]+`, "äbc", true},
+		{`\w+`, "Straße1", true},
+		{`\w+`, "a-b", false},
+		{`a.c`, "a\rc", false},
+		{`a.c`, "aüc", true},
+		{`\s`, "\f", false},
+		{`\S+`, "a b", true},
+		{`\n\t\\`, "\n\t\\", true},
+	}
+	for _, tt := range tests {
+		pat, err := compileXSDPattern(tt.pattern)
+		if err != nil {
+			t.Errorf("compileXSDPattern(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := pat.re.MatchString(tt.value); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCompileXSDPatternErrors(t *testing.T) {
+	for _, p := range []string{`[a-`, `(a`, `a)`, `*a`, `a{2,1}`, `a{x}`, `[z-a]`, `[a-[b]c]`, `\p{IsNoSuchBlock}`, `\p{Xx}`, `\q`, `\$`, `a\`} {
+		if _, err := compileXSDPattern(p); err == nil {
+			t.Errorf("compileXSDPattern(%q) accepted an invalid pattern", p)
+		}
+	}
+}
+
+func TestEmbeddedSchemasCompile(t *testing.T) {
+	for _, version := range supportedEbInterfaceVersions() {
+		v, err := lookupEbInterfaceVersion(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := loadXSD(schemaFS, v.SchemaFile)
+		if err != nil {
+			t.Fatalf("%s: load: %v", version, err)
+		}
+		if err := schema.compileAll(); err != nil {
+			t.Errorf("%s: %v", version, err)
+		}
+	}
+}
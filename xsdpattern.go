@@ -0,0 +1,534 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// XSD pattern facets use the regular expression language of XML Schema Part 2,
+// Appendix F. It differs from Go's RE2 syntax in ways that matter for real
+// schemas: patterns are implicitly anchored, ^ and $ are ordinary characters,
+// \d, \w and . are defined over Unicode, character classes can be subtracted
+// ([a-z-[aeiou]]), and there are the XML name escapes \i and \c and the block
+// escapes \p{IsBasicLatin}. compileXSDPattern parses the XSD syntax and emits
+// an equivalent Go regexp in which every character class is expanded into
+// explicit code point ranges.
+
+// xsdPattern is a compiled pattern facet. The source is kept for messages.
+type xsdPattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+// compileXSDPattern translates an XSD regular expression into an anchored Go regexp.
+func compileXSDPattern(p string) (xsdPattern, error) {
+	t := &xsdRegexTranslator{src: []rune(p)}
+	if err := t.regExp(); err != nil {
+		return xsdPattern{}, err
+	}
+	if t.more() {
+		return xsdPattern{}, t.errorf("unexpected %q", t.peek())
+	}
+	re, err := regexp.Compile(`^(?:` + t.out.String() + `)$`)
+	if err != nil {
+		return xsdPattern{}, err
+	}
+	return xsdPattern{source: p, re: re}, nil
+}
+
+// xsdRegexTranslator is a recursive descent parser for the XSD regex grammar
+// that writes the Go translation to out as it goes.
+type xsdRegexTranslator struct {
+	src []rune
+	pos int
+	out strings.Builder
+}
+
+func (t *xsdRegexTranslator) more() bool { return t.pos < len(t.src) }
+
+func (t *xsdRegexTranslator) peek() rune { return t.src[t.pos] }
+
+func (t *xsdRegexTranslator) peekAt(i int) (rune, bool) {
+	if t.pos+i < len(t.src) {
+		return t.src[t.pos+i], true
+	}
+	return 0, false
+}
+
+func (t *xsdRegexTranslator) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+// regExp ::= branch ( '|' branch )*
+func (t *xsdRegexTranslator) regExp() error {
+	if err := t.branch(); err != nil {
+		return err
+	}
+	for t.more() && t.peek() == '|' {
+		t.pos++
+		t.out.WriteByte('|')
+		if err := t.branch(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// branch ::= piece*
+func (t *xsdRegexTranslator) branch() error {
+	for t.more() && t.peek() != '|' && t.peek() != ')' {
+		if err := t.atom(); err != nil {
+			return err
+		}
+		if err := t.quantifier(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *xsdRegexTranslator) atom() error {
+	switch c := t.peek(); c {
+	case '(':
+		t.pos++
+		t.out.WriteString("(?:")
+		if err := t.regExp(); err != nil {
+			return err
+		}
+		if !t.more() || t.peek() != ')' {
+			return t.errorf("missing )")
+		}
+		t.pos++
+		t.out.WriteByte(')')
+	case '[':
+		set, err := t.charClassExpr()
+		if err != nil {
+			return err
+		}
+		t.out.WriteString(set.String())
+	case '\\':
+		set, single, err := t.charClassEsc()
+		if err != nil {
+			return err
+		}
+		if single != nil {
+			t.out.WriteString(regexp.QuoteMeta(string(*single)))
+			break
+		}
+		t.out.WriteString(set.String())
+	case '.':
+		t.pos++
+		t.out.WriteString(xsdDotSet.String())
+	case '?', '*', '+', '{', '}', ']':
+		return t.errorf("unexpected %q", c)
+	default:
+		t.pos++
+		t.out.WriteString(regexp.QuoteMeta(string(c)))
+	}
+	return nil
+}
+
+var xsdQuantity = regexp.MustCompile(`^(\d+)(,(\d*))?$`)
+
+// quantifier ::= [?*+] | '{' quantity '}'
+func (t *xsdRegexTranslator) quantifier() error {
+	if !t.more() {
+		return nil
+	}
+	switch t.peek() {
+	case '?', '*', '+':
+		t.out.WriteRune(t.peek())
+		t.pos++
+	case '{':
+		end := t.pos + 1
+		for end < len(t.src) && t.src[end] != '}' {
+			end++
+		}
+		if end == len(t.src) {
+			return t.errorf("missing }")
+		}
+		q := string(t.src[t.pos+1 : end])
+		m := xsdQuantity.FindStringSubmatch(q)
+		if m == nil {
+			return t.errorf("invalid quantifier {%s}", q)
+		}
+		if m[3] != "" {
+			lo, _ := strconv.Atoi(m[1])
+			hi, _ := strconv.Atoi(m[3])
+			if lo > hi {
+				return t.errorf("invalid quantifier {%s}", q)
+			}
+		}
+		t.out.WriteString("{" + q + "}")
+		t.pos = end + 1
+	}
+	return nil
+}
+
+// charClassExpr ::= '[' ( '^' )? posCharGroup ( '-' charClassExpr )? ']'
+func (t *xsdRegexTranslator) charClassExpr() (runeSet, error) {
+	t.pos++ // '['
+	negated := false
+	if t.more() && t.peek() == '^' {
+		negated = true
+		t.pos++
+	}
+	var set, sub runeSet
+	first, subtract := true, false
+	for {
+		if !t.more() {
+			return nil, t.errorf("missing ]")
+		}
+		c := t.peek()
+		if c == ']' && !first {
+			break
+		}
+		if next, _ := t.peekAt(1); c == '-' && next == '[' && !first {
+			t.pos++
+			var err error
+			if sub, err = t.charClassExpr(); err != nil {
+				return nil, err
+			}
+			subtract = true
+			if !t.more() || t.peek() != ']' {
+				return nil, t.errorf("a subtraction must end the character class")
+			}
+			break
+		}
+		esc, single, err := t.classChar(first)
+		if err != nil {
+			return nil, err
+		}
+		first = false
+		if single == nil {
+			set = append(set, esc...)
+			continue
+		}
+		// A single character may start a range, unless the '-' ends the class
+		// or introduces a subtraction.
+		next, _ := t.peekAt(1)
+		if t.more() && t.peek() == '-' && next != ']' && next != '[' {
+			t.pos++
+			_, end, err := t.classChar(false)
+			if err != nil {
+				return nil, err
+			}
+			if end == nil || *end < *single {
+				return nil, t.errorf("invalid range")
+			}
+			set = append(set, runeRange{*single, *end})
+			continue
+		}
+		set = append(set, runeRange{*single, *single})
+	}
+	t.pos++ // ']'
+	set = set.normalize()
+	if negated {
+		set = set.negate()
+	}
+	if subtract {
+		set = set.subtract(sub)
+	}
+	return set, nil
+}
+
+// classChar reads one item of a character group: either a single character,
+// returned in single, or a multi-character escape, returned as a set.
+func (t *xsdRegexTranslator) classChar(first bool) (runeSet, *rune, error) {
+	if !t.more() {
+		return nil, nil, t.errorf("missing ]")
+	}
+	c := t.peek()
+	switch {
+	case c == '\\':
+		return t.charClassEsc()
+	case c == '[':
+		return nil, nil, t.errorf("unescaped [ in character class")
+	case c == '-' && !first:
+		if next, _ := t.peekAt(1); next != ']' {
+			return nil, nil, t.errorf("unescaped - in character class")
+		}
+	}
+	t.pos++
+	return nil, &c, nil
+}
+
+// charClassEsc reads an escape. Single character escapes are returned in
+// single so that they can start a range.
+func (t *xsdRegexTranslator) charClassEsc() (runeSet, *rune, error) {
+	t.pos++ // '\'
+	if !t.more() {
+		return nil, nil, t.errorf("trailing backslash")
+	}
+	c := t.peek()
+	t.pos++
+	switch c {
+	case 'n':
+		r := '\n'
+		return nil, &r, nil
+	case 'r':
+		r := '\r'
+		return nil, &r, nil
+	case 't':
+		r := '\t'
+		return nil, &r, nil
+	case '\\', '|', '.', '?', '*', '+', '(', ')', '{', '}', '-', '[', ']', '^':
+		return nil, &c, nil
+	case 'p', 'P':
+		if !t.more() || t.peek() != '{' {
+			return nil, nil, t.errorf(`\%c must be followed by {`, c)
+		}
+		end := t.pos
+		for end < len(t.src) && t.src[end] != '}' {
+			end++
+		}
+		if end == len(t.src) {
+			return nil, nil, t.errorf("missing }")
+		}
+		name := string(t.src[t.pos+1 : end])
+		t.pos = end + 1
+		set, ok := xsdCategorySet(name)
+		if !ok {
+			return nil, nil, t.errorf("unknown category or block %q", name)
+		}
+		if c == 'P' {
+			set = set.negate()
+		}
+		return set, nil, nil
+	}
+	if set, ok := xsdMultiCharEscapes[c]; ok {
+		return set, nil, nil
+	}
+	return nil, nil, t.errorf(`invalid escape \%c`, c)
+}
+
+// -------- Character sets --------
+
+type runeRange struct{ lo, hi rune }
+
+// runeSet is a set of code points as sorted, non-overlapping ranges.
+type runeSet []runeRange
+
+func (s runeSet) normalize() runeSet {
+	sort.Slice(s, func(i, j int) bool { return s[i].lo < s[j].lo })
+	var out runeSet
+	for _, r := range s {
+		if n := len(out); n > 0 && r.lo <= out[n-1].hi+1 {
+			if r.hi > out[n-1].hi {
+				out[n-1].hi = r.hi
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func (s runeSet) negate() runeSet {
+	var out runeSet
+	next := rune(0)
+	for _, r := range s {
+		if r.lo > next {
+			out = append(out, runeRange{next, r.lo - 1})
+		}
+		next = r.hi + 1
+	}
+	if next <= unicode.MaxRune {
+		out = append(out, runeRange{next, unicode.MaxRune})
+	}
+	return out
+}
+
+func (s runeSet) union(o runeSet) runeSet {
+	return append(append(runeSet{}, s...), o...).normalize()
+}
+
+func (s runeSet) subtract(o runeSet) runeSet {
+	return s.negate().union(o).negate()
+}
+
+// String renders the set as a Go character class.
+func (s runeSet) String() string {
+	if len(s) == 0 {
+		return `[^\x{0}-\x{10FFFF}]`
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for _, r := range s {
+		fmt.Fprintf(&b, `\x{%X}`, r.lo)
+		if r.hi > r.lo {
+			fmt.Fprintf(&b, `-\x{%X}`, r.hi)
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func runeSetFromTable(tab *unicode.RangeTable) runeSet {
+	var out runeSet
+	for _, r := range tab.R16 {
+		out = appendStrided(out, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range tab.R32 {
+		out = appendStrided(out, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return out.normalize()
+}
+
+func appendStrided(out runeSet, lo, hi, stride rune) runeSet {
+	if stride == 1 {
+		return append(out, runeRange{lo, hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		out = append(out, runeRange{r, r})
+	}
+	return out
+}
+
+// xsdCategorySet resolves the name in \p{name}: a Unicode general category
+// such as Lu or L, or a block such as IsBasicLatin.
+func xsdCategorySet(name string) (runeSet, bool) {
+	if block, ok := strings.CutPrefix(name, "Is"); ok {
+		r, ok := xsdBlocks[block]
+		return runeSet{r}, ok
+	}
+	if name == "Cn" {
+		return xsdAssigned().negate(), true
+	}
+	tab, ok := unicode.Categories[name]
+	if !ok {
+		return nil, false
+	}
+	set := runeSetFromTable(tab)
+	if name == "C" {
+		set = set.union(xsdAssigned().negate())
+	}
+	return set, true
+}
+
+// xsdAssigned is the set of code points with a general category other than Cn.
+func xsdAssigned() runeSet {
+	var out runeSet
+	for _, major := range []string{"L", "M", "N", "P", "S", "Z", "C"} {
+		out = out.union(runeSetFromTable(unicode.Categories[major]))
+	}
+	return out
+}
+
+var (
+	// . matches anything but a line end.
+	xsdDotSet = runeSet{{'\n', '\n'}, {'\r', '\r'}}.negate()
+
+	// XML 1.0 NameStartChar and NameChar.
+	xsdNameStartSet = runeSet{
+		{':', ':'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}, {0xC0, 0xD6}, {0xD8, 0xF6}, {0xF8, 0x2FF},
+		{0x370, 0x37D}, {0x37F, 0x1FFF}, {0x200C, 0x200D}, {0x2070, 0x218F}, {0x2C00, 0x2FEF},
+		{0x3001, 0xD7FF}, {0xF900, 0xFDCF}, {0xFDF0, 0xFFFD}, {0x10000, 0xEFFFF},
+	}.normalize()
+	xsdNameSet = xsdNameStartSet.union(runeSet{{'-', '-'}, {'.', '.'}, {'0', '9'}, {0xB7, 0xB7}, {0x300, 0x36F}, {0x203F, 0x2040}})
+
+	xsdSpaceSet = runeSet{{'\t', '\n'}, {'\r', '\r'}, {' ', ' '}}
+	xsdDigitSet = runeSetFromTable(unicode.Nd)
+	// \w is everything except punctuation, separators and other characters.
+	xsdWordSet = runeSetFromTable(unicode.P).union(runeSetFromTable(unicode.Z)).union(runeSetFromTable(unicode.C)).union(xsdAssigned().negate()).negate()
+
+	xsdMultiCharEscapes = map[rune]runeSet{
+		's': xsdSpaceSet, 'S': xsdSpaceSet.negate(),
+		'i': xsdNameStartSet, 'I': xsdNameStartSet.negate(),
+		'c': xsdNameSet, 'C': xsdNameSet.negate(),
+		'd': xsdDigitSet, 'D': xsdDigitSet.negate(),
+		'w': xsdWordSet, 'W': xsdWordSet.negate(),
+	}
+)
+
+// xsdBlocks lists the Unicode block escapes of XML Schema Part 2, F.1.1.
+var xsdBlocks = map[string]runeRange{
+	"BasicLatin":                         {0x0000, 0x007F},
+	"Latin-1Supplement":                  {0x0080, 0x00FF},
+	"LatinExtended-A":                    {0x0100, 0x017F},
+	"LatinExtended-B":                    {0x0180, 0x024F},
+	"IPAExtensions":                      {0x0250, 0x02AF},
+	"SpacingModifierLetters":             {0x02B0, 0x02FF},
+	"CombiningDiacriticalMarks":          {0x0300, 0x036F},
+	"Greek":                              {0x0370, 0x03FF},
+	"Cyrillic":                           {0x0400, 0x04FF},
+	"Armenian":                           {0x0530, 0x058F},
+	"Hebrew":                             {0x0590, 0x05FF},
+	"Arabic":                             {0x0600, 0x06FF},
+	"Syriac":                             {0x0700, 0x074F},
+	"Thaana":                             {0x0780, 0x07BF},
+	"Devanagari":                         {0x0900, 0x097F},
+	"Bengali":                            {0x0980, 0x09FF},
+	"Gurmukhi":                           {0x0A00, 0x0A7F},
+	"Gujarati":                           {0x0A80, 0x0AFF},
+	"Oriya":                              {0x0B00, 0x0B7F},
+	"Tamil":                              {0x0B80, 0x0BFF},
+	"Telugu":                             {0x0C00, 0x0C7F},
+	"Kannada":                            {0x0C80, 0x0CFF},
+	"Malayalam":                          {0x0D00, 0x0D7F},
+	"Sinhala":                            {0x0D80, 0x0DFF},
+	"Thai":                               {0x0E00, 0x0E7F},
+	"Lao":                                {0x0E80, 0x0EFF},
+	"Tibetan":                            {0x0F00, 0x0FFF},
+	"Myanmar":                            {0x1000, 0x109F},
+	"Georgian":                           {0x10A0, 0x10FF},
+	"HangulJamo":                         {0x1100, 0x11FF},
+	"Ethiopic":                           {0x1200, 0x137F},
+	"Cherokee":                           {0x13A0, 0x13FF},
+	"UnifiedCanadianAboriginalSyllabics": {0x1400, 0x167F},
+	"Ogham":                              {0x1680, 0x169F},
+	"Runic":                              {0x16A0, 0x16FF},
+	"Khmer":                              {0x1780, 0x17FF},
+	"Mongolian":                          {0x1800, 0x18AF},
+	"LatinExtendedAdditional":            {0x1E00, 0x1EFF},
+	"GreekExtended":                      {0x1F00, 0x1FFF},
+	"GeneralPunctuation":                 {0x2000, 0x206F},
+	"SuperscriptsandSubscripts":          {0x2070, 0x209F},
+	"CurrencySymbols":                    {0x20A0, 0x20CF},
+	"CombiningMarksforSymbols":           {0x20D0, 0x20FF},
+	"LetterlikeSymbols":                  {0x2100, 0x214F},
+	"NumberForms":                        {0x2150, 0x218F},
+	"Arrows":                             {0x2190, 0x21FF},
+	"MathematicalOperators":              {0x2200, 0x22FF},
+	"MiscellaneousTechnical":             {0x2300, 0x23FF},
+	"ControlPictures":                    {0x2400, 0x243F},
+	"OpticalCharacterRecognition":        {0x2440, 0x245F},
+	"EnclosedAlphanumerics":              {0x2460, 0x24FF},
+	"BoxDrawing":                         {0x2500, 0x257F},
+	"BlockElements":                      {0x2580, 0x259F},
+	"GeometricShapes":                    {0x25A0, 0x25FF},
+	"MiscellaneousSymbols":               {0x2600, 0x26FF},
+	"Dingbats":                           {0x2700, 0x27BF},
+	"BraillePatterns":                    {0x2800, 0x28FF},
+	"CJKRadicalsSupplement":              {0x2E80, 0x2EFF},
+	"KangxiRadicals":                     {0x2F00, 0x2FDF},
+	"IdeographicDescriptionCharacters":   {0x2FF0, 0x2FFF},
+	"CJKSymbolsandPunctuation":           {0x3000, 0x303F},
+	"Hiragana":                           {0x3040, 0x309F},
+	"Katakana":                           {0x30A0, 0x30FF},
+	"Bopomofo":                           {0x3100, 0x312F},
+	"HangulCompatibilityJamo":            {0x3130, 0x318F},
+	"Kanbun":                             {0x3190, 0x319F},
+	"BopomofoExtended":                   {0x31A0, 0x31BF},
+	"EnclosedCJKLettersandMonths":        {0x3200, 0x32FF},
+	"CJKCompatibility":                   {0x3300, 0x33FF},
+	"CJKUnifiedIdeographsExtensionA":     {0x3400, 0x4DB5},
+	"CJKUnifiedIdeographs":               {0x4E00, 0x9FFF},
+	"YiSyllables":                        {0xA000, 0xA48F},
+	"YiRadicals":                         {0xA490, 0xA4CF},
+	"HangulSyllables":                    {0xAC00, 0xD7A3},
+	"PrivateUse":                         {0xE000, 0xF8FF},
+	"CJKCompatibilityIdeographs":         {0xF900, 0xFAFF},
+	"AlphabeticPresentationForms":        {0xFB00, 0xFB4F},
+	"ArabicPresentationForms-A":          {0xFB50, 0xFDFF},
+	"CombiningHalfMarks":                 {0xFE20, 0xFE2F},
+	"CJKCompatibilityForms":              {0xFE30, 0xFE4F},
+	"SmallFormVariants":                  {0xFE50, 0xFE6F},
+	"ArabicPresentationForms-B":          {0xFE70, 0xFEFE},
+	"HalfwidthandFullwidthForms":         {0xFF00, 0xFFEF},
+	"Specials":                           {0xFFF0, 0xFFFD},
+}